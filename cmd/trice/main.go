@@ -26,11 +26,13 @@ var (
 
 // main is the entry point.
 func main() {
-	doit(os.Stdout)
+	if nil != doit(os.Stdout) {
+		os.Exit(1)
+	}
 }
 
-// doit is the action.
-func doit(w io.Writer) {
+// doit is the action. It returns the sub-command error, if any.
+func doit(w io.Writer) error {
 
 	// inject values
 	args.Version = version
@@ -42,4 +44,5 @@ func doit(w io.Writer) {
 	if nil != err {
 		fmt.Fprintln(w, error.Error(err))
 	}
+	return err
 }
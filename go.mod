@@ -16,4 +16,5 @@ require (
 	go.bug.st/serial v1.0.0
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/sys v0.0.0-20210119212857-b64e53b001e4 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200605160147-a5ece683394c
 )
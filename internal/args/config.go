@@ -0,0 +1,194 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package args
+
+// config file and environment variable defaults
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rokath/trice/pkg/msg"
+	"gopkg.in/yaml.v3"
+)
+
+// configNames are the config file names searched in the current directory and its parents, if no "-config" is given.
+var configNames = []string{"trice.yaml", "trice.yml", "trice.json"}
+
+// configAll is the config file section with flags for all sub-commands having them.
+const configAll = "all"
+
+// envPrefix is the prefix for environment variables setting flags, like TRICE_PORT for "-port".
+const envPrefix = "TRICE_"
+
+// config holds the flag values from a config file per section. A section is a sub-command name or configAll.
+type config map[string]map[string]interface{}
+
+// findConfig returns the first config file found in dir or its parent directories or "" if none exists.
+func findConfig(dir string) string {
+	for {
+		for _, name := range configNames {
+			fn := filepath.Join(dir, name)
+			if fi, err := os.Stat(fn); nil == err && !fi.IsDir() {
+				return fn
+			}
+		}
+		d := filepath.Dir(dir)
+		if d == dir {
+			return ""
+		}
+		dir = d
+	}
+}
+
+// readConfig reads the YAML or JSON config file fn. Each section name must be configAll or a sub-command in names.
+func readConfig(fn string, names map[string]bool) (config, error) {
+	b, err := ioutil.ReadFile(fn)
+	if nil != err {
+		return nil, err
+	}
+	c := make(config)
+	if err := yaml.Unmarshal(b, &c); nil != err {
+		return nil, fmt.Errorf("config file %s: %v", fn, err)
+	}
+	for section := range c {
+		if configAll != section && !names[section] {
+			return nil, fmt.Errorf("config file %s: unknown sub-command '%s'", fn, section)
+		}
+	}
+	return c, nil
+}
+
+// configValues converts the config value v into flag values. A list results in several values for a multi-flag.
+func configValues(v interface{}) []string {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var s []string
+		for _, y := range x {
+			s = append(s, fmt.Sprint(y))
+		}
+		return s
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+// flagTarget returns an identifier of the variable behind f. Flags like "-idlist" and "-til" share one variable.
+func flagTarget(f *flag.Flag) interface{} {
+	v := reflect.ValueOf(f.Value)
+	if reflect.Ptr == v.Kind() {
+		return v.Pointer()
+	}
+	return f.Name
+}
+
+// parse parses args into fs and fills flags not given in args from environment variables and from the config file sections
+// configAll, fs.Name() and alias, in this order. alias is the sub-command as typed in the command line.
+// The "-config" flag names the config file. Without it the config file is searched from the current directory upwards.
+// Precedence: command line, environment variable, config file sub-command section, config file "all" section, flag default.
+// Command line errors are reported only, as before. Environment variable and config file errors are returned.
+func parse(w io.Writer, fs *flag.FlagSet, alias string, args []string) error {
+	msg.OnErr(fs.Parse(args))
+	given := make(map[interface{}]bool)
+	fs.Visit(func(f *flag.Flag) { given[flagTarget(f)] = true })
+
+	// environment variables
+	var err error
+	envGiven := make(map[interface{}]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		s, ok := os.LookupEnv(envPrefix + strings.ToUpper(f.Name))
+		if !ok || given[flagTarget(f)] || nil != err {
+			return
+		}
+		if e := fs.Set(f.Name, s); nil != e {
+			err = fmt.Errorf("environment variable %s%s: %v", envPrefix, strings.ToUpper(f.Name), e)
+		}
+		envGiven[flagTarget(f)] = true
+	})
+	if nil != err {
+		return err
+	}
+	for t := range envGiven {
+		given[t] = true
+	}
+
+	// config file
+	fn := configFile
+	if "" == fn {
+		if wd, e := os.Getwd(); nil == e {
+			fn = findConfig(wd)
+		}
+		if "" == fn {
+			return nil
+		}
+	}
+	names := make(map[string]bool)
+	for _, s := range flagSets() {
+		names[s.Name()] = true
+	}
+	for _, a := range subCmdAliases {
+		names[a] = true
+	}
+	c, err := readConfig(fn, names)
+	if nil != err {
+		return err
+	}
+	if verbose {
+		fmt.Fprintln(w, "Using config file", fn)
+	}
+	return c.apply(fs, given, configAll, fs.Name(), alias)
+}
+
+// apply sets the flags of fs from the config sections in the given order. A later section overrides an earlier one.
+// Flags in given are not changed. Flags unknown to fs are an error except in section configAll.
+func (c config) apply(fs *flag.FlagSet, given map[interface{}]bool, sections ...string) error {
+	type setting struct {
+		name   string   // name is the flag name as written in the config file
+		values []string // values are the flag values to set
+	}
+	settings := make(map[interface{}]setting) // settings holds the last setting by flag variable, so "-til" in a section overrides "-idlist" in an earlier one
+	for i, section := range sections {
+		if 0 < i && section == sections[i-1] {
+			continue // alias equal to sub-command name
+		}
+		var names []string
+		for name := range c[section] {
+			names = append(names, name)
+		}
+		sort.Strings(names) // deterministic choice between flags sharing a variable inside one section
+		for _, name := range names {
+			f := fs.Lookup(name)
+			if nil == f {
+				if configAll == section {
+					continue
+				}
+				return fmt.Errorf("config file section '%s': flag provided but not defined: -%s", section, name)
+			}
+			settings[flagTarget(f)] = setting{name, configValues(c[section][name])}
+		}
+	}
+	var names []string
+	for t, x := range settings {
+		if !given[t] {
+			names = append(names, x.name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, s := range settings[flagTarget(fs.Lookup(name))].values {
+			if err := fs.Set(name, s); nil != err {
+				return fmt.Errorf("config file flag -%s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package args
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rokath/trice/internal/decoder"
+	"github.com/rokath/trice/internal/emitter"
+	"github.com/rokath/trice/internal/id"
+	"github.com/tj/assert"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "trice.yaml")
+	assert.Nil(t, ioutil.WriteFile(fn, []byte(`# project defaults
+all:
+  color: "off"
+  til: all.json
+  IDMin: 100 # ignored, because log has no -IDMin
+log:
+  encoding: DUMP
+  prefix: "log:"
+  ban: [dbg, wrn]
+  idList: log.json
+l:
+  prefix: "l:"
+`), 0644))
+	defer os.Unsetenv("TRICE_ENCODING")
+	assert.Nil(t, os.Setenv("TRICE_ENCODING", "CHAR"))
+	defer func(ban emitter.ChannelArrayFlag) { emitter.Ban = ban }(emitter.Ban)
+	emitter.Ban = nil
+	FlagsInit()
+	defer FlagsInit()
+
+	var out bytes.Buffer
+	assert.Nil(t, parse(&out, fsScLog, "l", []string{"-config", fn, "-ts", "off"}))
+	assert.Equal(t, "off", emitter.TimestampFormat) // command line
+	assert.Equal(t, "CHAR", decoder.Encoding)       // environment variable
+	assert.Equal(t, "l:", emitter.Prefix)           // alias section
+	assert.Contains(t, emitter.Ban, "wrn")          // sub-command section
+	assert.Equal(t, "log.json", id.FnJSON)          // sub-command section overrides all section, also for a different flag name
+	assert.Equal(t, "off", emitter.ColorPalette)    // all section
+
+	emitter.Ban = nil
+	FlagsInit()
+	assert.Nil(t, parse(&out, fsScLog, "log", []string{"-config", fn, "-til", "cli.json", "-ban", "err"}))
+	assert.Equal(t, "log:", emitter.Prefix)
+	assert.Contains(t, emitter.Ban, "err") // a multi-flag from the command line replaces the config values
+	assert.NotContains(t, emitter.Ban, "wrn")
+	assert.Equal(t, "cli.json", id.FnJSON)
+
+	assert.Nil(t, ioutil.WriteFile(fn, []byte(`{"log": {"IDMin": 9600}}`), 0644))
+	FlagsInit()
+	assert.NotNil(t, parse(&out, fsScLog, "log", []string{"-config", fn}))
+	assert.Nil(t, ioutil.WriteFile(fn, []byte(`{"logging": {"port": "COM1"}}`), 0644))
+	FlagsInit()
+	assert.NotNil(t, parse(&out, fsScLog, "log", []string{"-config", fn}))
+}
+
+func TestFindConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "a", "b")
+	assert.Nil(t, os.MkdirAll(sub, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "trice.json"), []byte(`{"update": {"src": ["a", "c"], "IDMin": 1000}}`), 0644))
+	fn := findConfig(sub)
+	assert.Equal(t, filepath.Join(dir, "trice.json"), fn)
+
+	FlagsInit()
+	defer FlagsInit()
+	defer func(srcs id.ArrayFlag, min, max id.TriceID) { id.Srcs, id.Min, id.Max = srcs, min, max }(id.Srcs, id.Min, id.Max)
+	id.Srcs = nil
+	var out bytes.Buffer
+	assert.Nil(t, parse(&out, fsScUpdate, "u", []string{"-config", fn, "-IDMax", "2000"}))
+	assert.Equal(t, id.ArrayFlag{"a", "c"}, id.Srcs)
+	assert.Equal(t, id.TriceID(1000), id.Min)
+	assert.Equal(t, id.TriceID(2000), id.Max)
+}
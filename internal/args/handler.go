@@ -19,6 +19,7 @@ import (
 	"github.com/rokath/trice/internal/id"
 	"github.com/rokath/trice/internal/link"
 	"github.com/rokath/trice/internal/receiver"
+	"github.com/rokath/trice/internal/tcp"
 	"github.com/rokath/trice/pkg/cage"
 	"github.com/rokath/trice/pkg/cipher"
 	"github.com/rokath/trice/pkg/msg"
@@ -53,50 +54,94 @@ func Handler(w io.Writer, args []string) error {
 	default:
 		return fmt.Errorf("unknown sub-command '%s'. try: 'trice help|h'", subCmd)
 	case "h", "help":
-		msg.OnErr(fsScHelp.Parse(subArgs))
+		if err := parse(w, fsScHelp, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return scHelp(w)
 	case "s", "scan":
-		msg.OnErr(fsScScan.Parse(subArgs))
+		if err := parse(w, fsScScan, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		_, err := com.GetSerialPorts(w)
 		return err
 	case "ver", "version":
-		msg.OnErr(fsScVersion.Parse(subArgs))
+		if err := parse(w, fsScVersion, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return scVersion(w)
 	case "renew":
-		msg.OnErr(fsScRenew.Parse(subArgs))
+		if err := parse(w, fsScRenew, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return id.SubCmdReNewList(w)
 	case "r", "refresh":
-		msg.OnErr(fsScRefresh.Parse(subArgs))
+		if err := parse(w, fsScRefresh, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return id.SubCmdRefreshList(w)
+	case "check", "lint":
+		if err := parse(w, fsScCheck, subCmd, subArgs); nil != err {
+			return err
+		}
+		distributeArgs(w)
+		return id.SubCmdCheck(w)
 	case "u", "update":
-		msg.OnErr(fsScUpdate.Parse(subArgs))
+		if err := parse(w, fsScUpdate, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return id.SubCmdUpdate(w)
+	case "merge":
+		if err := parse(w, fsScMerge, subCmd, subArgs); nil != err {
+			return err
+		}
+		distributeArgs(w)
+		return id.SubCmdMerge(w)
 	case "zeroSourceTreeIds":
-		msg.OnErr(fsScZero.Parse(subArgs))
+		if err := parse(w, fsScZero, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return id.ScZero(w, *pSrcZ, fsScZero)
 	case "sd", "shutdown":
-		msg.OnErr(fsScSdSv.Parse(subArgs))
+		if err := parse(w, fsScSdSv, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return emitter.ScShutdownRemoteDisplayServer(w, 0) // 0|1: 0=no 1=with shutdown timestamp in display server
 	case "ds", "displayServer":
-		msg.OnErr(fsScSv.Parse(subArgs))
+		if err := parse(w, fsScSv, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
 		return emitter.ScDisplayServer(w) // endless loop
 	case "l", "log":
-		msg.OnErr(fsScLog.Parse(subArgs))
+		ports.reset()
+		if err := parse(w, fsScLog, subCmd, subArgs); nil != err {
+			return err
+		}
 		distributeArgs(w)
+		if "json" == emitter.Format && emitter.DisplayRemote {
+			return errors.New("-format json is not usable with -displayserver, which gets text lines only")
+		}
 		logLoop(w) // endless loop
 		return nil
 	}
 }
 
+const (
+	// retryIntervalMin is the first wait time before trying to set up a lost input port again.
+	retryIntervalMin = 1000 * time.Millisecond
+
+	// retryIntervalMax limits the doubling of the retry interval on repeated set up failures.
+	retryIntervalMax = 16 * time.Second
+)
+
 type selector struct {
 	flag bool
 	info func(io.Writer) error
@@ -123,6 +168,23 @@ func logLoop(w io.Writer) {
 	}
 	c := cage.Start(w, cage.Name)
 	defer cage.Stop(w, c)
+
+	if "" != decoder.MetricsAddr {
+		msg.FatalOnErr(decoder.ServeMetrics(w, decoder.MetricsAddr))
+	}
+
+	ss := portSetups()
+	in := newInputs(len(ss))
+	go decoder.HandleSIGTERM(w, in.close) // one signal handler for all ports and reconnects
+	if 1 < len(ss) {
+		logPorts(w, ss, in)
+		return
+	}
+	s := ss[0] // single port: port specific settings override the common ones
+	receiver.Port, receiver.PortArguments = s.Port, s.args
+	decoder.Encoding, decoder.TargetEndianess = s.Encoding, s.TargetEndianess
+	id.FnJSON = s.idList
+
 	var lu id.TriceIDLookUp
 	if id.FnJSON == "emptyFile" { // reserved name for tests only
 		lu = make(id.TriceIDLookUp)
@@ -133,36 +195,110 @@ func logLoop(w io.Writer) {
 	m.Lock()
 	lu.AddFmtCount(w)
 	m.Unlock()
+	if decoder.ShowSource && id.FnJSON != "emptyFile" {
+		s.Sources = id.NewInfo(w, id.FnJSON)
+	}
 	// Just in case the id list file FnJSON gets updated, the file watcher updates lut.
 	// This way trice needs NOT to be restarted during development process.
-	go lu.FileWatcher(w, m)
+	go lu.WatchFileInfo(w, m, id.FnJSON, s.Sources)
+
+	var bl io.Writer
+	f, e := receiver.OpenBinaryLogfile(w, verbose, receiver.BinaryLogfileName)
+	msg.FatalOnErr(e)
+	if nil != f {
+		bl = f
+		defer func() { msg.OnErr(f.Close()) }()
+	}
 
 	sw := emitter.New(w)
+	receiveLoop(w, sw, lu, m, bl, s, in[0])
+}
+
+// input keeps the actual reader of a port, so the signal handler can close it.
+type input struct {
+	mu sync.Mutex
+	rc io.ReadCloser // rc is nil while the port is not set up.
+}
+
+// set makes rc the actual reader of p.
+func (p *input) set(rc io.ReadCloser) {
+	p.mu.Lock()
+	p.rc = rc
+	p.mu.Unlock()
+}
+
+// close closes the actual reader of p, if it is not closed already, for example by the signal handler.
+func (p *input) close() (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if nil != p.rc {
+		err = p.rc.Close()
+		p.rc = nil
+	}
+	return
+}
+
+// inputs are the inputs of all ports of a log session.
+type inputs []*input
+
+// newInputs returns n inputs without readers.
+func newInputs(n int) inputs {
+	in := make(inputs, n)
+	for i := range in {
+		in[i] = new(input)
+	}
+	return in
+}
+
+// close closes the actual readers of p and returns the first error.
+func (p inputs) close() (err error) {
+	for _, in := range p {
+		if e := in.close(); nil == err {
+			err = e
+		}
+	}
+	return
+}
+
+// receiveLoop opens the port of s and translates its bytes with lu into sw until the input ends.
+// A lost port is set up again. If bl is not nil, all received bytes are copied into bl.
+// The actual reader is kept in in for the signal handler.
+func receiveLoop(w io.Writer, sw *emitter.TriceLineComposer, lu id.TriceIDLookUp, m *sync.RWMutex, bl io.Writer, s portSetup, in *input) {
 	var interrupted bool
 	var counter int
+	retry := retryIntervalMin
 
 	for {
-		rc, e := receiver.NewReadCloser(w, verbose, receiver.Port, receiver.PortArguments)
+		rc, e := receiver.NewReadCloser(w, verbose, s.Port, s.args)
 		if nil != e {
 			fmt.Fprint(w, e)
 			if !interrupted {
 				//cage.Stop(c)
 				return // hopeless
 			}
-			time.Sleep(1000 * time.Millisecond) // retry interval
+			time.Sleep(retry) // retry interval
+			if retry < retryIntervalMax {
+				retry *= 2 // back off
+			}
 			fmt.Fprintf(w, "\rsig:(re-)setup input port...%d", counter)
 			counter++
 			continue
 		}
-		defer func() { msg.OnErr(rc.Close()) }()
 		interrupted = true
+		retry = retryIntervalMin
+		if nil != bl {
+			rc = receiver.NewBinaryLogger(bl, rc)
+		}
 		if receiver.ShowInputBytes {
 			rc = receiver.NewBytesViewer(w, rc)
 		}
-		e = decoder.Translate(w, sw, lu, m, rc)
+		in.set(rc)
+		e = s.Translate(w, sw, lu, m, rc)
+		msg.OnErr(in.close()) // each set up port is closed before the next try
 		if io.EOF == e {
 			return // end of predefined buffer
 		}
+		fmt.Fprintln(w, "\nsig:", s.Port, e)
 	}
 }
 
@@ -188,22 +324,26 @@ func distributeArgs(w io.Writer) {
 	com.Verbose = verbose
 	id.Verbose = verbose
 	link.Verbose = verbose
+	tcp.Verbose = verbose
 	cage.Verbose = verbose
 	decoder.Verbose = verbose
 	emitter.Verbose = verbose
 	emitter.TestTableMode = decoder.TestTableMode
 	evaluateColorPalette(w)
+	evaluateFormat(w)
 }
 
 // replaceDefaultArgs assigns port specific default strings.
 func replaceDefaultArgs() {
-	if receiver.PortArguments == "" { // nothing assigned in args
+	if receiver.PortArguments == "" || receiver.PortArguments == "default" { // nothing assigned in args
 		if strings.HasPrefix(receiver.Port, "COM") {
 			receiver.PortArguments = defaultCOMArgs
 		} else {
 			switch receiver.Port {
 			case "JLINK", "STLINK", "J-LINK", "ST-LINK":
 				receiver.PortArguments = defaultLinkArgs
+			case "OPENOCD":
+				receiver.PortArguments = defaultOpenOCDArgs
 			case "BUFFER":
 				receiver.PortArguments = defaultBUFFERArgs
 			}
@@ -221,3 +361,14 @@ func evaluateColorPalette(w io.Writer) {
 		emitter.ColorPalette = "default"
 	}
 }
+
+// evaluateFormat
+func evaluateFormat(w io.Writer) {
+	switch emitter.Format {
+	case "text", "json":
+		return
+	default:
+		fmt.Fprintln(w, "Ignoring unknown -format", emitter.Format, "using text.")
+		emitter.Format = "text"
+	}
+}
@@ -24,9 +24,11 @@ func scHelp(w io.Writer) error {
 	fmt.Fprintln(w, "syntax: 'trice sub-command' [params]")
 	var ok bool
 	x := []selector{
+		{allHelp || checkHelp, checkInfo},
 		{allHelp || displayServerHelp, displayServerInfo},
 		{allHelp || helpHelp, helpInfo},
 		{allHelp || logHelp, logInfo},
+		{allHelp || mergeHelp, mergeInfo},
 		{allHelp || refreshHelp, refreshInfo},
 		{allHelp || renewHelp, renewInfo},
 		{allHelp || scanHelp, scanInfo},
@@ -47,6 +49,19 @@ func scHelp(w io.Writer) error {
 	return nil
 }
 
+func checkInfo(w io.Writer) error {
+	_, e := fmt.Fprintln(w, `sub-command 'check|lint': For checking TRICE macros in source files without changing them.
+	"trice check" will parse source tree(s) for TRICE macros and report each problem as "file:line: text":
+	- The format specifier count does not match the macro name parameter count or the count of given values.
+	- A format specifier is not supported by the trice log decoder or a string specifier is used outside TRICE_S.
+	- The same ID is used with different format strings or is missing in the ID list.
+	The trice tool ends with a non-zero exit code if a problem was found, so a CI build can gate on it.`)
+	fsScCheck.SetOutput(w)
+	fsScCheck.PrintDefaults()
+	fmt.Fprintln(w, "example: 'trice check -src ../A -src ../../B': Check all TRICE macros in ../A and ../../B against til.json.")
+	return e
+}
+
 func displayServerInfo(w io.Writer) error {
 	_, e := fmt.Fprintln(w, `sub-command 'ds|displayServer': Starts a display server. 
 	Use in a separate console. On Windows use wt (https://github.com/microsoft/terminal) or a linux shell like git-bash to avoid ANSI color issues. 
@@ -79,6 +94,20 @@ func logInfo(w io.Writer) error {
 	return e
 }
 
+func mergeInfo(w io.Writer) error {
+	_, e := fmt.Fprintln(w, `sub-command 'merge': For merging ID lists changed in different branches.
+	"trice merge" does a three-way merge of the "-ours" and "-theirs" ID lists with their common ancestor "-base".
+	IDs are never dropped. An ID used for different formats on both sides is an ID collision. It keeps the "-ours" format.
+	Collisions are reported and end with an error, so a version control system marks the ID list as conflicted.
+	With "-renumber" the colliding "-theirs" formats get new IDs, also inside the TRICE macros of the "-src" source trees.
+	To use it as git merge driver, add "til.json merge=trice" to .gitattributes and configure the driver with
+	git config merge.trice.driver "trice merge -base %O -ours %A -theirs %B"`)
+	fsScMerge.SetOutput(w)
+	fsScMerge.PrintDefaults()
+	fmt.Fprintln(w, "example: 'trice merge -base base.json -ours til.json -theirs other.json -renumber -src ./': Merge other.json into til.json and renumber colliding IDs inside ./")
+	return e
+}
+
 func refreshInfo(w io.Writer) error {
 	_, e := fmt.Fprintln(w, `sub-command 'r|refresh': For updating ID list from source files but does not change the source files.
 	"trice refresh" will parse source tree(s) for TRICE macros, and refresh/generate the JSON list.
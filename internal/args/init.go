@@ -34,7 +34,9 @@ func init() {
 
 func FlagsInit() {
 	helpInit()
+	checkInit()
 	logInit()
+	mergeInit()
 	refreshInit()
 	renewInit()
 	updateInit()
@@ -43,17 +45,28 @@ func FlagsInit() {
 	dsInit()
 	scanInit()
 	sdInit()
+	for _, p := range flagSets() {
+		flagConfig(p)
+	}
+}
+
+// flagSets returns the flag sets of all sub-commands.
+func flagSets() []*flag.FlagSet {
+	return []*flag.FlagSet{fsScHelp, fsScCheck, fsScLog, fsScMerge, fsScRefresh, fsScRenew, fsScUpdate, fsScZero, fsScVersion, fsScSv, fsScScan, fsScSdSv}
 }
 
 func helpInit() {
 	fsScHelp = flag.NewFlagSet("help", flag.ContinueOnError) // sub-command
 	fsScHelp.BoolVar(&allHelp, "all", false, "Show all help.")
+	fsScHelp.BoolVar(&checkHelp, "check", false, "Show check|lint specific help.")
+	fsScHelp.BoolVar(&checkHelp, "lint", false, "Show check|lint specific help.")
 	fsScHelp.BoolVar(&displayServerHelp, "displayserver", false, "Show ds|displayserver specific help.")
 	fsScHelp.BoolVar(&displayServerHelp, "ds", false, "Show ds|displayserver specific help.")
 	fsScHelp.BoolVar(&helpHelp, "help", false, "Show h|help specific help.")
 	fsScHelp.BoolVar(&helpHelp, "h", false, "Show h|help specific help.")
 	fsScHelp.BoolVar(&logHelp, "log", false, "Show l|log specific help.")
 	fsScHelp.BoolVar(&logHelp, "l", false, "Show l|log specific help.")
+	fsScHelp.BoolVar(&mergeHelp, "merge", false, "Show merge specific help.")
 	fsScHelp.BoolVar(&refreshHelp, "refresh", false, "Show r|refresh specific help.")
 	fsScHelp.BoolVar(&refreshHelp, "r", false, "Show r|refresh specific help.")
 	fsScHelp.BoolVar(&renewHelp, "renew", false, "Show renew specific help.")
@@ -98,19 +111,58 @@ When set to "off" no PC timestamps displayed.
 If you need target timestamps you need to get the time inside the target and send it as TRICE* parameter.
 `) // flag
 	fsScLog.StringVar(&decoder.ShowID, "showID", "", `Format string for displaying first trice ID at start of each line. Example: "debug:%7d ". Default is "". If several trices form a log line only the first trice ID ist displayed.`)
+	fsScLog.BoolVar(&decoder.ShowSource, "showSource", false, `Display the source location "file:line" of the first trice at start of each line. The location is taken from the ID list file, where "trice update" or "trice refresh" store it. `+boolInfo)
 	fsScLog.StringVar(&decoder.ShowTargetTimestamp, "ttsf", "tim:%9d", `Target timestamp format string at start of each line, if target timestamps existent (configured). Use "" to suppress existing target timestamps. If several trices form a log line only the timestamp of first trice ist displayed.`)
+	fsScLog.Float64Var(&decoder.TargetTimestampFrequency, "ttFreq", 0, `Target timestamp tick frequency in Hz, like 1000 for milliseconds or 72e6 for a 72 MHz counter.
+Default 0 shows the raw target timestamps with -ttsf. Otherwise they are shown as time according -ttUnit.
+The 32-bit wraparound of the target timestamps is handled, as long as less than half the wraparound time passes between two trices.
+A bigger step backwards is taken as target reset and the first trice after it becomes the new reference.
+`)
+	fsScLog.StringVar(&decoder.TargetTimeUnit, "ttUnit", "ms", `Target time unit with -ttFreq, options: 's|ms|us|abs'.
+"s", "ms" and "us" show the target time since the reference trice, "abs" shows the local time based on the reception time of the reference trice.
+The reference trice is the first trice after the start or a target reset or, with -ttSync, the last sync trice.
+`)
+	fsScLog.IntVar(&decoder.TargetTimeSync, "ttSync", 0, `ID of a sync trice. Its reception time is the new reference for the target time with -ttFreq.
+Default 0 means the first trice is the reference.
+`)
+	fsScLog.BoolVar(&decoder.TargetTimeDelta, "ttDelta", false, `Show also the target time since the previous trice, like "+1.250ms". Without -ttFreq the delta is in ticks. `+boolInfo)
 	fsScLog.BoolVar(&decoder.DebugOut, "debug", false, "Show additional debug information")
 	fsScLog.StringVar(&decoder.TargetEndianess, "targetEndianess", "littleEndian", `Target endianness trice data stream. Option: "bigEndian".`)
 	fsScLog.StringVar(&emitter.ColorPalette, "color", "default", colorInfo)                                                                                                                                        // flag
+	fsScLog.StringVar(&emitter.Format, "format", "text", `Output format, options: 'text|json'.
+"text": Human readable lines according to -prefix, -suffix, -ts and -color.
+"json": One JSON object per trice with PC timestamp, target timestamp, ID, channel, type, format string, values and text.
+It is not usable together with -displayserver.
+`) // flag
+	fsScLog.DurationVar(&decoder.StatsInterval, "stats", 0, `Link quality statistics interval, like "10s" or "1m". 0 means statistics only at shutdown.
+The statistics per port are received bytes, COBS packages, decoded trices, trices per second, dropped invalid data (errors),
+unknown IDs, cycle counter gaps (lost data) and detected target resets. With "-format json" they are JSON lines.
+With this switch they are also written at the end of a FILE or STDIN input.
+`) // flag
+	fsScLog.StringVar(&decoder.MetricsAddr, "metricsAddr", "", `HTTP listen address for Prometheus metrics, like ":9100". Default "" means no metrics.
+The metrics at http://host:port/metrics contain per port the received bytes, COBS packages, decoded trices,
+decode errors, unknown IDs, cycle losses, target resets and the trice counts per ID and the line counts per channel.
+The received bytes per second are updated every 10 seconds. Other rates are computed by the scraper, like rate(trice_trices_total[1m]).
+`) // flag
 	fsScLog.StringVar(&emitter.Prefix, "prefix", DefaultPrefix, "Line prefix, options: any string or 'off|none' or 'source:' followed by 0-12 spaces, 'source:' will be replaced by source value e.g., 'COM17:'.") // flag
 	fsScLog.StringVar(&emitter.Suffix, "suffix", "", "Append suffix to all lines, options: any string.")                                                                                                           // flag
 
-	info := `receiver device: 'ST-LINK'|'J-LINK'|serial name. 
+	info := `receiver device: 'ST-LINK'|'J-LINK'|'OPENOCD'|'TCP:host:port'|'FILE'|'STDIN'|serial name. 
 The serial name is like 'COM12' for Windows or a Linux name like '/dev/tty/usb12'. 
 Using a virtual serial COM port on the PC over a FTDI USB adapter is a most likely variant.
+'TCP:host:port' connects to trice bytes forwarded over the network, like 'TCP:192.168.1.7:2000' for a ser2net server.
+A lost TCP connection is set up again automatically.
+'OPENOCD' reads the RTT channel from a running OpenOCD (or pyOCD) RTT server without an RTT logger executable, see -args.
+'FILE' reads a recorded binary trice stream from the file given with -args and 'STDIN' reads it from standard input. Both end at the stream end (see -follow).
+This is a multi-flag switch. Several ports are received concurrently and their lines are merged into one output in the order of their reception time.
+Therefore the lines are held back for 100 ms. Each line prefix then contains the port name. Port specific settings follow the port name comma separated as key=value pairs.
+Keys are 'encoding', 'targetEndianess', 'i' (ID list file) and 'args'. Not given settings are taken from the common switches.
+Example: "-port COM3 -port COM4,encoding=DUMP -port TCP:192.168.1.7:2000,i=board3/til.json"
 `
-	fsScLog.StringVar(&receiver.Port, "port", "J-LINK", info)           // flag
-	fsScLog.StringVar(&receiver.Port, "p", "J-LINK", "short for -port") // short flag
+	ports.reset()
+	receiver.Port = ports.def
+	fsScLog.Var(&ports, "port", info)           // multi flag
+	fsScLog.Var(&ports, "p", "short for -port") // short flag
 	fsScLog.IntVar(&com.Baud, "baud", 115200, `Set the serial port baudrate.
 It is the only setup parameter. The other values default to 8N1 (8 data bits, no parity, one stopbit).
 `)
@@ -123,13 +175,25 @@ It is the only setup parameter. The other values default to 8N1 (8 data bits, no
 port "COMn": default="`, defaultCOMArgs, `", use "TARM" for a different driver. (For baud rate settings see -baud.)
 port "J-LINK": default="`, defaultLinkArgs, `", `, linkArgsInfo, `
 port "ST-LINK": default="`, defaultLinkArgs, `", `, linkArgsInfo, `
+port "OPENOCD": default="`, defaultOpenOCDArgs, `",
+	The RTT server is set up over the OpenOCD Tcl port. Use "-tclPort 0" for an already running RTT server, like from pyOCD.
+	-RTTControlBlock sets the control block ID, spaces written as _ (default SEGGER_RTT).
 port "BUFFER": default="`, defaultBUFFERArgs, `", Option for args is any byte sequence.
+port "FILE": The args value is the name of the file to read, like "capture.bin".
 `)
 
 	fsScLog.StringVar(&receiver.PortArguments, "args", "default", argsInfo)
+	fsScLog.BoolVar(&receiver.Follow, "follow", false, `Keep reading at the end of a "-port FILE" file and wait for appended bytes like 'tail -f'.
+Use this for a file which is still growing. Without this switch trice ends at the file end.
+`+boolInfo)
 	fsScLog.BoolVar(&emitter.DisplayRemote, "displayserver", false, `Send trice lines to displayserver @ ipa:ipp.
 Example: "trice l -port COM38 -ds -ipa 192.168.178.44" sends trice output to a previously started display server in the same network.`)
 	fsScLog.BoolVar(&emitter.DisplayRemote, "ds", false, "Short for '-displayserver'.")
+	fsScLog.BoolVar(&emitter.DisplayLocal, "dsLocal", false, `Show the trice lines also locally, when sending them to the displayserver. `+boolInfo)
+	fsScLog.IntVar(&emitter.DisplayQueueSize, "dsQueue", 10000, `Count of lines kept while the displayserver is not reachable. trice reconnects automatically and sends them afterwards.
+When the queue is full, the oldest lines are dropped and counted in a warning line.`)
+	fsScLog.StringVar(&emitter.ProducerName, "dsName", "", `Name shown by the displayserver in front of each line of this log instance.
+Default "" means host name and port, like "bench1/COM3".`)
 
 	//  	fsScLog.BoolVar(&emitter.Autostart, "autostart", false, `Autostart displayserver @ ipa:ipp.
 	//  Works not perfect with windows, because of cmd and powershell color issues and missing cli params in wt and gitbash.
@@ -139,6 +203,14 @@ Example: "trice l -port COM38 -ds -ipa 192.168.178.44" sends trice output to a p
 	fsScLog.BoolVar(&decoder.Unsigned, "unsigned", true, "Hex, Octal and Bin values are printed as unsigned values.")
 	fsScLog.BoolVar(&decoder.Unsigned, "u", true, "Short for '-unsigned'.")
 	// fsScLog.BoolVar(&emitter.Autostart, "a", false, "Short for '-autostart'.")
+	fsScLog.StringVar(&receiver.BinaryLogfileName, "binaryLogfile", "off", `Append all received raw bytes to a binary logfile. Options are: 'off|none|filename|auto':
+"off": no binary logfile (same as "none")
+"none": no binary logfile (same as "off")
+"auto": Use as binary logfile name "2006-01-02_1504-05_trice.bin" with actual time.
+"filename": Any other string than "auto", "none" or "off" is treated as a filename. If the file exists, bytes are appended.
+A binary logfile can be replayed later with "trice log -port FILE -args filename", for example with a different til.json.
+With several ports each port gets its own binary logfile with the port name in front of the file extension, like "capture_COM3.bin".
+`)
 	fsScLog.BoolVar(&receiver.ShowInputBytes, "showInputBytes", false, `Show incoming bytes, what can be helpful during setup.
 `+boolInfo)
 	fsScLog.BoolVar(&receiver.ShowInputBytes, "s", false, "Short for '-showInputBytes'.")
@@ -147,6 +219,7 @@ Example: "trice l -port COM38 -ds -ipa 192.168.178.44" sends trice output to a p
 	flagVerbosity(fsScLog)
 	flagIDList(fsScLog)
 	flagIPAddress(fsScLog)
+	flagDisplayClient(fsScLog)
 	fsScLog.Var(&emitter.Ban, "ban", `Channel(s) to ignore. This is a multi-flag switch. It can be used several times with a colon separated list of channel descriptors not to display.
 Example: "-ban dbg:wrn -ban diag" results in suppressing all as debug, diag and warning tagged messages. Not usable in conjunction with "-pick".`) // multi flag
 	fsScLog.Var(&emitter.Pick, "pick", `Channel(s) to display. This is a multi-flag switch. It can be used several times with a colon separated list of channel descriptors only to display.
@@ -154,6 +227,39 @@ Example: "-pick err:wrn -pick default" results in suppressing all messages despi
 
 }
 
+func checkInit() {
+	fsScCheck = flag.NewFlagSet("check", flag.ExitOnError) // sub-command
+	flagSrcs(fsScCheck)
+	flagSrcFilter(fsScCheck)
+	flagVerbosity(fsScCheck)
+	flagIDList(fsScCheck)
+}
+
+func mergeInit() {
+	fsScMerge = flag.NewFlagSet("merge", flag.ExitOnError) // sub-command
+	fsScMerge.StringVar(&id.MergeBase, "base", "", `The ID list file of the common ancestor. An empty or missing file means no common ancestor.
+`) // flag
+	fsScMerge.StringVar(&id.MergeOurs, "ours", "til.json", `The ID list file of the own branch. It is overwritten with the merge result, if "-out" is not given.
+`) // flag
+	fsScMerge.StringVar(&id.MergeTheirs, "theirs", "", `The ID list file of the other branch, required.
+`) // flag
+	fsScMerge.StringVar(&id.MergeOut, "out", "", `The merge result file. Default is the "-ours" file.
+`) // flag
+	fsScMerge.BoolVar(&id.Renumber, "renumber", false, `Give the formats of the "-theirs" file new IDs if their IDs are used differently in the "-ours" file.
+The new IDs are patched into the TRICE macros inside the "-src" source trees. Without this switch ID collisions end with an error.
+`+boolInfo) // flag
+	fsScMerge.Var(&id.Min, "IDMin", "Lower end of ID range for renumbered trices.")
+	fsScMerge.Var(&id.Max, "IDMax", "Upper end of ID range for renumbered trices.")
+	fsScMerge.StringVar(&id.SearchMethod, "IDMethod", "random", "Search method for renumbered ID's in range- Options are 'upward', 'downward' & 'random'.")
+	fsScMerge.Var(&id.Ranges, "IDRange", `ID range for renumbered trices of source files matching a pattern in the form "pattern=min:max[:method]", like with update.
+This is a multi-flag switch. The source file of a trice is taken from the "-theirs" file. Trices without source file or matching range
+get new IDs from "-IDMin" to "-IDMax".`)
+	flagDryRun(fsScMerge)
+	flagSrcs(fsScMerge)
+	flagSrcFilter(fsScMerge)
+	flagVerbosity(fsScMerge)
+}
+
 func refreshInit() {
 	fsScRefresh = flag.NewFlagSet("refresh", flag.ExitOnError) // sub-command
 	flagsRefreshAndUpdate(fsScRefresh)
@@ -170,6 +276,12 @@ func updateInit() {
 	fsScUpdate.Var(&id.Min, "IDMin", "Lower end of ID range for normal trices.")
 	fsScUpdate.Var(&id.Max, "IDMax", "Upper end of ID range for normal trices.")
 	fsScUpdate.StringVar(&id.SearchMethod, "IDMethod", "random", "Search method for new ID's in range- Options are 'upward', 'downward' & 'random'.")
+	fsScUpdate.Var(&id.Ranges, "IDRange", `ID range for source files matching a pattern in the form "pattern=min:max[:method]".
+This is a multi-flag switch. The first range with a pattern matching a source file or one of its parent directories is used
+for new IDs in this file. Files without matching range get new IDs from "-IDMin" to "-IDMax".
+Ranges overlapping each other or the interval from "-IDMin" to "-IDMax" are rejected.
+Example: "trice u -src boot -src app -src lib -IDRange boot=1000:1999 -IDRange lib/*=2000:4999:upward -IDRange app=5000:9999"
+The pattern syntax is like in Go's path.Match and relative to the current directory.`)
 	fsScUpdate.BoolVar(&id.ExtendMacrosWithParamCount, "addParamCount", false, "Extend TRICE macro names with the parameter count _n to enable compile time checks.")
 	fsScUpdate.BoolVar(&id.SharedIDs, "sharedIDs", false, `ID policy:
 true: TriceFmt's without TriceID get equal TriceID if an equal TriceFmt exists already.
@@ -179,6 +291,7 @@ false: TriceFmt's without TriceID get a different TriceID if an equal TriceFmt e
 func zeroInit() {
 	fsScZero = flag.NewFlagSet("zeroSourceTreeIds", flag.ContinueOnError)
 	pSrcZ = fsScZero.String("src", "", "Zero all Id(n) inside source tree dir, required.") // flag
+	flagSrcFilter(fsScZero)
 	flagDryRun(fsScZero)
 }
 
@@ -191,8 +304,17 @@ func versionInit() {
 func dsInit() {
 	fsScSv = flag.NewFlagSet("displayServer", flag.ExitOnError)            // sub-command
 	fsScSv.StringVar(&emitter.ColorPalette, "color", "default", colorInfo) // flag
+	fsScSv.StringVar(&emitter.WebAddr, "webAddr", "", `HTTP listen address for a web viewer, like ":8080". Default "" means no web viewer.
+Any browser can then follow the lines at http://host:port with the channel colors, producer and channel filters (ban, pick) and a text search.
+`) // flag
+	fsScSv.IntVar(&emitter.HistorySize, "history", 1000, "Count of recent lines kept for web viewers connecting later.") // flag
+	fsScSv.StringVar(&emitter.Producers, "producers", "", `Comma separated list of the producer names (see "trice log -dsName") to display. Default "" means all producers.
+The web viewers select their producers independently.`) // flag
 	flagLogfile(fsScSv)
 	flagIPAddress(fsScSv)
+	flagDisplayToken(fsScSv)
+	fsScSv.StringVar(&emitter.TLSCert, "tlsCert", "", `PEM certificate file for TLS connections. Needs also -tlsKey. Default "" means no TLS.`) // flag
+	fsScSv.StringVar(&emitter.TLSKey, "tlsKey", "", `PEM private key file for -tlsCert.`)                                                       // flag
 }
 
 func scanInit() {
@@ -202,13 +324,21 @@ func scanInit() {
 func sdInit() {
 	fsScSdSv = flag.NewFlagSet("shutdownServer", flag.ExitOnError) // sub-command
 	flagIPAddress(fsScSdSv)
+	flagDisplayClient(fsScSdSv)
 }
 
 func flagsRefreshAndUpdate(p *flag.FlagSet) {
 	flagDryRun(p)
 	flagSrcs(p)
+	flagSrcFilter(p)
 	flagVerbosity(p)
 	flagIDList(p)
+	p.StringVar(&id.ScanCache, "cache", "auto", `Cache file for the TRICE macros found per source file, options: 'auto|off|none|filename'.
+Only source files changed since the last run are parsed again. A file counts as changed, if its modification time, size or content hash differs.
+"auto": Use a file inside the user cache directory with a name derived from the absolute ID list file path, like "~/.cache/trice/til.json-0123456789abcdef.cache".
+"filename": Any other string is used as cache file name. Do not put the cache file under version control.
+"off": No cache (same as "none").
+`) // flag
 }
 
 func flagLogfile(p *flag.FlagSet) {
@@ -234,6 +364,24 @@ Without the "-dry-run" switch it would create|extend a list file til.json in the
 	p.Var(&id.Srcs, "s", "Short for src.") // multi flag
 }
 
+func flagSrcFilter(p *flag.FlagSet) {
+	p.Var(&id.Includes, "include", `Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+This is a multi-flag switch. Example: "trice `+p.Name()+` -include *.ino -include *.inc" handles also Arduino sketches and include files.`) // multi flag
+	p.Var(&id.Excludes, "exclude", `File or directory pattern to skip inside the source trees. Skipped files are never changed.
+This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.`) // multi flag
+}
+
+func flagConfig(p *flag.FlagSet) {
+	p.StringVar(&configFile, "config", "", `Config file with default flag values, options: YAML or JSON file name.
+Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+`) // flag
+}
+
 func flagDryRun(p *flag.FlagSet) {
 	p.BoolVar(&id.DryRun, "dry-run", false, `No changes applied but output shows what would happen.
 "trice `+p.Name()+` -dry-run" will change nothing but show changes it would perform without the "-dry-run" switch.
@@ -259,6 +407,21 @@ The specified JSON file is needed to display the ID coded trices during runtime
 `) // flag
 }
 
+func flagDisplayToken(p *flag.FlagSet) {
+	p.StringVar(&emitter.Token, "dsToken", "", `Shared secret between the display server and its clients. Default "" means no token.
+A display server with a token refuses clients without it, otherwise it accepts control messages like shutdown only from the local host.
+Use the environment variable TRICE_DSTOKEN or the config file to keep it out of the process list.
+`) // flag
+}
+
+func flagDisplayClient(p *flag.FlagSet) {
+	flagDisplayToken(p)
+	p.BoolVar(&emitter.TLS, "dsTLS", false, `Connect to the display server with TLS. `+boolInfo) // flag
+	p.StringVar(&emitter.TLSCA, "dsCA", "", `PEM certificate authority file to check the display server certificate with -dsTLS, like a self-signed server certificate.
+Default "" means the system certificate authorities.
+`) // flag
+}
+
 func flagIPAddress(p *flag.FlagSet) {
 	p.StringVar(&emitter.IPAddr, "ipa", "localhost", `IP address like '127.0.0.1'.
 You can specify this switch if you intend to use the remote display option to show the output on a different PC in the network.
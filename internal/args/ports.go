@@ -0,0 +1,197 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package args
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rokath/trice/internal/decoder"
+	"github.com/rokath/trice/internal/emitter"
+	"github.com/rokath/trice/internal/id"
+	"github.com/rokath/trice/internal/receiver"
+	"github.com/rokath/trice/pkg/msg"
+)
+
+// portsFlag is a multi flag collecting all -port values.
+// The port name of the first value is also assigned to receiver.Port.
+type portsFlag struct {
+	def    string   // def is the default port
+	values []string // values are the port specifications in command line order
+}
+
+// ports holds the -port values of the log sub-command.
+var ports = portsFlag{def: "J-LINK"}
+
+// String method is the needed for interface satisfaction.
+func (p *portsFlag) String() string {
+	if 0 == len(p.values) {
+		return p.def
+	}
+	return strings.Join(p.values, " ")
+}
+
+// Set is a needed method for multi flags.
+func (p *portsFlag) Set(value string) error {
+	s, err := parsePortSetup(value)
+	if nil != err {
+		return err
+	}
+	p.values = append(p.values, value)
+	if 1 == len(p.values) {
+		receiver.Port = s.Port
+	}
+	return nil
+}
+
+// reset clears the collected values before a new command line parsing.
+func (p *portsFlag) reset() {
+	p.values = nil
+}
+
+// portSetup contains all settings for one port of a log session.
+type portSetup struct {
+	decoder.Setup
+	args   string // args are the port specific parameters like -args
+	idList string // idList is the til.json file name for this port
+}
+
+// parsePortSetup evaluates a port specification like "COM3,encoding=DUMP,targetEndianess=bigEndian,i=board2/til.json,args=TARM".
+// Settings not contained in the specification are left empty.
+func parsePortSetup(spec string) (s portSetup, err error) {
+	fields := strings.Split(spec, ",")
+	s.Port = fields[0]
+	if "" == s.Port {
+		return s, fmt.Errorf("missing port name in %q", spec)
+	}
+	for _, f := range fields[1:] {
+		kv := strings.SplitN(f, "=", 2)
+		if 2 != len(kv) {
+			return s, fmt.Errorf("expecting key=value instead of %q in %q", f, spec)
+		}
+		switch kv[0] {
+		case "encoding", "e":
+			s.Encoding = kv[1]
+		case "targetEndianess":
+			s.TargetEndianess = kv[1]
+		case "idlist", "idList", "til", "i":
+			s.idList = kv[1]
+		case "args":
+			s.args = kv[1]
+		default:
+			return s, fmt.Errorf("unknown port setting %q in %q", kv[0], spec)
+		}
+	}
+	return
+}
+
+// portSetups returns the settings for all -port values. Missing settings are taken from the common command line switches.
+func portSetups() (ss []portSetup) {
+	values := ports.values
+	if 0 == len(values) {
+		values = []string{receiver.Port}
+	}
+	for _, v := range values {
+		s, err := parsePortSetup(v)
+		msg.FatalOnErr(err)
+		if "" == s.Encoding {
+			s.Encoding = decoder.Encoding
+		}
+		if "" == s.TargetEndianess {
+			s.TargetEndianess = decoder.TargetEndianess
+		}
+		if "" == s.idList {
+			s.idList = id.FnJSON
+		}
+		if "" == s.args {
+			s.args = receiver.PortArguments
+		}
+		ss = append(ss, s)
+	}
+	return
+}
+
+// lookUp is a trice ID look-up map together with its guarding mutex.
+type lookUp struct {
+	lu id.TriceIDLookUp
+	m  *sync.RWMutex
+	li id.TriceIDInfo // li is not nil only with -showSource.
+}
+
+// newLookUp reads the ID list file fn and watches it for changes.
+func newLookUp(w io.Writer, fn string) lookUp {
+	var lu id.TriceIDLookUp
+	if fn == "emptyFile" { // reserved name for tests only
+		lu = make(id.TriceIDLookUp)
+	} else {
+		lu = id.NewLut(w, fn) // lut is a map, that means a pointer
+	}
+	m := new(sync.RWMutex) // m is a pointer to a read write mutex for lu
+	m.Lock()
+	lu.AddFmtCount(w)
+	m.Unlock()
+	// Just in case the id list file fn gets updated, the file watcher updates lut.
+	// This way trice needs NOT to be restarted during development process.
+	var li id.TriceIDInfo
+	if decoder.ShowSource && fn != "emptyFile" {
+		li = id.NewInfo(w, fn)
+	}
+	if fn != "emptyFile" {
+		go lu.WatchFileInfo(w, m, fn, li)
+	}
+	return lookUp{lu, m, li}
+}
+
+// logPorts receives and translates all ports in ss concurrently and writes the lines of all ports into one output.
+// Ports using the same ID list file share one look-up map. logPorts returns when all ports are done.
+// The actual port readers are kept in in, one per port, for the signal handler.
+func logPorts(w io.Writer, ss []portSetup, in inputs) {
+	names := make([]string, len(ss))
+	for i, s := range ss {
+		names[i] = s.Port
+		if "FILE" == s.Port { // several files are distinguishable by name only
+			names[i] = filepath.Base(s.args)
+		}
+	}
+	bls := make([]io.Writer, len(ss)) // one binary logfile per port
+	fn := receiver.BinaryLogfileName
+	if "auto" == fn {
+		fn = time.Now().Format(receiver.DefaultBinaryLogfileName) // same timestamp for all ports
+	}
+	for i, name := range names {
+		f, e := receiver.OpenBinaryLogfile(w, verbose, receiver.PortBinaryLogfileName(fn, name))
+		msg.FatalOnErr(e)
+		if nil != f {
+			bls[i] = f
+			defer func() { msg.OnErr(f.Close()) }()
+		}
+	}
+	sws, flush := emitter.NewPorts(w, names)
+	defer flush()
+	defer msg.AtExit(flush)() // held back lines are written also on a fatal error
+	lus := make(map[string]lookUp)
+	var wg sync.WaitGroup
+	for i, s := range ss {
+		fn := id.ConditionalFilePath(s.idList)
+		if s.idList == "emptyFile" {
+			fn = s.idList
+		}
+		l, ok := lus[fn]
+		if !ok {
+			l = newLookUp(w, fn)
+			lus[fn] = l
+		}
+		s.Sources = l.li
+		wg.Add(1)
+		go func(sw *emitter.TriceLineComposer, bl io.Writer, s portSetup, in *input) {
+			defer wg.Done()
+			receiveLoop(w, sw, l.lu, l.m, bl, s, in)
+		}(sws[i], bls[i], s, in[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package args
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rokath/trice/internal/decoder"
+	"github.com/rokath/trice/internal/emitter"
+	"github.com/tj/assert"
+)
+
+func TestParsePortSetup(t *testing.T) {
+	s, err := parsePortSetup("COM3")
+	assert.Nil(t, err)
+	assert.Equal(t, portSetup{Setup: decoder.Setup{Port: "COM3"}}, s)
+
+	s, err = parsePortSetup("TCP:localhost:2000,encoding=DUMP,targetEndianess=bigEndian,i=board2/til.json,args=TARM")
+	assert.Nil(t, err)
+	assert.Equal(t, portSetup{Setup: decoder.Setup{Port: "TCP:localhost:2000", Encoding: "DUMP", TargetEndianess: "bigEndian"}, args: "TARM", idList: "board2/til.json"}, s)
+
+	_, err = parsePortSetup(",e=COBS")
+	assert.NotNil(t, err)
+	_, err = parsePortSetup("COM3,encoding")
+	assert.NotNil(t, err)
+	_, err = parsePortSetup("COM3,baud=9600")
+	assert.NotNil(t, err)
+}
+
+// TestLogSeveralPorts checks that each port gets its own decoder. Otherwise the second START trice would cause a cycle warning.
+func TestLogSeveralPorts(t *testing.T) {
+	x.Lock()
+	defer x.Unlock()
+	FlagsInit()
+	var out bytes.Buffer
+	err := Handler(&out, []string{"trice", "log", "-ts", "off", "-color", "off", "-i", "testdata/til.json",
+		"-port", "BUFFER,args=2 1 1 1 3 208 7 1 5 192 1 196 188 1 1 1 1 0",
+		"-port", "DUMP,args=02 01 01 01 03 d0 07 01 05 c0 01 c4 bc 01 01 01 01 00",
+	})
+	assert.Nil(t, err)
+	act := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 3, len(act))
+	sort.Strings(act[1:]) // the order of both ports is undefined
+	assert.Equal(t, []string{
+		"BUFFER: tim:     2000MSG: START select = 0, TriceDepthMax =   0",
+		"DUMP: tim:     2000MSG: START select = 0, TriceDepthMax =   0",
+	}, act[1:])
+}
+
+// TestShowSource checks that -showSource displays the source location stored inside the ID list file.
+func TestShowSource(t *testing.T) {
+	x.Lock()
+	defer x.Unlock()
+	FlagsInit()
+	var out bytes.Buffer
+	err := Handler(&out, []string{"trice", "log", "-ts", "off", "-color", "off", "-i", "testdata/tilSource.json", "-showSource",
+		"-port", "BUFFER", "-args", "2 1 1 1 3 208 7 1 5 192 1 196 188 1 1 1 1 0",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "tim:     2000src/main.c:42 MSG: START select = 0, TriceDepthMax =   0")
+}
+
+// TestJSONWithDisplayServer checks that -format json is rejected together with -displayserver.
+func TestJSONWithDisplayServer(t *testing.T) {
+	x.Lock()
+	defer x.Unlock()
+	defer func(remote bool, format string) { emitter.DisplayRemote, emitter.Format = remote, format }(emitter.DisplayRemote, emitter.Format)
+	FlagsInit()
+	var out bytes.Buffer
+	err := Handler(&out, []string{"trice", "log", "-format", "json", "-ds", "-port", "BUFFER", "-args", "0"})
+	assert.NotNil(t, err)
+}
+
+// closeCounter counts its Close calls.
+type closeCounter struct {
+	bytes.Buffer
+	n int
+}
+
+func (p *closeCounter) Close() error {
+	p.n++
+	return nil
+}
+
+func TestInputsClose(t *testing.T) {
+	in := newInputs(3)
+	var a, b, stale closeCounter
+	in[0].set(&stale)
+	in[0].set(&a) // reconnected
+	in[2].set(&b)
+	assert.Nil(t, in.close())
+	assert.Equal(t, 1, a.n)
+	assert.Equal(t, 1, b.n)
+	assert.Equal(t, 0, stale.n)
+	assert.Nil(t, in.close()) // closed already
+	assert.Equal(t, 1, a.n)
+	assert.Nil(t, in[2].close()) // like at the end of a receive loop
+	assert.Equal(t, 1, b.n)
+}
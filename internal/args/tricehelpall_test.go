@@ -8,6 +8,55 @@ import "testing"
 func TestHelpAll(t *testing.T) {
 	input := []string{"trice", "help", "-all"}
 	expected := `syntax: 'trice sub-command' [params]
+      sub-command 'check|lint': For checking TRICE macros in source files without changing them.
+          "trice check" will parse source tree(s) for TRICE macros and report each problem as "file:line: text":
+          - The format specifier count does not match the macro name parameter count or the count of given values.
+          - A format specifier is not supported by the trice log decoder or a string specifier is used outside TRICE_S.
+          - The same ID is used with different format strings or is missing in the ID list.
+          The trice tool ends with a non-zero exit code if a problem was found, so a CI build can gate on it.
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
+        -exclude value
+              File or directory pattern to skip inside the source trees. Skipped files are never changed.
+              This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+              A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+              The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.
+        -i string
+              Short for '-idlist'.
+               (default "til.json")
+        -idList string
+              Alternate for '-idlist'.
+               (default "til.json")
+        -idlist string
+              The trice ID list file.
+              The specified JSON file is needed to display the ID coded trices during runtime and should be under version control.
+               (default "til.json")
+        -include value
+              Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+              This is a multi-flag switch. Example: "trice check -include *.ino -include *.inc" handles also Arduino sketches and include files.
+        -s value
+              Short for src.
+        -src value
+              Source dir or file, It has one parameter. Not usable in the form "-src *.c".
+              This is a multi-flag switch. It can be used several times for directories and also for files.
+              Example: "trice check -dry-run -v -src ./test/ -src pkg/src/trice.h" will scan all C|C++ header and
+              source code files inside directory ./test and scan also file trice.h inside pkg/src directory.
+              Without the "-dry-run" switch it would create|extend a list file til.json in the current directory.
+               (default "./")
+        -til string
+              Short for '-idlist'.
+               (default "til.json")
+        -v    short for verbose
+        -verbose
+              Gives more informal output if used. Can be helpful during setup.
+              For example "trice u -dry-run -v" is the same as "trice u -dry-run" but with more descriptive output.
+              This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+      example: 'trice check -src ../A -src ../../B': Check all TRICE macros in ../A and ../../B against til.json.
       sub-command 'ds|displayServer': Starts a display server.
               Use in a separate console. On Windows use wt (https://github.com/microsoft/terminal) or a linux shell like git-bash to avoid ANSI color issues.
               Running "trice ds" inside a console opens a display server to be used for displaying the TRICE logs remotely.
@@ -19,6 +68,20 @@ func TestHelpAll(t *testing.T) {
               "none": Disable ANSI color. The lower case channel information is removed: "w:x"-> "x"
               "default|color": Use ANSI color codes for known upper and lower case channel info are inserted and lower case channel information is removed.
                (default "default")
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
+        -dsToken string
+              Shared secret between the display server and its clients. Default "" means no token.
+              A display server with a token refuses clients without it, otherwise it accepts control messages like shutdown only from the local host.
+              Use the environment variable TRICE_DSTOKEN or the config file to keep it out of the process list.
+
+        -history int
+              Count of recent lines kept for web viewers connecting later. (default 1000)
         -ipa string
               IP address like '127.0.0.1'.
               You can specify this switch if you intend to use the remote display option to show the output on a different PC in the network.
@@ -36,11 +99,31 @@ func TestHelpAll(t *testing.T) {
               All trice output of the appropriate subcommands is appended per default into the logfile trice additionally to the normal output.
               Change the filename with "-logfile myName.txt" or switch logging off with "-logfile none".
                (default "off")
+        -producers string
+              Comma separated list of the producer names (see "trice log -dsName") to display. Default "" means all producers.
+              The web viewers select their producers independently.
+        -tlsCert string
+              PEM certificate file for TLS connections. Needs also -tlsKey. Default "" means no TLS.
+        -tlsKey string
+              PEM private key file for -tlsCert.
+        -webAddr string
+              HTTP listen address for a web viewer, like ":8080". Default "" means no web viewer.
+              Any browser can then follow the lines at http://host:port with the channel colors, producer and channel filters (ban, pick) and a text search.
+
       example: 'trice ds': Start display server.
       sub-command 'h|help': For command line usage.
               "trice h" will print this help text as a whole.
         -all
               Show all help.
+        -check
+              Show check|lint specific help.
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -displayserver
               Show ds|displayserver specific help.
         -ds
@@ -49,6 +132,8 @@ func TestHelpAll(t *testing.T) {
         -help
               Show h|help specific help.
         -l    Show l|log specific help.
+        -lint
+              Show check|lint specific help.
         -log
               Show l|log specific help.
         -logfile string
@@ -60,6 +145,8 @@ func TestHelpAll(t *testing.T) {
               All trice output of the appropriate subcommands is appended per default into the logfile trice additionally to the normal output.
               Change the filename with "-logfile myName.txt" or switch logging off with "-logfile none".
                (default "off")
+        -merge
+              Show merge specific help.
         -r    Show r|refresh specific help.
         -refresh
               Show r|refresh specific help.
@@ -100,7 +187,11 @@ func TestHelpAll(t *testing.T) {
               port "ST-LINK": default="-Device STM32F030R8 -if SWD -Speed 4000 -RTTChannel 0 -RTTSearchRanges 0x20000000_0x1000",
                       The -RTTSearchRanges "..." need to be written without "" and with _ instead of space.
                       For args options see JLinkRTTLogger in SEGGER UM08001_JLink.pdf.
+              port "OPENOCD": default="-host localhost -tclPort 6666 -dataPort 19021 -RTTChannel 0 -RTTSearchRanges 0x20000000_0x1000",
+                  The RTT server is set up over the OpenOCD Tcl port. Use "-tclPort 0" for an already running RTT server, like from pyOCD.
+                  -RTTControlBlock sets the control block ID, spaces written as _ (default SEGGER_RTT).
               port "BUFFER": default="0 0 0 0", Option for args is any byte sequence.
+              port "FILE": The args value is the name of the file to read, like "capture.bin".
                (default "default")
         -ban value
               Channel(s) to ignore. This is a multi-flag switch. It can be used several times with a colon separated list of channel descriptors not to display.
@@ -109,6 +200,15 @@ func TestHelpAll(t *testing.T) {
               Set the serial port baudrate.
               It is the only setup parameter. The other values default to 8N1 (8 data bits, no parity, one stopbit).
                (default 115200)
+        -binaryLogfile string
+              Append all received raw bytes to a binary logfile. Options are: 'off|none|filename|auto':
+              "off": no binary logfile (same as "none")
+              "none": no binary logfile (same as "off")
+              "auto": Use as binary logfile name "2006-01-02_1504-05_trice.bin" with actual time.
+              "filename": Any other string than "auto", "none" or "off" is treated as a filename. If the file exists, bytes are appended.
+              A binary logfile can be replayed later with "trice log -port FILE -args filename", for example with a different til.json.
+              With several ports each port gets its own binary logfile with the port name in front of the file extension, like "capture_COM3.bin".
+               (default "off")
         -color string
               The format strings can start with a lower or upper case channel information.
               See https://github.com/rokath/trice/blob/master/pkg/src/triceCheck.c for examples. Color options:
@@ -116,6 +216,13 @@ func TestHelpAll(t *testing.T) {
               "none": Disable ANSI color. The lower case channel information is removed: "w:x"-> "x"
               "default|color": Use ANSI color codes for known upper and lower case channel info are inserted and lower case channel information is removed.
                (default "default")
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -dc int
               Dumped bytes per line when "-encoding DUMP" (default 32)
         -debug
@@ -125,6 +232,25 @@ func TestHelpAll(t *testing.T) {
               Example: "trice l -port COM38 -ds -ipa 192.168.178.44" sends trice output to a previously started display server in the same network.
         -ds
               Short for '-displayserver'.
+        -dsCA string
+              PEM certificate authority file to check the display server certificate with -dsTLS, like a self-signed server certificate.
+              Default "" means the system certificate authorities.
+
+        -dsLocal
+              Show the trice lines also locally, when sending them to the displayserver. This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -dsName string
+              Name shown by the displayserver in front of each line of this log instance.
+              Default "" means host name and port, like "bench1/COM3".
+        -dsQueue int
+              Count of lines kept while the displayserver is not reachable. trice reconnects automatically and sends them afterwards.
+              When the queue is full, the oldest lines are dropped and counted in a warning line. (default 10000)
+        -dsTLS
+              Connect to the display server with TLS. This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -dsToken string
+              Shared secret between the display server and its clients. Default "" means no token.
+              A display server with a token refuses clients without it, otherwise it accepts control messages like shutdown only from the local host.
+              Use the environment variable TRICE_DSTOKEN or the config file to keep it out of the process list.
+
         -e string
               Short for -encoding. (default "COBS")
         -encoding string
@@ -135,6 +261,16 @@ func TestHelpAll(t *testing.T) {
                                 ESC is a legacy format and will be removed in the future.
                                 FLEX is a legacy format and will be removed in the future.
                (default "COBS")
+        -follow
+              Keep reading at the end of a "-port FILE" file and wait for appended bytes like 'tail -f'.
+              Use this for a file which is still growing. Without this switch trice ends at the file end.
+              This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -format string
+              Output format, options: 'text|json'.
+              "text": Human readable lines according to -prefix, -suffix, -ts and -color.
+              "json": One JSON object per trice with PC timestamp, target timestamp, ID, channel, type, format string, values and text.
+              It is not usable together with -displayserver.
+               (default "text")
         -i string
               Short for '-idlist'.
                (default "til.json")
@@ -162,19 +298,33 @@ func TestHelpAll(t *testing.T) {
               All trice output of the appropriate subcommands is appended per default into the logfile trice additionally to the normal output.
               Change the filename with "-logfile myName.txt" or switch logging off with "-logfile none".
                (default "off")
-        -p string
-              short for -port (default "J-LINK")
+        -metricsAddr string
+              HTTP listen address for Prometheus metrics, like ":9100". Default "" means no metrics.
+              The metrics at http://host:port/metrics contain per port the received bytes, COBS packages, decoded trices,
+              decode errors, unknown IDs, cycle losses, target resets and the trice counts per ID and the line counts per channel.
+              The received bytes per second are updated every 10 seconds. Other rates are computed by the scraper, like rate(trice_trices_total[1m]).
+
+        -p value
+              short for -port (default J-LINK)
         -password string
               The decrypt passphrase. If you change this value you need to compile the target with the appropriate key (see -showKeys).
               Encryption is recommended if you deliver firmware to customers and want protect the trice log output. This does work right now only with flex and flexL format.
         -pick value
               Channel(s) to display. This is a multi-flag switch. It can be used several times with a colon separated list of channel descriptors only to display.
               Example: "-pick err:wrn -pick default" results in suppressing all messages despite of as error, warning and default tagged messages. Not usable in conjunction with "-ban".
-        -port string
-              receiver device: 'ST-LINK'|'J-LINK'|serial name.
+        -port value
+              receiver device: 'ST-LINK'|'J-LINK'|'OPENOCD'|'TCP:host:port'|'FILE'|'STDIN'|serial name.
               The serial name is like 'COM12' for Windows or a Linux name like '/dev/tty/usb12'.
               Using a virtual serial COM port on the PC over a FTDI USB adapter is a most likely variant.
-               (default "J-LINK")
+              'TCP:host:port' connects to trice bytes forwarded over the network, like 'TCP:192.168.1.7:2000' for a ser2net server.
+              A lost TCP connection is set up again automatically.
+              'OPENOCD' reads the RTT channel from a running OpenOCD (or pyOCD) RTT server without an RTT logger executable, see -args.
+              'FILE' reads a recorded binary trice stream from the file given with -args and 'STDIN' reads it from standard input. Both end at the stream end (see -follow).
+              This is a multi-flag switch. Several ports are received concurrently and their lines are merged into one output in the order of their reception time.
+              Therefore the lines are held back for 100 ms. Each line prefix then contains the port name. Port specific settings follow the port name comma separated as key=value pairs.
+              Keys are 'encoding', 'targetEndianess', 'i' (ID list file) and 'args'. Not given settings are taken from the common switches.
+              Example: "-port COM3 -port COM4,encoding=DUMP -port TCP:192.168.1.7:2000,i=board3/til.json"
+               (default J-LINK)
         -prefix string
               Line prefix, options: any string or 'off|none' or 'source:' followed by 0-12 spaces, 'source:' will be replaced by source value e.g., 'COM17:'. (default "source: ")
         -pw string
@@ -189,6 +339,14 @@ func TestHelpAll(t *testing.T) {
               Show encryption key. Use this switch for creating your own password keys. If applied together with "-password MySecret" it shows the encryption key.
               Simply copy this key than into the line "#define ENCRYPT XTEA_KEY( ea, bb, ec, 6f, 31, 80, 4e, b9, 68, e2, fa, ea, ae, f1, 50, 54 ); //!< -password MySecret" inside triceConfig.h.
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -showSource
+              Display the source location "file:line" of the first trice at start of each line. The location is taken from the ID list file, where "trice update" or "trice refresh" store it. This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -stats duration
+              Link quality statistics interval, like "10s" or "1m". 0 means statistics only at shutdown.
+              The statistics per port are received bytes, COBS packages, decoded trices, trices per second, dropped invalid data (errors),
+              unknown IDs, cycle counter gaps (lost data) and detected target resets. With "-format json" they are JSON lines.
+              With this switch they are also written at the end of a FILE or STDIN input.
+
         -suffix string
               Append suffix to all lines, options: any string.
         -targetEndianess string
@@ -206,6 +364,23 @@ func TestHelpAll(t *testing.T) {
               When set to "off" no PC timestamps displayed.
               If you need target timestamps you need to get the time inside the target and send it as TRICE* parameter.
                (default "LOCmicro")
+        -ttDelta
+              Show also the target time since the previous trice, like "+1.250ms". Without -ttFreq the delta is in ticks. This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -ttFreq float
+              Target timestamp tick frequency in Hz, like 1000 for milliseconds or 72e6 for a 72 MHz counter.
+              Default 0 shows the raw target timestamps with -ttsf. Otherwise they are shown as time according -ttUnit.
+              The 32-bit wraparound of the target timestamps is handled, as long as less than half the wraparound time passes between two trices.
+              A bigger step backwards is taken as target reset and the first trice after it becomes the new reference.
+
+        -ttSync int
+              ID of a sync trice. Its reception time is the new reference for the target time with -ttFreq.
+              Default 0 means the first trice is the reference.
+
+        -ttUnit string
+              Target time unit with -ttFreq, options: 's|ms|us|abs'.
+              "s", "ms" and "us" show the target time since the reference trice, "abs" shows the local time based on the reception time of the reference trice.
+              The reference trice is the first trice after the start or a target reset or, with -ttSync, the last sync trice.
+               (default "ms")
         -ttsf string
               Target timestamp format string at start of each line, if target timestamps existent (configured). Use "" to suppress existing target timestamps. If several trices form a log line only the timestamp of first trice ist displayed. (default "tim:%9d")
         -u    Short for '-unsigned'. (default true)
@@ -219,6 +394,73 @@ func TestHelpAll(t *testing.T) {
       example: 'trice l -p COM15 -baud 38400': Display trice log messages from serial port COM15
       example: 'trice l': Display flexL data format trice log messages from default source J-LINK over Segger RTT protocol.
       example: 'trice l -port ST-LINK -v -s': Shows verbose version information and also the received raw bytes.
+      sub-command 'merge': For merging ID lists changed in different branches.
+          "trice merge" does a three-way merge of the "-ours" and "-theirs" ID lists with their common ancestor "-base".
+          IDs are never dropped. An ID used for different formats on both sides is an ID collision. It keeps the "-ours" format.
+          Collisions are reported and end with an error, so a version control system marks the ID list as conflicted.
+          With "-renumber" the colliding "-theirs" formats get new IDs, also inside the TRICE macros of the "-src" source trees.
+          To use it as git merge driver, add "til.json merge=trice" to .gitattributes and configure the driver with
+          git config merge.trice.driver "trice merge -base %O -ours %A -theirs %B"
+        -IDMax value
+              Upper end of ID range for renumbered trices. (default 65535)
+        -IDMethod string
+              Search method for renumbered ID's in range- Options are 'upward', 'downward' & 'random'. (default "random")
+        -IDMin value
+              Lower end of ID range for renumbered trices. (default 32768)
+        -IDRange value
+              ID range for renumbered trices of source files matching a pattern in the form "pattern=min:max[:method]", like with update.
+              This is a multi-flag switch. The source file of a trice is taken from the "-theirs" file. Trices without source file or matching range
+              get new IDs from "-IDMin" to "-IDMax".
+        -base string
+              The ID list file of the common ancestor. An empty or missing file means no common ancestor.
+
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
+        -dry-run
+              No changes applied but output shows what would happen.
+              "trice merge -dry-run" will change nothing but show changes it would perform without the "-dry-run" switch.
+              This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -exclude value
+              File or directory pattern to skip inside the source trees. Skipped files are never changed.
+              This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+              A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+              The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.
+        -include value
+              Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+              This is a multi-flag switch. Example: "trice merge -include *.ino -include *.inc" handles also Arduino sketches and include files.
+        -ours string
+              The ID list file of the own branch. It is overwritten with the merge result, if "-out" is not given.
+               (default "til.json")
+        -out string
+              The merge result file. Default is the "-ours" file.
+
+        -renumber
+              Give the formats of the "-theirs" file new IDs if their IDs are used differently in the "-ours" file.
+              The new IDs are patched into the TRICE macros inside the "-src" source trees. Without this switch ID collisions end with an error.
+              This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -s value
+              Short for src.
+        -src value
+              Source dir or file, It has one parameter. Not usable in the form "-src *.c".
+              This is a multi-flag switch. It can be used several times for directories and also for files.
+              Example: "trice merge -dry-run -v -src ./test/ -src pkg/src/trice.h" will scan all C|C++ header and
+              source code files inside directory ./test and scan also file trice.h inside pkg/src directory.
+              Without the "-dry-run" switch it would create|extend a list file til.json in the current directory.
+               (default "./")
+        -theirs string
+              The ID list file of the other branch, required.
+
+        -v    short for verbose
+        -verbose
+              Gives more informal output if used. Can be helpful during setup.
+              For example "trice u -dry-run -v" is the same as "trice u -dry-run" but with more descriptive output.
+              This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+      example: 'trice merge -base base.json -ours til.json -theirs other.json -renumber -src ./': Merge other.json into til.json and renumber colliding IDs inside ./
       sub-command 'r|refresh': For updating ID list from source files but does not change the source files.
               "trice refresh" will parse source tree(s) for TRICE macros, and refresh/generate the JSON list.
               This command should be run on adding souce files to the project before the first time "trice update" is called.
@@ -230,10 +472,29 @@ func TestHelpAll(t *testing.T) {
               IDs used in the added sources with the result that IDs in the added sources could get changed what you may not want.
               Using "trice u -IDMethod random" (default) makes the chance for such conflicts very low.
               The "refresh" sub-command has no mandatory switches. Omitted optional switches are used with their default parameters.
+        -cache string
+              Cache file for the TRICE macros found per source file, options: 'auto|off|none|filename'.
+              Only source files changed since the last run are parsed again. A file counts as changed, if its modification time, size or content hash differs.
+              "auto": Use a file inside the user cache directory with a name derived from the absolute ID list file path, like "~/.cache/trice/til.json-0123456789abcdef.cache".
+              "filename": Any other string is used as cache file name. Do not put the cache file under version control.
+              "off": No cache (same as "none").
+               (default "auto")
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -dry-run
               No changes applied but output shows what would happen.
               "trice refresh -dry-run" will change nothing but show changes it would perform without the "-dry-run" switch.
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -exclude value
+              File or directory pattern to skip inside the source trees. Skipped files are never changed.
+              This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+              A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+              The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.
         -i string
               Short for '-idlist'.
                (default "til.json")
@@ -244,6 +505,9 @@ func TestHelpAll(t *testing.T) {
               The trice ID list file.
               The specified JSON file is needed to display the ID coded trices during runtime and should be under version control.
                (default "til.json")
+        -include value
+              Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+              This is a multi-flag switch. Example: "trice refresh -include *.ino -include *.inc" handles also Arduino sketches and include files.
         -s value
               Short for src.
         -src value
@@ -263,10 +527,29 @@ func TestHelpAll(t *testing.T) {
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
       example: 'trice refresh': Update ID list from source tree.
       sub-command 'renew': It is like refresh, but til.json is cleared first, so all 'old' trices are removed. Use with care.
+        -cache string
+              Cache file for the TRICE macros found per source file, options: 'auto|off|none|filename'.
+              Only source files changed since the last run are parsed again. A file counts as changed, if its modification time, size or content hash differs.
+              "auto": Use a file inside the user cache directory with a name derived from the absolute ID list file path, like "~/.cache/trice/til.json-0123456789abcdef.cache".
+              "filename": Any other string is used as cache file name. Do not put the cache file under version control.
+              "off": No cache (same as "none").
+               (default "auto")
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -dry-run
               No changes applied but output shows what would happen.
               "trice renew -dry-run" will change nothing but show changes it would perform without the "-dry-run" switch.
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -exclude value
+              File or directory pattern to skip inside the source trees. Skipped files are never changed.
+              This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+              A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+              The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.
         -i string
               Short for '-idlist'.
                (default "til.json")
@@ -277,6 +560,9 @@ func TestHelpAll(t *testing.T) {
               The trice ID list file.
               The specified JSON file is needed to display the ID coded trices during runtime and should be under version control.
                (default "til.json")
+        -include value
+              Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+              This is a multi-flag switch. Example: "trice renew -include *.ino -include *.inc" handles also Arduino sketches and include files.
         -s value
               Short for src.
         -src value
@@ -296,8 +582,33 @@ func TestHelpAll(t *testing.T) {
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
       example: 'trice renew': Rebuild ID list from source tree, discard old IDs.
       sub-command 's|scan': Shows available serial ports)
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
       example: 'trice s': Show COM ports.
       sub-command 'sd|shutdown': Ends display server at IPA:IPP, works also on a remote machine.
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
+        -dsCA string
+              PEM certificate authority file to check the display server certificate with -dsTLS, like a self-signed server certificate.
+              Default "" means the system certificate authorities.
+
+        -dsTLS
+              Connect to the display server with TLS. This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -dsToken string
+              Shared secret between the display server and its clients. Default "" means no token.
+              A display server with a token refuses clients without it, otherwise it accepts control messages like shutdown only from the local host.
+              Use the environment variable TRICE_DSTOKEN or the config file to keep it out of the process list.
+
         -ipa string
               IP address like '127.0.0.1'.
               You can specify this switch if you intend to use the remote display option to show the output on a different PC in the network.
@@ -309,6 +620,13 @@ func TestHelpAll(t *testing.T) {
       example: 'trice sd': Shut down remote display server.
       sub-command 'ver|version': For displaying version information.
               "trice v" will print the version information. If trice is not versioned the build time will be displayed instead.
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -logfile string
               Append all output to logfile. Options are: 'off|none|filename|auto':
               "off": no logfile (same as "none")
@@ -332,12 +650,38 @@ func TestHelpAll(t *testing.T) {
               Search method for new ID's in range- Options are 'upward', 'downward' & 'random'. (default "random")
         -IDMin value
               Lower end of ID range for normal trices. (default 32768)
+        -IDRange value
+              ID range for source files matching a pattern in the form "pattern=min:max[:method]".
+              This is a multi-flag switch. The first range with a pattern matching a source file or one of its parent directories is used
+              for new IDs in this file. Files without matching range get new IDs from "-IDMin" to "-IDMax".
+              Ranges overlapping each other or the interval from "-IDMin" to "-IDMax" are rejected.
+              Example: "trice u -src boot -src app -src lib -IDRange boot=1000:1999 -IDRange lib/*=2000:4999:upward -IDRange app=5000:9999"
+              The pattern syntax is like in Go's path.Match and relative to the current directory.
         -addParamCount
               Extend TRICE macro names with the parameter count _n to enable compile time checks.
+        -cache string
+              Cache file for the TRICE macros found per source file, options: 'auto|off|none|filename'.
+              Only source files changed since the last run are parsed again. A file counts as changed, if its modification time, size or content hash differs.
+              "auto": Use a file inside the user cache directory with a name derived from the absolute ID list file path, like "~/.cache/trice/til.json-0123456789abcdef.cache".
+              "filename": Any other string is used as cache file name. Do not put the cache file under version control.
+              "off": No cache (same as "none").
+               (default "auto")
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -dry-run
               No changes applied but output shows what would happen.
               "trice update -dry-run" will change nothing but show changes it would perform without the "-dry-run" switch.
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -exclude value
+              File or directory pattern to skip inside the source trees. Skipped files are never changed.
+              This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+              A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+              The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.
         -i string
               Short for '-idlist'.
                (default "til.json")
@@ -348,6 +692,9 @@ func TestHelpAll(t *testing.T) {
               The trice ID list file.
               The specified JSON file is needed to display the ID coded trices during runtime and should be under version control.
                (default "til.json")
+        -include value
+              Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+              This is a multi-flag switch. Example: "trice update -include *.ino -include *.inc" handles also Arduino sketches and include files.
         -s value
               Short for src.
         -sharedIDs
@@ -373,10 +720,25 @@ func TestHelpAll(t *testing.T) {
       sub-command 'zeroSourceTreeIds': Set all Id(n) inside source tree dir to Id(0).
               Avoid using this sub-command normally. The switch "-src" is mandatory and no multi-flag here.
               This sub-command is mainly for testing. For several source directories you need several runs.
+        -config string
+              Config file with default flag values, options: YAML or JSON file name.
+              Without this switch the first file named trice.yaml, trice.yml or trice.json in the current directory or its parents is used, if any.
+              The config file has a section for each sub-command and a section "all" for flags of all sub-commands, like:
+              all: {til: "demo/til.json"}, log: {port: "COM3", baud: 115200}, update: {src: ["src", "lib"], IDMin: 1000}.
+              Command line flags override environment variables like TRICE_PORT=COM5 and these override config file values.
+
         -dry-run
               No changes applied but output shows what would happen.
               "trice zeroSourceTreeIds -dry-run" will change nothing but show changes it would perform without the "-dry-run" switch.
               This is a bool switch. It has no parameters. Its default value is false. If the switch is applied its value is true.
+        -exclude value
+              File or directory pattern to skip inside the source trees. Skipped files are never changed.
+              This is a multi-flag switch. A pattern without "/" matches file and directory names anywhere, like "third_party" or "*_gen.c".
+              A pattern with "/" matches the path relative to the source tree root, like "lib/vendor". A pattern ending with "/" matches only directories.
+              The same patterns are read line by line from .triceignore files. Their patterns are valid for the directory containing the file and below.
+        -include value
+              Additional source file name pattern. Files ending with .c, .h, .cc, .cpp or .hpp are always source files.
+              This is a multi-flag switch. Example: "trice zeroSourceTreeIds -include *.ino -include *.inc" handles also Arduino sketches and include files.
         -src string
               Zero all Id(n) inside source tree dir, required.
       example: 'trice zeroSourceTreeIds -src ../A': Sets all TRICE IDs to 0 in ../A. Use with care!
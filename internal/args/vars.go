@@ -25,6 +25,9 @@ var (
 	// used to replace "default" args value for STLINK and JLINK port
 	defaultLinkArgs = "-Device STM32F030R8 -if SWD -Speed 4000 -RTTChannel 0 -RTTSearchRanges 0x20000000_0x1000"
 
+	// used to replace "default" args value for OPENOCD port
+	defaultOpenOCDArgs = "-host localhost -tclPort 6666 -dataPort 19021 -RTTChannel 0 -RTTSearchRanges 0x20000000_0x1000"
+
 	// used to replace "default" args value for COMn port
 	defaultCOMArgs = ""
 
@@ -40,6 +43,12 @@ var (
 	// fsScUpdate is flag set for sub command 'update' for updating ID list.
 	fsScUpdate *flag.FlagSet
 
+	// fsScCheck is flag set for sub command 'check' for checking TRICE macros without touching the sources.
+	fsScCheck *flag.FlagSet
+
+	// fsScMerge is flag set for sub command 'merge' for merging ID lists from different branches.
+	fsScMerge *flag.FlagSet
+
 	// fsScHelp is flag set for sub command 'help'.
 	fsScHelp *flag.FlagSet
 
@@ -64,10 +73,18 @@ var (
 	// pSrcZ is a string pointer to the safety string for scZero.
 	pSrcZ *string
 
+	// configFile is the config file name given with "-config".
+	configFile string
+
+	// subCmdAliases are the sub-command names accepted in the command line besides the flag set names.
+	subCmdAliases = []string{"h", "s", "ver", "r", "lint", "u", "sd", "shutdown", "ds", "l"}
+
 	allHelp           bool // flag for partial help
+	checkHelp         bool // flag for partial help
 	displayServerHelp bool // flag for partial help
 	helpHelp          bool // flag for partial help
 	logHelp           bool // flag for partial help
+	mergeHelp         bool // flag for partial help
 	refreshHelp       bool // flag for partial help
 	renewHelp         bool // flag for partial help
 	scanHelp          bool // flag for partial help
@@ -58,7 +58,8 @@ func doCOBSTableTest(t *testing.T, out io.Writer, f newDecoder, endianness bool,
 				break
 			}
 			if ShowID != "" && lineStart {
-				act += fmt.Sprintf(ShowID, LastTriceID)
+				t, _ := dec.(triceProvider).lastTrice()
+				act += fmt.Sprintf(ShowID, t.ID)
 			}
 			act += fmt.Sprint(string(buf[:n]))
 			lineStart = false
@@ -79,6 +80,43 @@ func TestCOBS(t *testing.T) {
 	assert.Equal(t, "", out.String())
 }
 
+// TestCOBSInvalidData checks, that a truncated package is reported and the following trice is decoded.
+func TestCOBSInvalidData(t *testing.T) {
+	tt := testTable{
+		{[]byte{2, 1, 1, 1, 3, 208, 7, 1, 5, 192, 1, 196, 188, 1, 1, 1, 1, 0, 2, 1, 1, 1, 3, 209, 7, 0, 2, 1, 1, 1, 3, 209, 7, 1, 5, 193, 1, 205, 209, 1, 2, 28, 1, 0},
+			"MSG: START select = 0, TriceDepthMax =   0\\nERROR:Decoded trice COBS package has not expected  multiple of 4 len. The len is 6 [1 0 0 0 209 7]\nMSG: STOP  select = 0, TriceDepthMax =  28"},
+	}
+	var out bytes.Buffer
+	doCOBSTableTest(t, &out, NewCOBSDecoder, LittleEndian, tt)
+	assert.Equal(t, "", out.String())
+}
+
+// FuzzCOBSDecoder feeds random bytes into a COBS decoder. It must neither panic nor exit.
+// Run it with "go test ./internal/decoder -run XXX -fuzz FuzzCOBSDecoder".
+func FuzzCOBSDecoder(f *testing.F) {
+	f.Add([]byte{2, 1, 1, 1, 3, 208, 7, 1, 5, 192, 1, 196, 188, 1, 1, 1, 1, 0}, LittleEndian)
+	f.Add([]byte{2, 1, 1, 1, 3, 209, 7, 1, 5, 193, 1, 205, 209, 1, 2, 28, 1, 0}, BigEndian)
+	f.Add([]byte{1, 1, 1, 1, 3, 192, 4, 1, 2, 1, 2, 2, 1, 1, 1, 0}, LittleEndian)
+	lu := make(id.TriceIDLookUp)
+	assert.Nil(f, lu.FromJSON([]byte(`{
+		"1": {"Type": "TRICE_S", "Strg": "%s"},
+		"2": {"Type": "TRICE8", "Strg": "%d %u %x"},
+		"3": {"Type": "TRICE64_2", "Strg": "%d %b"},
+		"48324": {"Type": "TRICE16", "Strg": "MSG: START select = %d, TriceDepthMax =%4u\\n"}
+	}`)))
+	m := new(sync.RWMutex)
+	b := make([]byte, defaultSize)
+	f.Fuzz(func(t *testing.T, in []byte, endian bool) {
+		dec := NewCOBSDecoder(ioutil.Discard, lu, m, bytes.NewReader(in), endian)
+		for i := 0; i <= len(in); i++ { // each Read consumes at least one byte
+			n, err := dec.Read(b)
+			if nil != err && 0 == n {
+				return
+			}
+		}
+	})
+}
+
 // used command to get sequences: "trice l -p COM1 -s -debug"
 //        02 01 01 01 03 d0 07 01 05 c0 01 c4 bc 01 01 01 01 00 00 00 02 01 01 01 03 d1 07 01 05 c1 01 cd d1 01 02 1c 01 00 00 00
 //  COBS: 02 01 01 01 03 d0 07 01 05 c0 01 c4 bc 01 01 01 01 00
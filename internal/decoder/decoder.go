@@ -5,13 +5,11 @@
 package decoder
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
-	"regexp"
 	"strings"
 	"sync"
 	"syscall"
@@ -21,6 +19,7 @@ import (
 	"github.com/rokath/trice/internal/id"
 	"github.com/rokath/trice/internal/receiver"
 	"github.com/rokath/trice/pkg/msg"
+	"github.com/rokath/trice/pkg/trice"
 )
 
 const (
@@ -32,27 +31,6 @@ const (
 
 	// flag value for TargetEndianess
 	BigEndian = false
-
-	// patNextFormatSpecifier is a regex to find next format specifier in a string (exclude %%*) and ignoring %s
-	//
-	// Language C plus from language Go: %b, %F, %q
-	// Partial implemented: %hi, %hu, %ld, %li, %lf, %Lf, %Lu, %lli, %lld
-	// Not implemented: %s
-	patNextFormatSpecifier = `(?:^|[^%])(%[0-9]*(-|c|d|e|E|f|F|g|G|h|i|l|L|o|O|p|q|u|x|X|n|b))`
-
-	// patNextFormatUSpecifier is a regex to find next format u specifier in a string
-	// It does also match %%u positions! so an additional check must follow.
-	patNextFormatUSpecifier = `(?:%[0-9]*u)`
-
-	// patNextFormatXSpecifier is a regex to find next format x specifier in a string
-	// It does also match %%x positions! so an additional check must follow.
-	patNextFormatXSpecifier = `(?:%[0-9]*(l|o|O|x|X|b))`
-
-	// headSize is 4; each trice message starts with a head of 4 bytes.
-	headSize = 4
-
-	// hints is the help information in case of errors.
-	hints = "att:Hints:Baudrate? Overflow? Encoding? Interrupt? til.json? Password?"
 )
 
 var (
@@ -62,8 +40,8 @@ var (
 	// ShowID is used as format string for displaying the first trice ID at the start of each line if not "".
 	ShowID string
 
-	// LastTriceID is last decoded ID. It is used for switch -showID.
-	LastTriceID id.TriceID
+	// ShowSource if true, displays the source location "file:line" of the first trice at the start of each line.
+	ShowSource bool
 
 	// Encoding describes the way the byte stream is coded.
 	Encoding string
@@ -78,24 +56,14 @@ var (
 	// Unsigned if true, forces hex and in values printed as unsigned values.
 	Unsigned bool
 
-	matchNextFormatSpecifier  = regexp.MustCompile(patNextFormatSpecifier)
-	matchNextFormatUSpecifier = regexp.MustCompile(patNextFormatUSpecifier)
-	matchNextFormatXSpecifier = regexp.MustCompile(patNextFormatXSpecifier)
-
 	// DebugOut enables debug information.
 	DebugOut = false
 
 	// DumpLineByteCount is the bytes per line for the DUMP decoder.
 	DumpLineByteCount int
 
-	// initialCycle is a helper for the cycle counter automatic.
-	initialCycle = true
-
-	targetTimestamp uint32
-
+	// ShowTargetTimestamp is the format string for the raw target timestamps at the start of each line. "" suppresses the target timestamps.
 	ShowTargetTimestamp string
-
-	targetTimestampExists bool
 )
 
 // newDecoder abstracts the function type for a new decoder.
@@ -108,21 +76,19 @@ type Decoder interface {
 	setInput(io.Reader)
 }
 
+// triceProvider is implemented by decoders able to deliver the structured data of the last decoded trice.
+type triceProvider interface {
+	lastTrice() (t trice.Trice, ok bool)
+}
+
 // decoderData is the common data struct for all decoders.
 type decoderData struct {
-	w          io.Writer        // io.Stdout or the like
-	in         io.Reader        // in is the inner reader, which is used to get raw bytes
-	iBuf       []byte           // iBuf holds unprocessed (raw) bytes for interpretation.
-	b          []byte           // read buffer holds a single decoded COBS package, which can contain several trices.
-	endian     bool             // endian is true for LittleEndian and false for BigEndian
-	triceSize  int              // trice head and payload size as number of bytes
-	paramSpace int              // trice payload size after head
-	sLen       int              // string length for TRICE_S
-	lut        id.TriceIDLookUp // id look-up map for translation
-	lutMutex   *sync.RWMutex    // to avoid concurrent map read and map write during map refresh triggered by filewatcher
-	trice      id.TriceFmt      // id.TriceFmt // received trice
-	//lastInnerRead     time.Time
-	//innerReadInterval time.Duration
+	w        io.Writer        // io.Stdout or the like
+	in       io.Reader        // in is the inner reader, which is used to get raw bytes
+	iBuf     []byte           // iBuf holds unprocessed (raw) bytes for interpretation.
+	endian   bool             // endian is true for LittleEndian and false for BigEndian
+	lut      id.TriceIDLookUp // id look-up map for translation
+	lutMutex *sync.RWMutex    // to avoid concurrent map read and map write during map refresh triggered by filewatcher
 }
 
 // setInput allows switching the input stream to a different source.
@@ -132,8 +98,9 @@ func (p *decoderData) setInput(r io.Reader) {
 	p.in = r
 }
 
-// handleSIGTERM is called on CTRL-C shutdown.
-func handleSIGTERM(w io.Writer, rc io.ReadCloser) {
+// HandleSIGTERM is the CTRL-C shutdown reaction of a log session. It is started once per session.
+// On a signal it writes the statistics, calls closeInputs to close the actual input readers and ends the program.
+func HandleSIGTERM(w io.Writer, closeInputs func() error) {
 	// prepare CTRL-C shutdown reaction
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -144,34 +111,52 @@ func handleSIGTERM(w io.Writer, rc io.ReadCloser) {
 			if Verbose {
 				fmt.Fprintln(w, "####################################", sig, "####################################")
 			}
+			writeStats(w, true, time.Now())
 			emitter.PrintColorChannelEvents(w)
-			msg.FatalOnErr(rc.Close())
+			msg.FatalOnErr(closeInputs())
 			os.Exit(0) // end
 		case <-ticker.C:
 		}
 	}
 }
 
+// Setup contains the decoder settings for one receiver port.
+type Setup struct {
+	Port            string // Port is the receiver port name, like "COM3".
+	Encoding        string // Encoding describes the way the byte stream is coded.
+	TargetEndianess string // TargetEndianess is "littleEndian" or "bigEndian".
+
+	// Sources are the source locations of the trice IDs used with ShowSource. They are guarded by the look-up mutex.
+	Sources id.TriceIDInfo
+}
+
 // Translate performs the trice log task.
 //
 // Bytes are read with rc. Then according decoder.Encoding they are translated into strings.
 // Each read returns the amount of bytes for one trice. rc is called on every
-// Translate returns true on io.EOF or false on hard read error or sigterm.
+// Translate returns io.EOF at the end of a predefined buffer or input stream or the read error on a hard read error, like a lost TCP connection.
 func Translate(w io.Writer, sw *emitter.TriceLineComposer, lut id.TriceIDLookUp, m *sync.RWMutex, rc io.ReadCloser) error {
+	s := Setup{Port: receiver.Port, Encoding: Encoding, TargetEndianess: TargetEndianess}
+	return s.Translate(w, sw, lut, m, rc)
+}
+
+// Translate performs the trice log task like the function Translate but with the settings s.
+// Each call uses its own decoder instance, so several Translate calls can run concurrently for different ports.
+func (s Setup) Translate(w io.Writer, sw *emitter.TriceLineComposer, lut id.TriceIDLookUp, m *sync.RWMutex, rc io.ReadCloser) error {
 	var dec Decoder //io.Reader
 	if Verbose {
-		fmt.Fprintln(w, "Encoding is", Encoding)
+		fmt.Fprintln(w, s.Port, "encoding is", s.Encoding)
 	}
 	var endian bool
-	switch TargetEndianess {
+	switch s.TargetEndianess {
 	case "littleEndian":
 		endian = LittleEndian
 	case "bigEndian":
 		endian = BigEndian
 	default:
-		log.Fatalf(fmt.Sprintln("unknown endianness ", TargetEndianess, "-accepting litteEndian or bigEndian."))
+		log.Fatalf(fmt.Sprintln("unknown endianness ", s.TargetEndianess, "-accepting litteEndian or bigEndian."))
 	}
-	switch strings.ToUpper(Encoding) {
+	switch strings.ToUpper(s.Encoding) {
 	case "COBS":
 		dec = NewCOBSDecoder(w, lut, m, rc, endian)
 	case "CHAR":
@@ -179,19 +164,33 @@ func Translate(w io.Writer, sw *emitter.TriceLineComposer, lut id.TriceIDLookUp,
 	case "DUMP":
 		dec = NewDUMPDecoder(w, lut, m, rc, endian)
 	default:
-		log.Fatalf(fmt.Sprintln("unknown encoding ", Encoding))
+		log.Fatalf(fmt.Sprintln("unknown encoding ", s.Encoding))
 	}
-	go handleSIGTERM(w, rc)
-	return decodeAndComposeLoop(w, sw, dec)
+	ps := startStats(w, s.Port, dec)
+	err := decodeAndComposeLoop(w, sw, dec, s.Port, s.Sources, m, ps)
+	ps.stop()
+	if io.EOF == err {
+		writePortStats(w, s.Port)
+	}
+	return err
 }
 
-// decodeAndComposeLoop does not return.
-func decodeAndComposeLoop(w io.Writer, sw *emitter.TriceLineComposer, dec Decoder) error {
+// decodeAndComposeLoop returns only at the end of a predefined buffer or on a hard read error.
+// In the hard read error case the caller can set up the input port again.
+// sources, guarded by m, are used for ShowSource. The decoded trices are counted by ID in ps, if not nil.
+func decodeAndComposeLoop(w io.Writer, sw *emitter.TriceLineComposer, dec Decoder, port string, sources id.TriceIDInfo, m *sync.RWMutex, ps *portStats) error {
 	b := make([]byte, defaultSize) // intermediate trice string buffer
+	var tt targetTime
 	for {
 		n, err := dec.Read(b) // Code to measure
+		if err != nil && err != io.EOF && n == 0 {
+			if Verbose {
+				fmt.Fprintln(w, err, "-> RETURNING...")
+			}
+			return err
+		}
 		if (err == io.EOF || err == nil) && n == 0 {
-			if receiver.Port == "BUFFER" || receiver.Port == "DUMP" { // do not wait for a predefined buffer
+			if err == io.EOF && (port == "BUFFER" || port == "DUMP" || port == "FILE" || port == "STDIN") { // do not wait for a predefined buffer or a stream end
 				return err
 			}
 			if Verbose {
@@ -199,6 +198,12 @@ func decodeAndComposeLoop(w io.Writer, sw *emitter.TriceLineComposer, dec Decode
 			}
 			continue // read again
 		}
+		if tp, ok := dec.(triceProvider); ok {
+			if t, ok := tp.lastTrice(); ok {
+				ps.countID(t.ID)
+				tt.update(t, time.Now())
+			}
+		}
 		// b contains here no or several complete trice strings.
 		// If several, they end with a newline, despite the last one which optionally ends with a newline.
 
@@ -207,81 +212,76 @@ func decodeAndComposeLoop(w io.Writer, sw *emitter.TriceLineComposer, dec Decode
 
 		start := time.Now()
 
-		var tts bool
-		if targetTimestampExists && 0 < n && ShowTargetTimestamp != "" && len(sw.Line) == 0 {
-			s := fmt.Sprintf(ShowTargetTimestamp, targetTimestamp)
-			_, err := sw.Write([]byte(s))
-			msg.OnErr(err)
-			if ShowID != "" {
-				tts = true
+		if sw.StructuredOutput() {
+			if 0 < n {
+				writeTrice(sw, dec, start, b[:n])
+			}
+			continue
+		}
+
+		var t trice.Trice // stays zero, if the last decode failed
+		if tp, ok := dec.(triceProvider); ok {
+			if x, ok := tp.lastTrice(); ok {
+				t = x
 			}
 		}
 
-		if tts || (0 < n && ShowID != "" && len(sw.Line) == 0) {
-			// dec.Read can return n=0 in some cases and then wait.
-			s := fmt.Sprintf(ShowID, LastTriceID)
+		lineStart := 0 < n && len(sw.Line) == 0 // dec.Read can return n=0 in some cases and then wait.
+		if lineStart && t.HasTargetTimestamp && ShowTargetTimestamp != "" {
+			_, err := sw.Write([]byte(tt.format(t.TargetTimestamp)))
+			msg.OnErr(err)
+		}
+
+		if lineStart && ShowID != "" {
+			s := fmt.Sprintf(ShowID, t.ID)
 			_, err := sw.Write([]byte(s))
 			msg.OnErr(err)
-			tts = false
 		}
-		m, err := sw.Write(b[:n])
+
+		if lineStart && ShowSource && nil != sources {
+			m.RLock()
+			s := sources.Location(t.ID)
+			m.RUnlock()
+			if s != "" {
+				_, err := sw.Write([]byte(s + " "))
+				msg.OnErr(err)
+			}
+		}
+		k, err := sw.Write(b[:n])
 		duration := time.Since(start).Milliseconds()
 		if duration > 100 {
 			fmt.Fprintln(w, "TriceLineComposer.Write duration =", duration, "ms.")
 		}
-		msg.InfoOnErr(err, fmt.Sprintln("sw.Write wrote", m, "bytes"))
-	}
-}
-
-// readU16 returns the 2 b bytes as uint16 according the specified endianness
-func (p *decoderData) readU16(b []byte) uint16 {
-	if p.endian {
-		return binary.LittleEndian.Uint16(b)
+		msg.InfoOnErr(err, fmt.Sprintln("sw.Write wrote", k, "bytes"))
 	}
-	return binary.BigEndian.Uint16(b)
 }
 
-// readU32 returns the 4 b bytes as uint32 according the specified endianness
-func (p *decoderData) readU32(b []byte) uint32 {
-	if p.endian {
-		return binary.LittleEndian.Uint32(b)
+// writeTrice writes the last decoded trice as structured data to sw.
+// Additional messages in b, like cycle errors, are written around it as plain text.
+// If dec delivers no structured data, only the text b is used.
+func writeTrice(sw *emitter.TriceLineComposer, dec Decoder, pcTime time.Time, b []byte) {
+	s := string(b)
+	var t trice.Trice
+	var ok bool
+	if tp, isProvider := dec.(triceProvider); isProvider {
+		t, ok = tp.lastTrice()
 	}
-	return binary.BigEndian.Uint32(b)
-}
-
-// readU64 returns the 8 b bytes as uint64 according the specified endianness
-func (p *decoderData) readU64(b []byte) uint64 {
-	if p.endian {
-		return binary.LittleEndian.Uint64(b)
+	i := strings.Index(s, t.Text)
+	if !ok || i < 0 {
+		t = trice.Trice{Text: s}
+		i = 0
 	}
-	return binary.BigEndian.Uint64(b)
+	t.PCTime = pcTime
+	writeMessages(sw, pcTime, s[:i])
+	msg.OnErr(sw.WriteTrice(t))
+	writeMessages(sw, pcTime, s[i+len(t.Text):])
 }
 
-// uReplaceN checks all format specifier in i and replaces %nu with %nd and returns that result as o.
-//
-// If a replacement took place on position k u[k] is true. Afterwards len(u) is amount of found format specifiers.
-// Additionall, if UnsignedHex is true, for FormatX specifiers u[k] is also true.
-func uReplaceN(i string) (o string, u []bool) {
-	o = i
-	s := i
-	var offset int
-	for {
-		loc := matchNextFormatSpecifier.FindStringIndex(s)
-		if nil == loc { // no (more) fm found
-			return
-		}
-		offset += loc[1] // track position
-		fm := s[loc[0]:loc[1]]
-		locU := matchNextFormatUSpecifier.FindStringIndex(fm)
-		locX := matchNextFormatXSpecifier.FindStringIndex(fm)
-		if nil != locU { // a %nu found
-			o = o[:offset-1] + "d" + o[offset:] // replace %nu -> %nd
-			u = append(u, true)
-		} else if nil != locX && Unsigned { // a %nx, %nX or, %no, %nO or %nb found
-			u = append(u, true) // no negative values
-		} else { // keep sign
-			u = append(u, false)
+// writeMessages writes each non-empty line in s as structured data without trice information to sw.
+func writeMessages(sw *emitter.TriceLineComposer, pcTime time.Time, s string) {
+	for _, line := range strings.Split(s, "\n") {
+		if line != "" {
+			msg.OnErr(sw.WriteTrice(trice.Trice{PCTime: pcTime, Text: line}))
 		}
-		s = i[offset:] // remove processed part
 	}
 }
@@ -0,0 +1,140 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package decoder
+
+// Prometheus metrics
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rokath/trice/internal/emitter"
+	"github.com/rokath/trice/internal/id"
+	"github.com/rokath/trice/pkg/trice"
+)
+
+// MetricsAddr is the HTTP listen address for the metrics, like ":9100". "" means no metrics.
+var MetricsAddr string
+
+const (
+	// metricsPath is the URL path of the metrics.
+	metricsPath = "/metrics"
+
+	// rateWindow is the update period of the byte rate gauge. It is fixed, so the rate does not depend on the scrape interval.
+	rateWindow = 10 * time.Second
+)
+
+// ServeMetrics listens on addr and serves the decoder statistics in the Prometheus text format under metricsPath.
+// It returns after the listener is set up. The metrics are served until the process ends.
+func ServeMetrics(w io.Writer, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if nil != err {
+		return err
+	}
+	if Verbose {
+		fmt.Fprintln(w, "Serving metrics on http://"+ln.Addr().String()+metricsPath)
+	}
+	go serveMetrics(ln)
+	go rateLoop(rateWindow)
+	return nil
+}
+
+// rateLoop updates the byte rates of all ports every window.
+func rateLoop(window time.Duration) {
+	for now := range time.Tick(window) {
+		updateRates(now)
+	}
+}
+
+// updateRates computes the byte rates of all ports since the last update or the port start.
+func updateRates(now time.Time) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	for _, s := range statsPorts {
+		x := s.total()
+		if d := now.Sub(s.rateTime).Seconds(); 0 < d {
+			s.byteRate = float64(x.Bytes-s.rateBytes) / d
+		}
+		s.rateBytes, s.rateTime = x.Bytes, now
+	}
+}
+
+// serveMetrics serves the metrics on ln.
+func serveMetrics(ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, metricsHandler)
+	return http.Serve(ln, mux)
+}
+
+// metricsHandler writes the actual metrics.
+func metricsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w)
+}
+
+// portCounter describes a counter metric per port taken from trice.Stats.
+type portCounter struct {
+	name  string
+	help  string
+	value func(trice.Stats) int
+}
+
+var portCounters = []portCounter{
+	{"trice_received_bytes_total", "Received bytes.", func(x trice.Stats) int { return x.Bytes }},
+	{"trice_packages_total", "Received COBS packages, valid or not.", func(x trice.Stats) int { return x.Packages }},
+	{"trice_trices_total", "Decoded trices.", func(x trice.Stats) int { return x.Trices }},
+	{"trice_decode_errors_total", "Dropped invalid COBS packages and trices.", func(x trice.Stats) int { return x.Errors }},
+	{"trice_unknown_ids_total", "Trices with an ID not inside the ID list.", func(x trice.Stats) int { return x.UnknownIDs }},
+	{"trice_cycle_losses_total", "Cycle counter mismatches, each meaning lost data.", func(x trice.Stats) int { return x.CycleGaps }},
+	{"trice_target_resets_total", "Detected target resets.", func(x trice.Stats) int { return x.TargetResets }},
+}
+
+// writeMetrics writes the statistics of all ports and the channel events in the Prometheus text format to w.
+// The byte rate is the one of the last complete rate window. Other rates are left to the scraper, like rate(trice_trices_total[1m]).
+func writeMetrics(w io.Writer) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	totals := make([]trice.Stats, len(statsPorts))
+	for i, s := range statsPorts {
+		totals[i] = s.total()
+	}
+	for _, c := range portCounters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for i, s := range statsPorts {
+			fmt.Fprintf(w, "%s{port=%q} %d\n", c.name, s.port, c.value(totals[i]))
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP trice_received_bytes_per_second Received bytes per second within the last %v.\n# TYPE trice_received_bytes_per_second gauge\n", rateWindow)
+	for _, s := range statsPorts {
+		fmt.Fprintf(w, "trice_received_bytes_per_second{port=%q} %g\n", s.port, s.byteRate)
+	}
+
+	fmt.Fprint(w, "# HELP trice_id_trices_total Decoded trices by ID.\n# TYPE trice_id_trices_total counter\n")
+	for _, s := range statsPorts {
+		ids := make([]int, 0, len(s.ids))
+		for i := range s.ids {
+			ids = append(ids, int(i))
+		}
+		sort.Ints(ids)
+		for _, i := range ids {
+			fmt.Fprintf(w, "trice_id_trices_total{port=%q,id=\"%d\"} %d\n", s.port, i, s.ids[id.TriceID(i)])
+		}
+	}
+
+	fmt.Fprint(w, "# HELP trice_channel_events_total Displayed lines by channel, like error or warning.\n# TYPE trice_channel_events_total counter\n")
+	events := emitter.ChannelEvents()
+	channels := make([]string, 0, len(events))
+	for c := range events {
+		channels = append(channels, c)
+	}
+	sort.Strings(channels)
+	for _, c := range channels {
+		fmt.Fprintf(w, "trice_channel_events_total{channel=%q} %d\n", c, events[c])
+	}
+}
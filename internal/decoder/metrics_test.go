@@ -0,0 +1,52 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package decoder
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/rokath/trice/pkg/trice"
+	"github.com/tj/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	defer func(ports []*portStats) { statsPorts = ports }(statsPorts)
+	statsPorts = nil
+	s := startStats(ioutil.Discard, "COM3", fakeStats{s: trice.Stats{Bytes: 1000, Packages: 30, Trices: 30, Errors: 1, CycleGaps: 2}})
+	s.countID(48324)
+	s.countID(53709)
+	s.countID(48324)
+	updateRates(s.rateTime.Add(rateWindow))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go serveMetrics(ln)
+
+	scrape := func() string {
+		resp, err := http.Get("http://" + ln.Addr().String() + metricsPath)
+		assert.Nil(t, err)
+		b, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, resp.Body.Close())
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain; version=0.0.4")
+		return string(b)
+	}
+	m := scrape()
+	assert.Contains(t, m, "# TYPE trice_received_bytes_total counter\ntrice_received_bytes_total{port=\"COM3\"} 1000\n")
+	assert.Contains(t, m, "trice_decode_errors_total{port=\"COM3\"} 1\n")
+	assert.Contains(t, m, "trice_cycle_losses_total{port=\"COM3\"} 2\n")
+	assert.Contains(t, m, "trice_id_trices_total{port=\"COM3\",id=\"48324\"} 2\ntrice_id_trices_total{port=\"COM3\",id=\"53709\"} 1\n")
+	assert.Contains(t, m, "# TYPE trice_channel_events_total counter\n")
+	assert.Contains(t, m, "trice_channel_events_total{channel=\"warning\"} ")
+	assert.Contains(t, m, "# TYPE trice_received_bytes_per_second gauge\ntrice_received_bytes_per_second{port=\"COM3\"} 100\n")
+	assert.Contains(t, scrape(), "trice_received_bytes_per_second{port=\"COM3\"} 100\n") // a scrape does not change the rate
+
+	updateRates(s.rateTime.Add(rateWindow)) // no new bytes
+	assert.Contains(t, scrape(), "trice_received_bytes_per_second{port=\"COM3\"} 0\n")
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package decoder
+
+// link quality statistics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rokath/trice/internal/emitter"
+	"github.com/rokath/trice/internal/id"
+	"github.com/rokath/trice/pkg/trice"
+)
+
+// StatsInterval is the period for writing the decoder statistics. 0 means writing them only at shutdown.
+var StatsInterval time.Duration
+
+// statsProvider is implemented by decoders counting link quality values.
+type statsProvider interface {
+	Stats() trice.Stats
+}
+
+// portStats holds the statistics of one port over several decoder instances, for example after a lost TCP connection.
+type portStats struct {
+	port     string        // port is the receiver port name, like "COM3".
+	start    time.Time     // start is the time of the first decoder start for this port.
+	done     trice.Stats   // done are the sums of the counters of the finished decoders.
+	dec      statsProvider // dec is the actual decoder or nil.
+	last     trice.Stats   // last are the counters at the last output.
+	lastTime time.Time     // lastTime is the time of the last output.
+
+	ids map[id.TriceID]int // ids are the decoded trice counts by ID.
+
+	rateBytes int       // rateBytes is the received byte count at rateTime.
+	rateTime  time.Time // rateTime is the time of the last byte rate update.
+	byteRate  float64   // byteRate is the received bytes per second inside the last rate window.
+}
+
+// jsonStats is the JSON line representation of a portStats.
+type jsonStats struct {
+	Time  string `json:"time"`
+	Port  string `json:"port"`
+	Final bool   `json:"final,omitempty"`
+	trice.Stats
+	TricesPerSecond float64 `json:"tricesPerSecond"`
+}
+
+var (
+	statsMutex sync.Mutex   // statsMutex guards statsPorts and their values.
+	statsPorts []*portStats // statsPorts are the ports in start order.
+	statsOnce  sync.Once    // statsOnce starts the periodic output only once for all ports.
+)
+
+// startStats adds dec to the statistics of port. Call stop, when dec is finished.
+// Decoders without counters are ignored and result in nil. With StatsInterval the periodic output to w starts.
+func startStats(w io.Writer, port string, dec Decoder) *portStats {
+	sp, ok := dec.(statsProvider)
+	if !ok {
+		return nil
+	}
+	statsMutex.Lock()
+	var s *portStats
+	for _, x := range statsPorts {
+		if x.port == port {
+			s = x
+		}
+	}
+	if nil == s {
+		now := time.Now()
+		s = &portStats{port: port, start: now, lastTime: now, ids: make(map[id.TriceID]int), rateTime: now}
+		statsPorts = append(statsPorts, s)
+	}
+	s.dec = sp
+	statsMutex.Unlock()
+	if 0 < StatsInterval {
+		statsOnce.Do(func() { go statsLoop(w, StatsInterval) })
+	}
+	return s
+}
+
+// stop adds the counters of the actual decoder to the finished ones.
+func (s *portStats) stop() {
+	if nil == s {
+		return
+	}
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	if nil != s.dec {
+		s.done = s.done.Add(s.dec.Stats())
+		s.dec = nil
+	}
+}
+
+// countID counts a decoded trice with ID i.
+func (s *portStats) countID(i id.TriceID) {
+	if nil == s {
+		return
+	}
+	statsMutex.Lock()
+	s.ids[i]++
+	statsMutex.Unlock()
+}
+
+// statsLoop writes the statistics every interval to w.
+func statsLoop(w io.Writer, interval time.Duration) {
+	for now := range time.Tick(interval) {
+		writeStats(w, false, now)
+	}
+}
+
+// writeStats writes the statistics of all ports to w. It is called periodically and with final set at shutdown.
+func writeStats(w io.Writer, final bool, now time.Time) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	for _, s := range statsPorts {
+		s.write(w, final, now)
+	}
+}
+
+// writePortStats writes the final statistics of port to w, if the periodic output is active.
+// It is used at the end of a predefined input, like a file, where no shutdown happens.
+func writePortStats(w io.Writer, port string) {
+	if 0 == StatsInterval {
+		return
+	}
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	for _, s := range statsPorts {
+		if s.port == port {
+			s.write(w, true, time.Now())
+		}
+	}
+}
+
+// total returns the counters of all decoders of s.
+func (s *portStats) total() trice.Stats {
+	if nil == s.dec {
+		return s.done
+	}
+	return s.done.Add(s.dec.Stats())
+}
+
+// write writes the statistics of s to w as text line or, with JSON output format, as JSON line.
+// The trice rate is computed since the last output or, if final, since the start.
+func (s *portStats) write(w io.Writer, final bool, now time.Time) {
+	x := s.total()
+	n, since := x.Trices-s.last.Trices, s.lastTime
+	if final {
+		n, since = x.Trices, s.start
+	}
+	var rate float64
+	if d := now.Sub(since).Seconds(); 0 < d {
+		rate = float64(n) / d
+	}
+	s.last, s.lastTime = x, now
+	if "json" == emitter.Format {
+		b, err := json.Marshal(jsonStats{now.Format(time.RFC3339Nano), s.port, final, x, rate})
+		if nil == err {
+			fmt.Fprintln(w, string(b))
+		}
+		return
+	}
+	fmt.Fprintf(w, "stats %s: bytes=%d packages=%d trices=%d trices/s=%.1f errors=%d unknownIDs=%d cycleGaps=%d targetResets=%d\n",
+		s.port, x.Bytes, x.Packages, x.Trices, rate, x.Errors, x.UnknownIDs, x.CycleGaps, x.TargetResets)
+}
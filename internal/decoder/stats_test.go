@@ -0,0 +1,65 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rokath/trice/internal/emitter"
+	"github.com/rokath/trice/pkg/trice"
+	"github.com/tj/assert"
+)
+
+// fakeStats is a statsProvider with fixed counters.
+type fakeStats struct {
+	Decoder
+	s trice.Stats
+}
+
+func (p fakeStats) Stats() trice.Stats {
+	return p.s
+}
+
+func TestStats(t *testing.T) {
+	defer func(ports []*portStats, format string) { statsPorts, emitter.Format = ports, format }(statsPorts, emitter.Format)
+	statsPorts = nil
+	emitter.Format = "text"
+
+	startStats(ioutil.Discard, "TCP:a:1", fakeStats{s: trice.Stats{Bytes: 100, Packages: 5, Trices: 5, CycleGaps: 1}}).stop() // lost connection
+	startStats(ioutil.Discard, "TCP:a:1", fakeStats{s: trice.Stats{Bytes: 50, Packages: 3, Trices: 3, Errors: 1}})
+	startStats(ioutil.Discard, "COM3", fakeStats{s: trice.Stats{Bytes: 8, Packages: 1, Trices: 1, UnknownIDs: 2, TargetResets: 1}})
+	assert.Nil(t, startStats(ioutil.Discard, "DUMP", NewDUMPDecoder(ioutil.Discard, nil, nil, nil, LittleEndian))) // no statistics
+	begin := time.Now()
+	for _, s := range statsPorts {
+		s.start, s.lastTime = begin, begin
+	}
+	var out bytes.Buffer
+	now := begin.Add(4 * time.Second)
+	writeStats(&out, false, now)
+	assert.Equal(t, `stats TCP:a:1: bytes=150 packages=8 trices=8 trices/s=2.0 errors=1 unknownIDs=0 cycleGaps=1 targetResets=0
+stats COM3: bytes=8 packages=1 trices=1 trices/s=0.2 errors=0 unknownIDs=2 cycleGaps=0 targetResets=1
+`, out.String())
+
+	out.Reset()
+	emitter.Format = "json"
+	writeStats(&out, false, now.Add(time.Second)) // no new trices
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	var x jsonStats
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &x))
+	assert.Equal(t, "TCP:a:1", x.Port)
+	assert.Equal(t, 150, x.Bytes)
+	assert.Equal(t, 0.0, x.TricesPerSecond)
+	assert.False(t, x.Final)
+
+	out.Reset()
+	writeStats(&out, true, now.Add(time.Second))
+	assert.Contains(t, out.String(), `"tricesPerSecond":1.6`) // 8 trices in 5 seconds
+	assert.Contains(t, out.String(), `"final":true`)
+}
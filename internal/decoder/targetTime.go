@@ -0,0 +1,104 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package decoder
+
+// target timestamp conversion
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rokath/trice/pkg/trice"
+)
+
+var (
+	// TargetTimestampFrequency is the tick frequency of the target timestamps in Hz. 0 means showing the raw ticks with ShowTargetTimestamp.
+	TargetTimestampFrequency float64
+
+	// TargetTimeUnit is the display unit of the target timestamps, options: "s", "ms", "us" or "µs" and "abs" for the local time.
+	TargetTimeUnit string
+
+	// TargetTimeSync is the ID of a sync trice. Its reception time is the reference for the target timestamps.
+	// 0 means the first trice is the reference.
+	TargetTimeSync int
+
+	// TargetTimeDelta if true, shows additionally the target time since the previous trice.
+	TargetTimeDelta bool
+)
+
+// targetTime converts the 32-bit target timestamps of one port into times.
+// It counts the ticks over wraparounds, assuming less than half a wraparound between two trices.
+// A bigger step backwards is a target reset and makes the trice the new reference.
+type targetTime struct {
+	valid    bool      // valid is true after the first timestamp.
+	last     uint32    // last is the previous target timestamp.
+	ticks    int64     // ticks is the tick count of the last timestamp since the first one.
+	delta    int64     // delta is the tick count since the previous timestamp.
+	refTicks int64     // refTicks is the tick count at the reference time.
+	refTime  time.Time // refTime is the PC reception time of the reference trice.
+}
+
+// update adds the target timestamp of t received at pcTime.
+// The first trice, each sync trice and the first trice after a target reset become the reference.
+func (p *targetTime) update(t trice.Trice, pcTime time.Time) {
+	if !t.HasTargetTimestamp {
+		return
+	}
+	if !p.valid {
+		p.valid = true
+		p.last, p.refTime = t.TargetTimestamp, pcTime
+		return
+	}
+	if int32(t.TargetTimestamp-p.last) < 0 { // a wraparound would need more than half a wrap time without trices, so the target restarted
+		p.delta = 0
+		p.last, p.refTicks, p.refTime = t.TargetTimestamp, p.ticks, pcTime
+		return
+	}
+	p.delta = int64(t.TargetTimestamp - p.last) // the uint32 difference handles the wraparound
+	p.ticks += p.delta
+	p.last = t.TargetTimestamp
+	if 0 != TargetTimeSync && TargetTimeSync == int(t.ID) {
+		p.refTicks, p.refTime = p.ticks, pcTime
+	}
+}
+
+// seconds converts ticks into seconds.
+func seconds(ticks int64) float64 {
+	return float64(ticks) / TargetTimestampFrequency
+}
+
+// format returns the target time display of the last update for the start of a line.
+func (p *targetTime) format(ts uint32) string {
+	if "" == ShowTargetTimestamp {
+		return ""
+	}
+	if 0 >= TargetTimestampFrequency {
+		s := fmt.Sprintf(ShowTargetTimestamp, ts)
+		if TargetTimeDelta {
+			s += fmt.Sprintf(" +%d ", p.delta)
+		}
+		return s
+	}
+	t := seconds(p.ticks - p.refTicks)
+	d := seconds(p.delta)
+	switch TargetTimeUnit {
+	case "s":
+		return withDelta(fmt.Sprintf("tim:%12.6fs", t), fmt.Sprintf("+%.6fs", d))
+	case "us", "µs":
+		return withDelta(fmt.Sprintf("tim:%12.0fµs", t*1e6), fmt.Sprintf("+%.0fµs", d*1e6))
+	case "abs":
+		a := p.refTime.Add(time.Duration(t * float64(time.Second)))
+		return withDelta("tim:"+a.Format(time.StampMicro), fmt.Sprintf("+%.3fms", d*1e3))
+	default: // "ms"
+		return withDelta(fmt.Sprintf("tim:%12.3fms", t*1e3), fmt.Sprintf("+%.3fms", d*1e3))
+	}
+}
+
+// withDelta returns s followed by delta, if TargetTimeDelta, and a space.
+func withDelta(s, delta string) string {
+	if TargetTimeDelta {
+		return s + " " + delta + " "
+	}
+	return s + " "
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rokath/trice/pkg/trice"
+	"github.com/tj/assert"
+)
+
+// restoreTargetTime returns a function restoring the target time settings.
+func restoreTargetTime() func() {
+	f, u, s, d, ttsf := TargetTimestampFrequency, TargetTimeUnit, TargetTimeSync, TargetTimeDelta, ShowTargetTimestamp
+	return func() {
+		TargetTimestampFrequency, TargetTimeUnit, TargetTimeSync, TargetTimeDelta, ShowTargetTimestamp = f, u, s, d, ttsf
+	}
+}
+
+// stamp returns a trice with ID id and target timestamp ts.
+func stamp(id int, ts uint32) trice.Trice {
+	return trice.Trice{ID: trice.TriceID(id), TargetTimestamp: ts, HasTargetTimestamp: true}
+}
+
+func TestTargetTimeWraparound(t *testing.T) {
+	defer restoreTargetTime()()
+	ShowTargetTimestamp, TargetTimestampFrequency, TargetTimeUnit, TargetTimeSync, TargetTimeDelta = "tim:%9d", 1000, "ms", 0, true
+	var p targetTime
+	now := time.Now()
+	p.update(stamp(1, 0xfffffc18), now) // 1000 ticks before the wraparound
+	assert.Equal(t, "tim:       0.000ms +0.000ms ", p.format(0xfffffc18))
+	p.update(stamp(1, 500), now)
+	assert.Equal(t, "tim:    1500.000ms +1500.000ms ", p.format(500))
+	p.update(trice.Trice{}, now) // no timestamp
+	p.update(stamp(1, 501), now)
+	assert.Equal(t, "tim:    1501.000ms +1.000ms ", p.format(501))
+
+	TargetTimeUnit, TargetTimeDelta = "s", false
+	assert.Equal(t, "tim:    1.501000s ", p.format(501))
+	TargetTimeUnit = "µs"
+	assert.Equal(t, "tim:     1501000µs ", p.format(501))
+
+	TargetTimestampFrequency, TargetTimeDelta = 0, true // raw ticks
+	assert.Equal(t, "tim:      501 +1 ", p.format(501))
+	ShowTargetTimestamp = ""
+	assert.Equal(t, "", p.format(501))
+}
+
+func TestTargetTimeSync(t *testing.T) {
+	defer restoreTargetTime()()
+	ShowTargetTimestamp, TargetTimestampFrequency, TargetTimeUnit, TargetTimeSync, TargetTimeDelta = "tim:%9d", 1e6, "abs", 77, true
+	var p targetTime
+	first := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	p.update(stamp(1, 1000000), first)
+	assert.Equal(t, "tim:Oct 18 12:00:00.000000 +0.000ms ", p.format(1000000))
+	p.update(stamp(1, 3000000), first.Add(time.Minute)) // PC reception time is ignored
+	assert.Equal(t, "tim:Oct 18 12:00:02.000000 +2000.000ms ", p.format(3000000))
+
+	sync := first.Add(time.Hour)
+	p.update(stamp(77, 3500000), sync) // new reference
+	assert.Equal(t, "tim:Oct 18 13:00:00.000000 +500.000ms ", p.format(3500000))
+	p.update(stamp(1, 3500250), sync.Add(time.Hour))
+	assert.Equal(t, "tim:Oct 18 13:00:00.000250 +0.250ms ", p.format(3500250))
+}
+
+func TestTargetTimeReset(t *testing.T) {
+	defer restoreTargetTime()()
+	ShowTargetTimestamp, TargetTimestampFrequency, TargetTimeUnit, TargetTimeSync, TargetTimeDelta = "tim:%9d", 1000, "ms", 0, true
+	var p targetTime
+	now := time.Now()
+	p.update(stamp(1, 3000000), now)
+	p.update(stamp(1, 3000500), now)
+	assert.Equal(t, "tim:     500.000ms +500.000ms ", p.format(3000500))
+	p.update(stamp(1, 20), now) // target reset: new reference
+	assert.Equal(t, "tim:       0.000ms +0.000ms ", p.format(20))
+	p.update(stamp(1, 270), now)
+	assert.Equal(t, "tim:     250.000ms +250.000ms ", p.format(270))
+
+	TargetTimeUnit = "abs" // the reception time of the first trice after the reset is the reference
+	restart := time.Date(2026, 10, 18, 14, 0, 0, 0, time.Local)
+	p.update(stamp(1, 10), restart)
+	assert.Equal(t, "tim:Oct 18 14:00:00.000000 +0.000ms ", p.format(10))
+}
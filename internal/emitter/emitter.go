@@ -13,6 +13,7 @@ import (
 	"github.com/rokath/trice/internal/receiver"
 	"github.com/rokath/trice/pkg/cage"
 	"github.com/rokath/trice/pkg/msg"
+	"github.com/rokath/trice/pkg/trice"
 )
 
 var (
@@ -33,6 +34,11 @@ var (
 	// Suffix lollows lines. Usually empty.
 	Suffix string
 
+	// Format is the output format.
+	// text = human readable lines
+	// json = one JSON object per trice
+	Format string
+
 	// ColorPalette determines the way color is handled.
 	// off = no color handling at all. Lower case color prefixes are not removed. Use with care.
 	// none = no colors. Lower case color prefixes are removed.
@@ -48,6 +54,9 @@ var (
 	// DisplayRemote if set, sends trice lines over TCP.
 	DisplayRemote bool
 
+	// DisplayLocal if set together with DisplayRemote, shows the trice lines also locally.
+	DisplayLocal bool
+
 	// Autostart if set, starts an additional trice instance as displayserver.
 	// Autostart bool
 
@@ -100,6 +109,21 @@ type LineWriter interface {
 	writeLine([]string)
 }
 
+// lineWriters is a LineWriter writing each line to all its LineWriters.
+type lineWriters []LineWriter
+
+func (p lineWriters) writeLine(line []string) {
+	for _, lw := range p {
+		lw.writeLine(line)
+	}
+}
+
+// triceWriter is implemented by output devices accepting the structured data of single trices.
+// port is the trice source name or "" for a single source.
+type triceWriter interface {
+	writeTrice(port string, t trice.Trice)
+}
+
 //  // baseName returns basic filename of program without extension
 //  func baseName() string {
 //  	a0 := os.Args[0]
@@ -113,9 +137,11 @@ type LineWriter interface {
 //  	return b0
 //  }
 
-// newLineWriter provides a LineWriter which can be a remote Display or the local console.
+// newLineWriter provides a LineWriter which can be a remote Display, the local console or a JSON lines output.
 func newLineWriter(w io.Writer) (lwD LineWriter) {
-	if DisplayRemote {
+	if Format == "json" {
+		lwD = NewJSONDisplay(w)
+	} else if DisplayRemote {
 		//var p *RemoteDisplay
 		//  var args []string
 		//  if true == Autostart {
@@ -125,8 +151,13 @@ func newLineWriter(w io.Writer) (lwD LineWriter) {
 		//  	args = os.Args
 		//  }
 		p := NewRemoteDisplay(w, os.Args)
-		msg.FatalOnErr(p.Err)
+		if nil != p.Err {
+			p.connectLater()
+		}
 		lwD = p
+		if DisplayLocal {
+			lwD = lineWriters{p, NewColorDisplay(w, ColorPalette)}
+		}
 		// keybcmd.ReadInput()
 	} else {
 		lwD = NewColorDisplay(w, ColorPalette)
@@ -136,7 +167,7 @@ func newLineWriter(w io.Writer) (lwD LineWriter) {
 
 // New creates the emitter instance and returns a string writer to be used for emitting.
 func New(w io.Writer) *TriceLineComposer {
-	if !DisplayRemote {
+	if !DisplayRemote || DisplayLocal {
 		cage.Enable(os.Stdout)
 		defer cage.Disable(os.Stdout)
 	}
@@ -148,6 +179,43 @@ func New(w io.Writer) *TriceLineComposer {
 	return newLineComposer(newLineWriter(w))
 }
 
+// NewPorts creates an emitter instance for each of the ports. All of them write into one shared line writer.
+// The lines of all ports are written in the order of the reception time of their first part on the PC.
+// Therefore they are held back for MergeDelay. The returned flush function writes the held back lines and must be called at the end.
+// Each line prefix contains the port name.
+func NewPorts(w io.Writer, ports []string) (sws []*TriceLineComposer, flush func()) {
+	if !DisplayRemote || DisplayLocal {
+		cage.Enable(os.Stdout)
+		defer cage.Disable(os.Stdout)
+	}
+	lw := newLineWriter(w)
+	m := newMerger(MergeDelay)
+	sws = make([]*TriceLineComposer, len(ports))
+	for i, port := range ports {
+		p := newLineComposer(lw)
+		if !TestTableMode { // do not change prefix in TestTableMode
+			p.prefix = portPrefix(port, Prefix)
+		}
+		p.port = port
+		p.merge = m
+		sws[i] = p
+	}
+	return sws, m.flush
+}
+
+// portPrefix returns prefix with "source:" replaced by port, e.g., "COM3:".
+// If prefix does not start with "source:", the port name is put in front.
+func portPrefix(port, prefix string) string {
+	defaultPrefix := "source:"
+	if strings.HasPrefix(prefix, defaultPrefix) {
+		return port + ":" + prefix[len(defaultPrefix):]
+	}
+	if prefix == "off" || prefix == "none" {
+		prefix = ""
+	}
+	return port + ":" + prefix
+}
+
 // SetPrefix changes "source:" to e.g., "JLINK:".
 func SetPrefix() {
 	defaultPrefix := "source:"
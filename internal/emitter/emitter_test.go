@@ -9,7 +9,6 @@ import (
 	"testing"
 
 	"github.com/rokath/trice/internal/receiver"
-	"github.com/rokath/trice/pkg/msg"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,14 +40,6 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, "", act)
 }
 
-func TestNewLineWriter(t *testing.T) {
-	o := msg.OsExitDisallow()
-	defer msg.OsExitAllow(o)
-	DisplayRemote = true
-	var out bytes.Buffer
-	_ = newLineWriter(&out)
-}
-
 //  func TestNewLineWriter2(t *testing.T) {
 //  	o := msg.OsExitDisallow()
 //  	defer msg.OsExitAllow(o)
@@ -72,3 +63,9 @@ func ExampleNewRemoteDisplay() {
 	// Error in [remoteDisplay.go %!s(int=110) github.com/rokath/trice/internal/emitter.(*RemoteDisplay).Connect dial tcp [::1]:11111: connectex: No connection could be made because the target machine actively refused it.]:%!d(MISSING): func '%!s(MISSING)' -> %!v(MISSING)
 }
 */
+
+func TestPortPrefix(t *testing.T) {
+	assert.Equal(t, "COM3: ", portPrefix("COM3", "source: "))
+	assert.Equal(t, "COM3:", portPrefix("COM3", "off"))
+	assert.Equal(t, "COM3:board:", portPrefix("COM3", "board:"))
+}
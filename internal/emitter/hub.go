@@ -0,0 +1,169 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package emitter
+
+// display server hub for several producers and viewers
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rokath/trice/internal/receiver"
+)
+
+var (
+	// HistorySize is the count of recent lines the display server keeps for late joining viewers.
+	HistorySize = 1000
+
+	// ProducerName is the name a log instance registers at the display server. "" means host name and port, like "bench1/COM3".
+	ProducerName string
+
+	// Producers is a comma separated list of the producers shown by the display server itself. "" means all.
+	Producers string
+)
+
+// producerLine is a line of a named producer.
+type producerLine struct {
+	producer string
+	line     []string
+}
+
+// hubViewer receives the lines of the subscribed producers.
+type hubViewer struct {
+	producers map[string]bool // producers are the subscribed producer names. nil means all.
+	write     func(producer string, line []string)
+}
+
+// Hub distributes the lines of several producers to its viewers.
+// It keeps the recent lines, so a viewer connecting later gets them first.
+type Hub struct {
+	mu        sync.Mutex          // mu guards all other fields and serializes the viewer writes.
+	history   []producerLine      // history is a ring buffer with the recent lines.
+	next      int                 // next is the history index for the next line.
+	producers map[string]int      // producers are the connection counts by producer name.
+	viewers   map[*hubViewer]bool // viewers are the subscribed viewers.
+}
+
+// NewHub creates a Hub keeping the last size lines.
+func NewHub(size int) *Hub {
+	if size < 0 {
+		size = 0
+	}
+	return &Hub{
+		history:   make([]producerLine, 0, size),
+		producers: make(map[string]int),
+		viewers:   make(map[*hubViewer]bool),
+	}
+}
+
+// register adds a connection of producer.
+func (h *Hub) register(producer string) {
+	h.mu.Lock()
+	h.producers[producer]++
+	h.mu.Unlock()
+}
+
+// unregister removes a connection of producer.
+func (h *Hub) unregister(producer string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.producers[producer]--; h.producers[producer] <= 0 {
+		delete(h.producers, producer)
+	}
+}
+
+// Producers returns the sorted names of the connected producers.
+func (h *Hub) Producers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := make([]string, 0, len(h.producers))
+	for name := range h.producers {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return s
+}
+
+// writeLine keeps line of producer in the history and writes it to all viewers subscribed to producer.
+func (h *Hub) writeLine(producer string, line []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if 0 < cap(h.history) {
+		x := producerLine{producer, line}
+		if len(h.history) < cap(h.history) {
+			h.history = append(h.history, x)
+		} else {
+			h.history[h.next] = x
+		}
+		h.next = (h.next + 1) % cap(h.history)
+	}
+	for v := range h.viewers {
+		v.write1(producer, line)
+	}
+}
+
+// write1 writes line, if v is subscribed to producer.
+func (v *hubViewer) write1(producer string, line []string) {
+	if nil == v.producers || v.producers[producer] {
+		v.write(producer, line)
+	}
+}
+
+// Subscribe adds a viewer for the lines of producers. Empty producers means all producers.
+// The viewer gets the history of its producers first. The returned function ends the subscription.
+// write is called with the hub locked and must not block.
+func (h *Hub) Subscribe(producers []string, write func(producer string, line []string)) (unsubscribe func()) {
+	v := &hubViewer{write: write}
+	if 0 < len(producers) {
+		v.producers = make(map[string]bool, len(producers))
+		for _, name := range producers {
+			v.producers[name] = true
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := len(h.history)
+	for i := 0; i < n; i++ {
+		x := h.history[(h.next+i)%n] // oldest first: next is len(h.history) until the ring is full
+		v.write1(x.producer, x.line)
+	}
+	h.viewers[v] = true
+	return func() {
+		h.mu.Lock()
+		delete(h.viewers, v)
+		h.mu.Unlock()
+	}
+}
+
+// labeled returns line with the producer name in front, so the lines of several producers are distinguishable.
+func labeled(producer string, line []string) []string {
+	if "" == producer {
+		return line
+	}
+	return append([]string{"[" + producer + "] "}, line...)
+}
+
+// producerName returns ProducerName or, if empty, the host name and port.
+func producerName() string {
+	if "" != ProducerName {
+		return ProducerName
+	}
+	host, err := os.Hostname()
+	if nil != err {
+		host = "trice"
+	}
+	return host + "/" + receiver.Port
+}
+
+// producerList splits a comma separated list of producer names. Colons are part of names like "bench1/TCP:localhost:19021".
+func producerList(s string) (list []string) {
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); "" != name {
+			list = append(list, name)
+		}
+	}
+	return
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// whitebox test for package emitter.
+package emitter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// viewerCount returns the count of subscribed viewers.
+func (h *Hub) viewerCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.viewers)
+}
+
+// collect subscribes to producers of h and returns the received lines joined with their producer.
+func collect(h *Hub, producers ...string) (lines *[]string, unsubscribe func()) {
+	lines = new([]string)
+	unsubscribe = h.Subscribe(producers, func(producer string, line []string) {
+		*lines = append(*lines, strings.Join(labeled(producer, line), ""))
+	})
+	return
+}
+
+func TestHubHistory(t *testing.T) {
+	h := NewHub(3)
+	early, _ := collect(h)
+	for _, s := range []string{"1", "2", "3", "4", "5"} {
+		h.writeLine("a", []string{s})
+	}
+	assert.Equal(t, []string{"[a] 1", "[a] 2", "[a] 3", "[a] 4", "[a] 5"}, *early)
+	late, unsubscribe := collect(h)
+	assert.Equal(t, []string{"[a] 3", "[a] 4", "[a] 5"}, *late) // only the last 3 lines
+	unsubscribe()
+	h.writeLine("a", []string{"6"})
+	assert.Equal(t, 3, len(*late))
+	assert.Equal(t, 6, len(*early))
+	assert.Equal(t, 1, h.viewerCount())
+}
+
+func TestHubSubscribeProducers(t *testing.T) {
+	h := NewHub(10)
+	h.writeLine("a", []string{"a1"})
+	h.writeLine("b", []string{"b1"})
+	h.writeLine("", []string{"dbg:shutdown"})
+	b, _ := collect(h, "b")
+	all, _ := collect(h)
+	h.writeLine("a", []string{"a2"})
+	h.writeLine("b", []string{"b2"})
+	assert.Equal(t, []string{"[b] b1", "[b] b2"}, *b)
+	assert.Equal(t, []string{"[a] a1", "[b] b1", "dbg:shutdown", "[a] a2", "[b] b2"}, *all)
+}
+
+func TestProducerList(t *testing.T) {
+	assert.Nil(t, producerList(""))
+	assert.Equal(t, []string{"bench1/COM3", "bench2/TCP:localhost:19021"}, producerList("bench1/COM3, bench2/TCP:localhost:19021,"))
+}
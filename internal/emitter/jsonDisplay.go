@@ -0,0 +1,119 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package emitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rokath/trice/pkg/trice"
+)
+
+// jsonTrice is the JSON line representation of a Trice.
+type jsonTrice struct {
+	PCTime          string        `json:"pcTime,omitempty"`
+	TargetTimestamp *uint32       `json:"targetTimestamp,omitempty"`
+	Port            string        `json:"port,omitempty"`
+	ID              trice.TriceID `json:"id,omitempty"`
+	Channel         string        `json:"channel,omitempty"`
+	Type            string        `json:"type,omitempty"`
+	Fmt             string        `json:"fmt,omitempty"`
+	Values          []interface{} `json:"values,omitempty"`
+	Text            string        `json:"text"`
+}
+
+// JSONDisplay is an object used for displaying trices as JSON lines, one JSON object per trice.
+// It implements the LineWriter interface.
+type JSONDisplay struct {
+	w               io.Writer
+	timestampFormat string
+	Err             error
+}
+
+// NewJSONDisplay creates a JSONDisplay writing to w.
+func NewJSONDisplay(w io.Writer) *JSONDisplay {
+	p := &JSONDisplay{w, TimestampFormat, nil}
+	return p
+}
+
+// pcTime returns t as string according to p.timestampFormat.
+func (p *JSONDisplay) pcTime(t time.Time) string {
+	switch p.timestampFormat {
+	case "off", "none":
+		return ""
+	case "UTCmicro":
+		return t.UTC().Format(time.RFC3339Nano)
+	case "zero":
+		return "2006-01-02T15:04:05Z"
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// channel returns the channel information at the start of s, if any.
+func channel(s string) string {
+	sc := strings.SplitN(s, ":", 2)
+	if len(sc) < 2 || !isChannel(sc[0]) {
+		return ""
+	}
+	return sc[0]
+}
+
+// text removes the line end from s and converts escaped newlines.
+func text(s string) string {
+	s = strings.ReplaceAll(s, "\\r\\n", "\n")
+	s = strings.ReplaceAll(s, "\\n", "\n")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.TrimRight(s, "\n")
+}
+
+// write encodes x as a single JSON line.
+func (p *JSONDisplay) write(x jsonTrice) {
+	if nil != p.Err {
+		return
+	}
+	var b []byte
+	b, p.Err = json.Marshal(x)
+	if nil != p.Err {
+		return
+	}
+	_, p.Err = fmt.Fprintln(p.w, string(b))
+}
+
+// WriteTrice writes t as a single JSON line.
+func (p *JSONDisplay) WriteTrice(t trice.Trice) {
+	p.writeTrice("", t)
+}
+
+// writeTrice writes t as a single JSON line with port as source information.
+func (p *JSONDisplay) writeTrice(port string, t trice.Trice) {
+	x := jsonTrice{
+		PCTime:  p.pcTime(t.PCTime),
+		Port:    port,
+		ID:      t.ID,
+		Channel: channel(t.Fmt),
+		Type:    t.Type,
+		Fmt:     t.Fmt,
+		Values:  t.Args,
+		Text:    text(t.Text),
+	}
+	if "" == x.Channel {
+		x.Channel = channel(x.Text)
+	}
+	if t.HasTargetTimestamp {
+		ts := t.TargetTimestamp
+		x.TargetTimestamp = &ts
+	}
+	p.write(x)
+}
+
+// writeLine is the implemented LineWriter interface for JSONDisplay.
+// Lines without trice information are written as JSON objects with text only.
+func (p *JSONDisplay) writeLine(line []string) {
+	s := text(strings.Join(line, ""))
+	p.write(jsonTrice{PCTime: p.pcTime(time.Now()), Channel: channel(s), Text: s})
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// whitebox test for package emitter.
+package emitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rokath/trice/pkg/trice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONDisplayWriteTrice(t *testing.T) {
+	var b bytes.Buffer
+	TimestampFormat = "off"
+	p := NewJSONDisplay(&b)
+	p.WriteTrice(trice.Trice{
+		TargetTimestamp:    2000,
+		HasTargetTimestamp: true,
+		ID:                 48324,
+		Type:               "TRICE16_2",
+		Fmt:                "MSG: START select = %d, TriceDepthMax =%4u\\n",
+		Args:               []interface{}{int16(-1), uint16(7)},
+		Text:               "MSG: START select = -1, TriceDepthMax =   7\\n",
+	})
+	assert.Nil(t, p.Err)
+	exp := `{"targetTimestamp":2000,"id":48324,"channel":"MSG","type":"TRICE16_2","fmt":"MSG: START select = %d, TriceDepthMax =%4u\\n","values":[-1,7],"text":"MSG: START select = -1, TriceDepthMax =   7"}` + "\n"
+	assert.Equal(t, exp, b.String())
+}
+
+func TestJSONDisplayWriteLine(t *testing.T) {
+	var b bytes.Buffer
+	TimestampFormat = "UTCmicro"
+	p := NewJSONDisplay(&b)
+	p.writeLine([]string{"wrn:", "something odd\n"})
+	var x map[string]interface{}
+	assert.Nil(t, json.Unmarshal(b.Bytes(), &x))
+	assert.Equal(t, "wrn", x["channel"])
+	assert.Equal(t, "wrn:something odd", x["text"])
+	assert.NotEmpty(t, x["pcTime"])
+	_, ok := x["id"]
+	assert.False(t, ok)
+}
@@ -6,6 +6,8 @@ package emitter
 import (
 	"strings"
 	"time"
+
+	"github.com/rokath/trice/pkg/trice"
 )
 
 // SyncPacketPattern is used if a sync packet arrives
@@ -19,13 +21,16 @@ type TriceLineComposer struct {
 	suffix          string
 	Line            []string // line collector
 	err             error
+	port            string    // port is the source name for structured output, if several ports share one line writer
+	merge           *merger   // merge, if not nil, orders the outputs of several ports sharing the line writer lw
+	start           time.Time // start is the reception time of the first part of Line
 }
 
 // newLineComposer constructs log lines according to these rules:...
 // It provides an io.StringWriter interface which is used for the reception of (trice) strings.
 // It uses lw for writing the generated lines.
 func newLineComposer(lw LineWriter) *TriceLineComposer {
-	p := &TriceLineComposer{lw, TimestampFormat, Prefix, Suffix, make([]string, 0, 4096), nil} // not more than 4096 strings per line expected
+	p := &TriceLineComposer{lw, TimestampFormat, Prefix, Suffix, make([]string, 0, 4096), nil, "", nil, time.Time{}} // not more than 4096 strings per line expected
 	return p
 }
 
@@ -92,12 +97,15 @@ func (p *TriceLineComposer) WriteString(s string) (n int, err error) {
 	// If a string was already started and gets completed with a following WriteString call,
 	// it keeps its original timestamp, but if following lines inside s they get a new timestamp.
 	ts := p.timestamp()
+	now := time.Now()
 	for _, sx := range ss {
 		if len(p.Line) == 0 && 0 < lineEndCount { // start new line && and complete line
+			p.start = now
 			p.Line = append(p.Line, ts, p.prefix, sx, p.suffix)
 			p.completeLine()
 			lineEndCount--
 		} else if len(p.Line) == 0 && lineEndCount == 0 { // start new line
+			p.start = now
 			p.Line = append(p.Line, ts, p.prefix, sx)
 			if len(sx) == 0 { // A new line with an empty string was started.
 				// This could cause unwanted timestamp offsets if the next line is significantly delayed.
@@ -117,8 +125,34 @@ func (p *TriceLineComposer) WriteString(s string) (n int, err error) {
 	return
 }
 
+// WriteTrice forwards t to the internal line writer p.lw, if it accepts structured trice data,
+// otherwise t.Text is handled like by WriteString.
+func (p *TriceLineComposer) WriteTrice(t trice.Trice) (err error) {
+	if tw, ok := p.lw.(triceWriter); ok {
+		if nil != p.merge {
+			p.merge.add(t.PCTime, func() { tw.writeTrice(p.port, t) })
+			return
+		}
+		tw.writeTrice(p.port, t)
+		return
+	}
+	_, err = p.WriteString(t.Text)
+	return
+}
+
+// StructuredOutput returns true, if the internal line writer accepts structured trice data.
+func (p *TriceLineComposer) StructuredOutput() bool {
+	_, ok := p.lw.(triceWriter)
+	return ok
+}
+
 func (p *TriceLineComposer) completeLine() {
-	p.lw.writeLine(p.Line)
+	if nil != p.merge {
+		line := append([]string(nil), p.Line...) // p.Line is reused
+		p.merge.add(p.start, func() { p.lw.writeLine(line) })
+	} else {
+		p.lw.writeLine(p.Line)
+	}
 	p.Line = p.Line[:0]
 	NextLine = true
 }
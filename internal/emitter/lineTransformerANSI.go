@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
 	"unicode"
 
 	"github.com/mgutz/ansi"
@@ -21,12 +22,13 @@ import (
 type LineTransformerANSI struct {
 	lw           LineWriter
 	colorPalette string
+	uncounted    bool // uncounted is true for an additional transformer of the same lines, like for a web display.
 }
 
 // NewLineTransformerANSI translates lines to ANSI colors according to colorPalette.
 // It provides a Linewriter interface and uses internally a Linewriter.
 func NewLineTransformerANSI(lw LineWriter, colorPalette string) *LineTransformerANSI {
-	p := &LineTransformerANSI{lw, colorPalette}
+	p := &LineTransformerANSI{lw: lw, colorPalette: colorPalette}
 	return p
 }
 
@@ -66,7 +68,7 @@ func isLower(s string) bool {
 }
 
 type ColorChannel struct {
-	events   int
+	events   int64 // events is accessed atomically, because it is read concurrently, for example for metrics.
 	channel  []string
 	colorize func(string) string
 }
@@ -100,10 +102,11 @@ var ColorChannels = []ColorChannel{
 // ColorChannelEvents returns count of occurred channel events.
 // If ch is unknown, the returned value is -1.
 func ColorChannelEvents(ch string) int {
-	for _, s := range ColorChannels {
+	for i := range ColorChannels {
+		s := &ColorChannels[i]
 		for _, c := range s.channel {
 			if c == ch {
-				return s.events
+				return int(atomic.LoadInt64(&s.events))
 			}
 		}
 	}
@@ -112,9 +115,10 @@ func ColorChannelEvents(ch string) int {
 
 // PrintColorChannelEvents shows the amount of occurred channel events.
 func PrintColorChannelEvents(w io.Writer) {
-	for _, s := range ColorChannels {
-		if s.events != 0 {
-			fmt.Fprintf(w, "%6d times: ", s.events)
+	for i := range ColorChannels {
+		s := &ColorChannels[i]
+		if n := atomic.LoadInt64(&s.events); n != 0 {
+			fmt.Fprintf(w, "%6d times: ", n)
 			for _, c := range s.channel {
 				fmt.Fprint(w, c, " ")
 			}
@@ -123,10 +127,52 @@ func PrintColorChannelEvents(w io.Writer) {
 	}
 }
 
+// ChannelEvents returns the count of occurred events for each channel. The key is the channel name, see channelName.
+func ChannelEvents() map[string]int {
+	m := make(map[string]int, len(ColorChannels))
+	for i := range ColorChannels {
+		s := &ColorChannels[i]
+		m[s.name()] = int(atomic.LoadInt64(&s.events))
+	}
+	return m
+}
+
+// name returns the channel name in lower case, taken from the capitalized variant, like "Error",
+// or otherwise from the longest variant, like "message".
+func (s *ColorChannel) name() string {
+	var name string
+	for _, c := range s.channel {
+		if len(name) < len(c) {
+			name = c
+		}
+	}
+	for _, c := range s.channel {
+		if 1 < len(c) && unicode.IsUpper(rune(c[0])) && isLower(c[1:]) {
+			name = c
+			break
+		}
+	}
+	return strings.ToLower(strings.TrimSuffix(name, "_"))
+}
+
+// channelName returns the channel name for the channel variant ch or "" if ch is no channel.
+func channelName(ch string) string {
+	for i := range ColorChannels {
+		s := &ColorChannels[i]
+		for _, c := range s.channel {
+			if c == ch {
+				return s.name()
+			}
+		}
+	}
+	return ""
+}
+
 // channelVariants returns all variants of ch as string slice.
 // If ch is not inside ansiSel nil is returned.
 func channelVariants(ch string) []string {
-	for _, s := range ColorChannels {
+	for i := range ColorChannels {
+		s := &ColorChannels[i]
 		for _, c := range s.channel {
 			if c == ch {
 				return s.channel
@@ -153,10 +199,11 @@ func (p *LineTransformerANSI) colorize(s string) (r string) {
 	if len(sc) < 2 { // no color separator
 		return // do nothing
 	}
-	for i, cc := range ColorChannels {
+	for i := range ColorChannels {
+		cc := &ColorChannels[i] // no copy, events is read concurrently
 		for _, c := range cc.channel {
-			if c == sc[0] {
-				ColorChannels[i].events++ // count event
+			if c == sc[0] && !p.uncounted {
+				atomic.AddInt64(&cc.events, 1) // count event
 			}
 		}
 	}
@@ -169,7 +216,8 @@ func (p *LineTransformerANSI) colorize(s string) (r string) {
 	if p.colorPalette == "none" {
 		return
 	}
-	for _, cs := range ColorChannels {
+	for i := range ColorChannels {
+		cs := &ColorChannels[i]
 		for _, c := range cs.channel {
 			if c == sc[0] {
 				return cs.colorize(r)
@@ -0,0 +1,115 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package emitter
+
+// time ordered output of several ports
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// MergeDelay is the time the lines of several ports are held back before they are written in the order of their reception time.
+// A line started earlier than a held back line of a different port, but completed later, is sorted in before it,
+// if it is completed within MergeDelay.
+var MergeDelay = 100 * time.Millisecond
+
+// mergeItem is a held back output.
+type mergeItem struct {
+	t     time.Time // t is the PC reception time of the line start or trice.
+	seq   int       // seq keeps the order of items with equal t.
+	write func()    // write writes the output.
+}
+
+// mergeHeap is a min heap of mergeItems ordered by t and seq.
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].t.Equal(h[j].t) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].t.Before(h[j].t)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// merger writes the outputs of several ports in the order of their reception time.
+type merger struct {
+	mu     sync.Mutex // mu guards the following fields and serializes the writes.
+	items  mergeHeap  // items are the held back outputs.
+	seq    int        // seq is the count of added items.
+	closed bool       // closed is true after flush. Further outputs are written immediately.
+	done   chan struct{}
+}
+
+// newMerger returns a merger holding the outputs back for delay.
+func newMerger(delay time.Duration) *merger {
+	m := &merger{done: make(chan struct{})}
+	if delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+	go m.run(delay)
+	return m
+}
+
+// add holds write back until the reception time t is older than the merge delay.
+func (m *merger) add(t time.Time, write func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		write()
+		return
+	}
+	m.seq++
+	heap.Push(&m.items, mergeItem{t, m.seq, write})
+}
+
+// run writes the outputs older than delay until flush is called.
+func (m *merger) run(delay time.Duration) {
+	ticker := time.NewTicker(delay / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.writeUntil(time.Now().Add(-delay))
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// writeUntil writes all outputs received not after t.
+func (m *merger) writeUntil(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for 0 < len(m.items) && !m.items[0].t.After(t) {
+		heap.Pop(&m.items).(mergeItem).write()
+	}
+}
+
+// flush writes all held back outputs and ends the merger.
+func (m *merger) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	close(m.done)
+	for 0 < len(m.items) {
+		heap.Pop(&m.items).(mergeItem).write()
+	}
+}
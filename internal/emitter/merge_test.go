@@ -0,0 +1,60 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package emitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerger(t *testing.T) {
+	m := newMerger(time.Hour)
+	var out []string
+	add := func(t time.Time, s string) { m.add(t, func() { out = append(out, s) }) }
+	now := time.Now()
+	add(now.Add(2*time.Second), "c")
+	add(now, "a")
+	add(now.Add(time.Second), "b1")
+	add(now.Add(time.Second), "b2") // equal times keep their order
+	m.writeUntil(now.Add(time.Second))
+	assert.Equal(t, []string{"a", "b1", "b2"}, out)
+	m.flush()
+	assert.Equal(t, []string{"a", "b1", "b2", "c"}, out)
+	add(now, "d") // written immediately after flush
+	assert.Equal(t, []string{"a", "b1", "b2", "c", "d"}, out)
+}
+
+// TestNewPortsOrder checks, that a line started earlier is written first, even when completed later.
+func TestNewPortsOrder(t *testing.T) {
+	defer func(remote bool, format, ts, prefix, suffix string) {
+		DisplayRemote, Format, TimestampFormat, Prefix, Suffix = remote, format, ts, prefix, suffix
+	}(DisplayRemote, Format, TimestampFormat, Prefix, Suffix)
+	DisplayRemote, Format, TimestampFormat, Prefix, Suffix = false, "text", "off", "off", ""
+	var lb lineCollector
+	sws, flush := NewPorts(nil, []string{"A", "B"})
+	for _, p := range sws {
+		p.lw = &lb
+	}
+	_, _ = sws[0].WriteString("a1 ")
+	time.Sleep(time.Millisecond)
+	_, _ = sws[1].WriteString("b\n")
+	_, _ = sws[0].WriteString("a2\n")
+	flush()
+	assert.Equal(t, []string{"A:a1 a2", "B:b"}, lb.lines)
+}
+
+// lineCollector is a LineWriter keeping all lines as strings.
+type lineCollector struct {
+	lines []string
+}
+
+func (p *lineCollector) writeLine(line []string) {
+	var s string
+	for _, x := range line {
+		s += x
+	}
+	p.lines = append(p.lines, s)
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package emitter
+
+// display server wire protocol, see docs/DisplayServerProtocol.md
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ProtocolVersion is the display server protocol version. A server accepts only clients with the same version.
+const ProtocolVersion = 1
+
+// maxFrameSize limits the JSON size of a message.
+const maxFrameSize = 1 << 20
+
+var (
+	// Token is the shared secret between the display server and its clients. "" means no token.
+	// A server with a token refuses clients without it. A server without a token accepts control messages only from the local host.
+	Token string
+
+	// TLSCert and TLSKey are the PEM file names of the display server certificate and key. Both are needed for TLS.
+	TLSCert, TLSKey string
+
+	// TLS switches TLS on for the connection to the display server.
+	TLS bool
+
+	// TLSCA is the PEM file name of the certificate authority for checking the display server certificate. "" means the system roots.
+	TLSCA string
+)
+
+// Message types. Each message is answered with msgOK or msgError, except msgLine.
+const (
+	msgHello        = "hello"        // msgHello is the first client message with Version, Name and optional Token.
+	msgLine         = "line"         // msgLine carries a display line in Line.
+	msgColorPalette = "colorPalette" // msgColorPalette sets the color palette to Value.
+	msgLogFlags     = "logFlags"     // msgLogFlags sets the log package flags to Flags.
+	msgShutdown     = "shutdown"     // msgShutdown ends the display server. Timestamp adds the time to the shutdown line.
+	msgOK           = "ok"           // msgOK is the positive answer.
+	msgError        = "error"        // msgError is the negative answer with the reason in Error.
+)
+
+// message is a display server protocol message.
+type message struct {
+	Type      string   `json:"type"`
+	Version   int      `json:"version,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Token     string   `json:"token,omitempty"`
+	Line      []string `json:"line,omitempty"`
+	Value     string   `json:"value,omitempty"`
+	Flags     int      `json:"flags,omitempty"`
+	Timestamp bool     `json:"timestamp,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// writeFrame writes m as JSON preceded by its length as 4 byte big endian number.
+func writeFrame(w io.Writer, m message) error {
+	b, err := json.Marshal(m)
+	if nil != err {
+		return err
+	}
+	f := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(f, uint32(len(b)))
+	copy(f[4:], b)
+	_, err = w.Write(f)
+	return err
+}
+
+// readFrame reads a message written by writeFrame into m.
+func readFrame(r io.Reader, m *message) error {
+	var h [4]byte
+	if _, err := io.ReadFull(r, h[:]); nil != err {
+		return err
+	}
+	n := binary.BigEndian.Uint32(h[:])
+	if maxFrameSize < n {
+		return fmt.Errorf("message size %d exceeds %d", n, maxFrameSize)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); nil != err {
+		return err
+	}
+	*m = message{}
+	return json.Unmarshal(b, m)
+}
+
+// serverTLSConfig returns the TLS configuration of the display server or nil without TLSCert.
+func serverTLSConfig() (*tls.Config, error) {
+	if "" == TLSCert && "" == TLSKey {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(TLSCert, TLSKey)
+	if nil != err {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// clientTLSConfig returns the TLS configuration of a display server client or nil without TLS.
+func clientTLSConfig(host string) (*tls.Config, error) {
+	if !TLS {
+		return nil, nil
+	}
+	c := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if "" != TLSCA {
+		b, err := ioutil.ReadFile(TLSCA)
+		if nil != err {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(b) {
+			return nil, errors.New("no certificate found in " + TLSCA)
+		}
+	}
+	return c, nil
+}
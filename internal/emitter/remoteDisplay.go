@@ -4,27 +4,48 @@
 package emitter
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/rpc"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rokath/trice/pkg/msg"
 )
 
+// DisplayQueueSize is the count of lines a remote display keeps while the display server is not reachable.
+var DisplayQueueSize = 10000
+
+var (
+	reconnectMin = 100 * time.Millisecond // reconnectMin is the first wait time before a reconnect.
+	reconnectMax = 5 * time.Second        // reconnectMax is the longest wait time between reconnects.
+	writeTimeout = 5 * time.Second        // writeTimeout is the longest time for sending a message. A display server not reading counts as lost.
+)
+
 // RemoteDisplay is transferring to a remote display object.
+// After a lost connection it reconnects in the background and keeps the lines meanwhile in a bounded queue.
 type RemoteDisplay struct {
-	w      io.Writer   // os.Stdout
-	Err    error       // stored error
-	Cmd    string      // remote server executable
-	Params string      // remote server additional parameters (despite "ds -ipa a.b.c.d -ipp nnnn")
-	IPAddr string      // IP addr
-	IPPort string      // IP port
-	PtrRPC *rpc.Client // PtrRPC is a pointer for remote calls valid after a successful rpc.Dial()
+	w      io.Writer // os.Stdout
+	Err    error     // stored error
+	Cmd    string    // remote server executable
+	Params string    // remote server additional parameters (despite "ds -ipa a.b.c.d -ipp nnnn")
+	IPAddr string    // IP addr
+	IPPort string    // IP port
+	Name   string    // Name is the producer name shown by the display server.
+	Token  string    // Token authenticates the client at the display server.
+
+	mu           sync.Mutex // mu guards the following fields, because the reconnect runs concurrently.
+	conn         net.Conn   // conn is the display server connection, nil while disconnected.
+	queue        [][]string // queue holds the lines while disconnected, oldest first.
+	dropped      int        // dropped counts the lines lost because of a full queue since the last connection.
+	reconnecting bool       // reconnecting is true while the reconnect goroutine runs.
 }
 
 // NewRemoteDisplay creates a connection to a remote Display and implements the Linewriter interface.
@@ -44,7 +65,8 @@ func NewRemoteDisplay(w io.Writer, args []string) *RemoteDisplay {
 		Params: strings.Join(args[1:], " "),
 		IPAddr: IPAddr,
 		IPPort: IPPort,
-		PtrRPC: nil,
+		Name:   producerName(),
+		Token:  Token,
 	}
 	p.w = w
 	//  if Autostart {
@@ -63,9 +85,153 @@ func (p *RemoteDisplay) ErrorFatal() {
 }
 
 // writeLine is implementing the Linewriter interface for RemoteDisplay.
+// Without connection the line goes into the queue.
 func (p *RemoteDisplay) writeLine(line []string) {
-	p.ErrorFatal()
-	p.Err = p.PtrRPC.Call("Server.WriteLine", line, nil) // TODO: Change to "Server.WriteLine"
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if nil != p.conn {
+		if p.Err = send(p.conn, message{Type: msgLine, Line: line}); nil == p.Err {
+			return
+		}
+		fmt.Fprintln(p.w, "remote display connection lost:", p.Err)
+		p.conn.Close()
+		p.conn = nil
+	}
+	p.enqueue(line)
+	p.startReconnect()
+}
+
+// startReconnect starts the reconnect goroutine, if it is not running already. p.mu must be held.
+func (p *RemoteDisplay) startReconnect() {
+	if !p.reconnecting {
+		p.reconnecting = true
+		go p.reconnect(reconnectMin, reconnectMax)
+	}
+}
+
+// connectLater starts the reconnect after a failed Connect, so the display server can start after the log instance.
+// The lines are queued meanwhile.
+func (p *RemoteDisplay) connectLater() {
+	fmt.Fprintln(p.w, "remote display not reachable:", p.Err, "- queueing lines until it is")
+	p.mu.Lock()
+	p.startReconnect()
+	p.mu.Unlock()
+}
+
+// enqueue appends line to the queue. If the queue is full, the oldest line is dropped.
+func (p *RemoteDisplay) enqueue(line []string) {
+	if len(p.queue) < DisplayQueueSize {
+		p.queue = append(p.queue, line)
+		return
+	}
+	if 0 == p.dropped {
+		fmt.Fprintln(p.w, "remote display queue full - dropping oldest lines")
+	}
+	p.dropped++
+	if 0 < len(p.queue) {
+		p.queue = append(p.queue[1:], line)
+	}
+}
+
+// reconnect dials the display server with wait times increasing from min to max until it succeeds.
+// Then it sends a warning about dropped lines and the queued lines.
+func (p *RemoteDisplay) reconnect(min, max time.Duration) {
+	addr := p.IPAddr + ":" + p.IPPort
+	for wait := min; ; wait *= 2 {
+		if max < wait {
+			wait = max
+		}
+		time.Sleep(wait)
+		conn, err := dial(addr)
+		if nil != err {
+			continue
+		}
+		if err = exchange(conn, p.helloMessage()); nil != err {
+			conn.Close()
+			continue
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if 0 < p.dropped {
+			n := fmt.Sprintf("%d lines dropped while the display server was not reachable", p.dropped)
+			fmt.Fprintln(p.w, "remote display:", n)
+			p.queue = append([][]string{{"wrn:remote display: " + n}}, p.queue...)
+			p.dropped = 0
+		}
+		for i, line := range p.queue {
+			if p.Err = send(conn, message{Type: msgLine, Line: line}); nil != p.Err {
+				conn.Close()
+				p.queue = p.queue[i:]
+				go p.reconnect(min, max)
+				return
+			}
+		}
+		p.queue = nil
+		p.conn = conn
+		p.reconnecting = false
+		if Verbose {
+			fmt.Fprintln(p.w, "remote display @ "+addr+" reconnected")
+		}
+		return
+	}
+}
+
+// call sends the control message m and waits for the answer.
+func (p *RemoteDisplay) call(m message) error {
+	if nil == p.conn {
+		return errors.New("not connected to display server")
+	}
+	return exchange(p.conn, m)
+}
+
+// send writes m to conn within writeTimeout, so a stuck display server does not block the log output forever.
+func send(conn net.Conn, m message) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); nil != err {
+		return err
+	}
+	return writeFrame(conn, m)
+}
+
+// exchange sends the control message m over conn and waits for the answer.
+func exchange(conn net.Conn, m message) error {
+	if err := send(conn, m); nil != err {
+		return err
+	}
+	var r message
+	if err := readFrame(conn, &r); nil != err {
+		return err
+	}
+	if msgError == r.Type {
+		return errors.New("displayServer: " + r.Error)
+	}
+	return nil
+}
+
+// helloMessage returns the first message with the producer name and the token.
+func (p *RemoteDisplay) helloMessage() message {
+	return message{Type: msgHello, Version: ProtocolVersion, Name: p.Name, Token: p.Token}
+}
+
+// hello starts the protocol on conn.
+func (p *RemoteDisplay) hello(conn net.Conn) error {
+	p.conn = conn
+	return p.call(p.helloMessage())
+}
+
+// dial connects to addr, with TLS if configured.
+func dial(addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if nil != err {
+		return nil, err
+	}
+	c, err := clientTLSConfig(host)
+	if nil != err {
+		return nil, err
+	}
+	if nil != c {
+		return tls.Dial("tcp", addr, c)
+	}
+	return net.Dial("tcp", addr)
 }
 
 //  // startServer starts a display server with the filename exe (if not already running).
@@ -80,22 +246,29 @@ func (p *RemoteDisplay) writeLine(line []string) {
 //  }
 
 // Connect is called by the client and tries to dial.
-// On success PtrRpc is valid afterwards and the output is re-directed.
-// Otherwise an error code is stored inside remotDisplay.
+// On success the connection is valid afterwards and the output is re-directed.
+// Otherwise an error code is stored inside remotDisplay and the RemoteDisplay stays disconnected.
 func (p *RemoteDisplay) Connect() {
 	addr := p.IPAddr + ":" + p.IPPort
-	if nil != p.PtrRPC {
+	if nil != p.conn {
 		if Verbose {
-			fmt.Fprintln(p.w, "already connected", p.PtrRPC)
+			fmt.Fprintln(p.w, "already connected", p.conn.RemoteAddr())
 		}
 		return
 	}
 	if Verbose {
 		fmt.Fprintln(p.w, "dialing "+addr+" ...")
 	}
-	p.PtrRPC, p.Err = rpc.Dial("tcp", addr)
-	msg.FatalOnErr(p.Err)
-	//p.ErrorFatal()
+	var conn net.Conn
+	conn, p.Err = dial(addr)
+	if nil != p.Err {
+		return
+	}
+	if p.Err = p.hello(conn); nil != p.Err {
+		conn.Close()
+		p.conn = nil
+		return
+	}
 	if Verbose {
 		fmt.Fprintln(p.w, "...remoteDisplay @ "+addr+" connected.")
 	}
@@ -111,9 +284,12 @@ func (p *RemoteDisplay) Connect() {
 func ScShutdownRemoteDisplayServer(w io.Writer, timeStamp int64, args ...string) error {
 	args = append(args, "", "")       // make sure to have at least 2 elements in args.
 	p := NewRemoteDisplay(w, os.Args) //"", "", args[0], args[1])
-	if nil == p.PtrRPC {
+	if nil == p.conn {
 		p.Connect()
 	}
+	if nil != p.Err {
+		return p.Err
+	}
 	p.stopServer(timeStamp)
 	return p.Err
 }
@@ -122,8 +298,8 @@ func ScShutdownRemoteDisplayServer(w io.Writer, timeStamp int64, args ...string)
 // `ts` is used as flag. If 1 shutdown message is with timestamp (default usage), if 0 shutdown message is without timestamp (for testing).
 func (p *RemoteDisplay) stopServer(ts int64) {
 	if Verbose {
-		fmt.Fprintln(p.w, "sending Server.Shutdown...")
+		fmt.Fprintln(p.w, "sending shutdown...")
 	}
-	p.Err = p.PtrRPC.Call("Server.Shutdown", []int64{ts}, nil) // if 1st param nil -> gob: cannot encode nil value
+	p.Err = p.call(message{Type: msgShutdown, Timestamp: 1 == ts})
 	msg.FatalOnErr(p.Err)
 }
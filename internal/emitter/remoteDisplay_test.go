@@ -5,18 +5,144 @@
 package emitter
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"math/rand"
+	"net"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDummy(t *testing.T) {
 }
 
+// connected returns true, if p has a display server connection.
+func (p *RemoteDisplay) connected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return nil != p.conn
+}
+
+func TestRemoteDisplayReconnect(t *testing.T) {
+	defer func(size int, min time.Duration) { DisplayQueueSize, reconnectMin = size, min }(DisplayQueueSize, reconnectMin)
+	defer log.SetFlags(log.Flags())
+	DisplayQueueSize, reconnectMin = 3, time.Millisecond
+
+	h := NewHub(0)
+	lines := make(chan string, 10)
+	h.Subscribe(nil, func(producer string, line []string) {
+		lines <- strings.Join(labeled(producer, line), "")
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	release := make(chan bool) // the server answers the hello only after release
+	go func() {
+		<-release
+		for {
+			conn, err := ln.Accept()
+			if nil != err {
+				return
+			}
+			go serveProducer(h, conn)
+		}
+	}()
+
+	c, s := net.Pipe()
+	assert.Nil(t, s.Close()) // lost connection
+	ip, port, _ := net.SplitHostPort(ln.Addr().String())
+	p := &RemoteDisplay{w: ioutil.Discard, IPAddr: ip, IPPort: port, Name: "bench", conn: c}
+	for _, s := range []string{"1", "2", "3", "4", "5"} {
+		p.writeLine([]string{s})
+	}
+	assert.False(t, p.connected())
+	close(release)
+	for !p.connected() {
+		time.Sleep(time.Millisecond)
+	}
+	p.writeLine([]string{"6"})
+	assert.Nil(t, p.call(message{Type: msgLogFlags})) // local host client is authenticated
+	for _, exp := range []string{
+		"[bench] wrn:remote display: 2 lines dropped while the display server was not reachable",
+		"[bench] 3", "[bench] 4", "[bench] 5", "[bench] 6",
+	} {
+		assert.Equal(t, exp, <-lines)
+	}
+	assert.Equal(t, 0, len(lines))
+}
+
+// TestNewLineWriter checks, that a log instance starting before the display server queues the lines until the server is up.
+func TestNewLineWriter(t *testing.T) {
+	defer func(remote bool, ipa, ipp, name string, min time.Duration) {
+		DisplayRemote, IPAddr, IPPort, ProducerName, reconnectMin = remote, ipa, ipp, name, min
+	}(DisplayRemote, IPAddr, IPPort, ProducerName, reconnectMin)
+	defer log.SetFlags(log.Flags())
+	reconnectMin = time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close()) // no display server yet
+	IPAddr, IPPort, _ = net.SplitHostPort(addr)
+	DisplayRemote, ProducerName = true, "bench"
+	var out bytes.Buffer
+	p, ok := newLineWriter(&out).(*RemoteDisplay)
+	assert.True(t, ok)
+	assert.NotNil(t, p.Err)
+	assert.True(t, strings.HasPrefix(out.String(), "remote display not reachable:"))
+	p.writeLine([]string{"1"})
+
+	h := NewHub(0)
+	lines := make(chan string, 10)
+	h.Subscribe(nil, func(producer string, line []string) {
+		lines <- strings.Join(labeled(producer, line), "")
+	})
+	ln, err = net.Listen("tcp", addr)
+	assert.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if nil != err {
+				return
+			}
+			go serveProducer(h, conn)
+		}
+	}()
+	for !p.connected() {
+		time.Sleep(time.Millisecond)
+	}
+	p.writeLine([]string{"2"})
+	assert.Nil(t, p.call(message{Type: msgLogFlags}))
+	assert.Equal(t, "[bench] 1", <-lines)
+	assert.Equal(t, "[bench] 2", <-lines)
+}
+
+func TestRemoteDisplayWriteTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { writeTimeout = timeout }(writeTimeout)
+	writeTimeout = 10 * time.Millisecond
+
+	c, s := net.Pipe() // s is never read
+	defer s.Close()
+	p := &RemoteDisplay{w: ioutil.Discard, conn: c, reconnecting: true} // no reconnect
+	p.writeLine([]string{"1"})
+	assert.False(t, p.connected())
+	assert.Equal(t, [][]string{{"1"}}, p.queue)
+}
+
+func TestLineWriters(t *testing.T) {
+	var a, b lineBuffer
+	lineWriters{&a, &b}.writeLine([]string{"msg:", "hi"})
+	assert.Equal(t, []string{"msg:", "hi"}, a.line)
+	assert.Equal(t, []string{"msg:", "hi"}, b.line)
+}
+
 /*
 // to do: avoid direct call of trice - it fails on github
 func _TestRemoteDisplay(t *testing.T) {
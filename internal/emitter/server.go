@@ -6,107 +6,181 @@
 package emitter
 
 import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"net/rpc"
 	"time"
 
 	"github.com/rokath/trice/pkg/cage"
 	"github.com/rokath/trice/pkg/msg"
 )
 
-/////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-// This code was derived from the information in:
-// https://stackoverflow.com/questions/37122401/execute-another-go-program-from-within-a-golang-program/37123869#37123869
-// "4 - another way is using "net/rpc", this is the best way for calling another function from another program."
-//
-
-// Server is the RPC struct for registered server functions
+// Server is the connection of a producer to the display server.
+// Each producer connection gets its own Server, all sharing one Hub.
 type Server struct {
-	Display ColorDisplay // todo: LineWriter?
+	hub           *Hub
+	conn          net.Conn
+	producer      string // producer is the name of the connected log instance.
+	authenticated bool   // authenticated is true for a client allowed to send control messages.
 }
 
-// WriteLine is the exported server method for string display, if trice tool acts as display server.
-// By declaring it as a Server struct method it is registered as RPC destination.
-func (p *Server) WriteLine(line []string, reply *int64) error {
-	*reply = int64(len(line))
-	p.Display.writeLine(line)
-	return nil // todo: ? p.Display.lw.Err
+// serveProducer serves the messages of a producer connection until it ends.
+func serveProducer(hub *Hub, conn net.Conn) {
+	defer conn.Close()
+	p := &Server{hub: hub, conn: conn}
+	if err := p.hello(); nil != err {
+		_ = writeFrame(conn, message{Type: msgError, Error: err.Error()}) // the client may be gone already
+		return
+	}
+	hub.register(p.producer)
+	defer hub.unregister(p.producer)
+	if nil != writeFrame(conn, message{Type: msgOK, Version: ProtocolVersion}) {
+		return
+	}
+	for {
+		var m message
+		if nil != readFrame(conn, &m) {
+			return
+		}
+		if msgLine == m.Type {
+			hub.writeLine(p.producer, m.Line)
+			continue
+		}
+		r := message{Type: msgOK}
+		if err := p.control(m); nil != err {
+			r = message{Type: msgError, Error: err.Error()}
+		}
+		if nil != writeFrame(conn, r) {
+			return
+		}
+		if msgShutdown == m.Type && msgOK == r.Type {
+			p.shutdown(m.Timestamp)
+			return
+		}
+	}
 }
 
-// ColorPalette is the exported server function for color palette, if trice tool acts as display server.
-// By declaring it as a Server struct method it is registered as RPC destination.
-func (p *Server) ColorPalette(s []string, reply *int64) error {
-	ColorPalette = s[0]
-	*reply = 0
+// hello checks the first client message for the protocol version and the token.
+// A client is authenticated by the token or, if the server has no token, by a local host connection.
+func (p *Server) hello() error {
+	var m message
+	if err := readFrame(p.conn, &m); nil != err {
+		return err
+	}
+	if msgHello != m.Type {
+		return fmt.Errorf("expected %s message and got %s", msgHello, m.Type)
+	}
+	if ProtocolVersion != m.Version {
+		return fmt.Errorf("protocol version %d not supported, server has version %d", m.Version, ProtocolVersion)
+	}
+	if "" != Token {
+		if 1 != subtle.ConstantTimeCompare([]byte(Token), []byte(m.Token)) {
+			return errors.New("invalid token")
+		}
+		p.authenticated = true
+	} else {
+		p.authenticated = isLoopback(p.conn.RemoteAddr())
+	}
+	p.producer = m.Name
+	if "" == p.producer {
+		p.producer = p.conn.RemoteAddr().String()
+	}
 	return nil
 }
 
-// LogSetFlags is called remotely to ...
-func (p *Server) LogSetFlags(f []int64, r *int64) error {
-	flags := int(f[0])
-	log.SetFlags(flags)
-	*r = f[0]
+// isLoopback returns true for a local host address.
+func isLoopback(a net.Addr) bool {
+	t, ok := a.(*net.TCPAddr)
+	return ok && t.IP.IsLoopback()
+}
+
+// control executes control message m.
+func (p *Server) control(m message) error {
+	switch m.Type {
+	case msgColorPalette, msgLogFlags, msgShutdown:
+		if !p.authenticated {
+			return errors.New(m.Type + " refused for unauthenticated client")
+		}
+	default:
+		return errors.New("unknown message type " + m.Type)
+	}
+	switch m.Type {
+	case msgColorPalette:
+		ColorPalette = m.Value
+	case msgLogFlags:
+		log.SetFlags(m.Flags)
+	}
 	return nil
 }
 
-// Shutdown is called remotely to shut down display server
-func (p *Server) Shutdown(ts []int64, _ *int64) error {
-	timeStamp := ts[0]
-	p.Display.writeLine([]string{""})
-	p.Display.writeLine([]string{""})
-	if 1 == timeStamp { // for normal usage
-		p.Display.writeLine([]string{"time:" + time.Now().String(), "dbg:displayServer shutdown"})
+// shutdown writes the shutdown lines and ends the display server. With timeStamp the shutdown line contains the time.
+func (p *Server) shutdown(timeStamp bool) {
+	p.hub.writeLine("", []string{""})
+	p.hub.writeLine("", []string{""})
+	if timeStamp { // for normal usage
+		p.hub.writeLine("", []string{"time:" + time.Now().String(), "dbg:displayServer shutdown"})
 	} else { // for testing
-		p.Display.writeLine([]string{"dbg:displayServer shutdown"})
+		p.hub.writeLine("", []string{"dbg:displayServer shutdown"})
 	}
-	p.Display.writeLine([]string{""})
-	p.Display.writeLine([]string{""})
-	defer func() {
+	p.hub.writeLine("", []string{""})
+	p.hub.writeLine("", []string{""})
+	if nil != listener {
 		msg.OnErr(listener.Close())
 		exit = true // do not set true before closing listener, otherwise panic!
-	}()
-	return nil
+	}
 }
 
 var (
 	// exit is usually false, when true the display server exits
 	exit = false
 
-	// conn is used only inside ScDisplayServer but here for Shutdown() trials
-	conn net.Conn
-
 	// listener is needed for shutdown
 	listener net.Listener
 )
 
 // ScDisplayServer is the endless function called when trice tool acts as remote display.
-// All in Server struct registered RPC functions are reachable, when displayServer runs.
+// The producers connect with the protocol in protocol.go, optionally secured by TLS and a token.
+// The lines of all connected producers go into a hub, which writes them to the display and the web viewers.
 func ScDisplayServer(w io.Writer) error {
 	cage.Enable(w)
 	defer cage.Disable(w)
 
 	a := fmt.Sprintf("%s:%s", IPAddr, IPPort)
 	fmt.Fprintln(w, "displayServer @", a)
-	srv := new(Server)
-	srv.Display = *NewColorDisplay(w, ColorPalette)
-	msg.OnErr(rpc.Register(srv))
-	var err error
-	listener, err = net.Listen("tcp", a)
+	hub := NewHub(HistorySize)
+	display := NewColorDisplay(w, ColorPalette)
+	hub.Subscribe(producerList(Producers), func(producer string, line []string) {
+		display.writeLine(labeled(producer, line))
+	})
+	if "" != WebAddr {
+		if err := ServeWeb(w, WebAddr, NewWebDisplay(hub, ColorPalette)); nil != err {
+			fmt.Fprintln(w, err)
+			return err
+		}
+	}
+	c, err := serverTLSConfig()
+	if nil == err {
+		listener, err = net.Listen("tcp", a)
+	}
+	if nil == err && nil != c {
+		listener = tls.NewListener(listener, c)
+	}
 	if nil != err {
 		fmt.Fprintln(w, err)
 		return err
 	}
 	for {
-		conn, err = listener.Accept()
+		conn, err := listener.Accept()
 		if nil != err {
 			if true == exit {
 				return err
 			}
 			continue
 		}
-		go rpc.ServeConn(conn)
+		go serveProducer(hub, conn)
 	}
 }
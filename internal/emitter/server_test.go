@@ -0,0 +1,169 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// whitebox test for package emitter.
+package emitter
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeClient returns a RemoteDisplay named name with token connected to a new serveProducer of h and the hello result.
+func pipeClient(h *Hub, name, token string) (*RemoteDisplay, error) {
+	c, s := net.Pipe()
+	go serveProducer(h, s)
+	p := &RemoteDisplay{Name: name, Token: token}
+	return p, p.hello(c)
+}
+
+func TestFrame(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, writeFrame(&b, message{Type: msgLine, Line: []string{"wrn:", "hi"}}))
+	assert.Equal(t, `{"type":"line","line":["wrn:","hi"]}`, string(b.Bytes()[4:]))
+	assert.Equal(t, uint32(b.Len()-4), binary.BigEndian.Uint32(b.Bytes()))
+	var m message
+	assert.Nil(t, readFrame(&b, &m))
+	assert.Equal(t, message{Type: msgLine, Line: []string{"wrn:", "hi"}}, m)
+
+	assert.NotNil(t, readFrame(bytes.NewReader([]byte{0x7f, 0, 0, 0}), &m))   // too big
+	assert.NotNil(t, readFrame(bytes.NewReader([]byte{0, 0, 0, 5, '{'}), &m)) // too short
+}
+
+func TestServeProducer(t *testing.T) {
+	defer func(token, palette string) { Token, ColorPalette = token, palette }(Token, ColorPalette)
+	defer log.SetFlags(log.Flags())
+	Token = "secret"
+	h := NewHub(10)
+	lines, _ := collect(h)
+
+	_, err := pipeClient(h, "bench3/COM5", "wrong")
+	assert.Equal(t, "displayServer: invalid token", err.Error())
+	p1, err := pipeClient(h, "bench1/COM3", "secret")
+	assert.Nil(t, err)
+	p2, err := pipeClient(h, "bench2/COM4", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bench1/COM3", "bench2/COM4"}, h.Producers())
+
+	p1.writeLine([]string{"msg:hi"})
+	assert.Nil(t, p1.call(message{Type: msgColorPalette, Value: "off"})) // the answer comes after the line is written
+	p2.writeLine([]string{"wrn:ho"})
+	assert.Nil(t, p2.call(message{Type: msgLogFlags}))
+	assert.Equal(t, "off", ColorPalette)
+	assert.Equal(t, []string{"[bench1/COM3] msg:hi", "[bench2/COM4] wrn:ho"}, *lines)
+	assert.Equal(t, "displayServer: unknown message type dance", p1.call(message{Type: "dance"}).Error())
+
+	assert.Nil(t, p1.conn.Close())
+	for 1 != len(h.Producers()) {
+		runtime.Gosched()
+	}
+	assert.Equal(t, []string{"bench2/COM4"}, h.Producers())
+	assert.Nil(t, p2.conn.Close())
+}
+
+func TestServeProducerUnauthenticated(t *testing.T) {
+	defer func(token string) { Token = token }(Token)
+	Token = "" // no token: only local host clients are authenticated, a pipe is not
+	h := NewHub(10)
+	lines, _ := collect(h)
+	p, err := pipeClient(h, "", "")
+	assert.Nil(t, err)
+	p.writeLine([]string{"lines are accepted"})
+	assert.Equal(t, "displayServer: shutdown refused for unauthenticated client", p.call(message{Type: msgShutdown}).Error())
+	assert.Equal(t, []string{"[pipe] lines are accepted"}, *lines)
+
+	c, s := net.Pipe()
+	go serveProducer(h, s)
+	p = &RemoteDisplay{}
+	p.conn = c
+	assert.Equal(t, "displayServer: protocol version 2 not supported, server has version 1", p.call(message{Type: msgHello, Version: 2}).Error())
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key into dir and returns the file names.
+func writeTestCert(t *testing.T, dir string) (cert, key string) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "trice test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.PublicKey, k)
+	assert.Nil(t, err)
+	kb, err := x509.MarshalECPrivateKey(k)
+	assert.Nil(t, err)
+	cert, key = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.Nil(t, ioutil.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0600))
+	return
+}
+
+func TestServeProducerTLS(t *testing.T) {
+	defer func(token, cert, key, ca string, tls bool) {
+		Token, TLSCert, TLSKey, TLSCA, TLS = token, cert, key, ca, tls
+	}(Token, TLSCert, TLSKey, TLSCA, TLS)
+	defer log.SetFlags(log.Flags())
+	dir, err := ioutil.TempDir("", "trice")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	TLSCert, TLSKey = writeTestCert(t, dir)
+	TLSCA, TLS, Token = TLSCert, true, ""
+
+	c, err := serverTLSConfig()
+	assert.Nil(t, err)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	ln = tls.NewListener(ln, c)
+	defer ln.Close()
+	h := NewHub(10)
+	lines, _ := collect(h)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if nil != err {
+				return
+			}
+			go serveProducer(h, conn)
+		}
+	}()
+
+	conn, err := dial(ln.Addr().String())
+	assert.Nil(t, err)
+	p := &RemoteDisplay{Name: "secure"}
+	assert.Nil(t, p.hello(conn))
+	p.writeLine([]string{"msg:encrypted"})
+	assert.Nil(t, p.call(message{Type: msgLogFlags})) // local host client is authenticated
+	assert.Equal(t, []string{"[secure] msg:encrypted"}, *lines)
+	assert.Nil(t, conn.Close())
+
+	TLS = false
+	conn, err = dial(ln.Addr().String())
+	assert.Nil(t, err)
+	p = &RemoteDisplay{Name: "plain"}
+	assert.NotNil(t, p.hello(conn)) // no TLS handshake
+	assert.Nil(t, conn.Close())
+}
@@ -0,0 +1,290 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package emitter
+
+// web viewer for the display server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebAddr is the HTTP listen address of the display server web viewer, like ":8080". "" means no web viewer.
+var WebAddr string
+
+// webViewerBuffer is the count of lines buffered per viewer additionally to the history. A viewer not reading fast enough loses lines.
+const webViewerBuffer = 1000
+
+// webLine is the JSON representation of a line sent to the web viewers.
+type webLine struct {
+	Producer string   `json:"producer,omitempty"` // Producer is the name of the log instance sending the line.
+	Channels []string `json:"channels,omitempty"` // Channels are the channel names of the line parts, if any.
+	Text     string   `json:"text"`               // Text is the line with ANSI color codes according to the color palette.
+}
+
+// lineBuffer is a LineWriter keeping the last written line.
+type lineBuffer struct {
+	line []string
+}
+
+func (p *lineBuffer) writeLine(line []string) {
+	p.line = line
+}
+
+// WebDisplay streams the lines of a Hub as Server-Sent Events to web browsers.
+// The coloring follows ColorChannels. The browsers filter the lines by producer, channel and text.
+type WebDisplay struct {
+	hub *Hub
+	mu  sync.Mutex           // mu guards lt and buf.
+	lt  *LineTransformerANSI // lt colors the lines into buf without counting channel events again.
+	buf lineBuffer
+}
+
+// NewWebDisplay creates a WebDisplay for the lines of hub coloring them according to colorPalette.
+func NewWebDisplay(hub *Hub, colorPalette string) *WebDisplay {
+	p := &WebDisplay{hub: hub}
+	p.lt = &LineTransformerANSI{lw: &p.buf, colorPalette: colorPalette, uncounted: true}
+	return p
+}
+
+// encode returns the JSON representation of line from producer.
+func (p *WebDisplay) encode(producer string, line []string) []byte {
+	x := webLine{Producer: producer}
+	for _, s := range line {
+		if c := channelName(channel(s)); "" != c {
+			x.Channels = append(x.Channels, c)
+		}
+	}
+	p.mu.Lock()
+	p.lt.writeLine(line)
+	x.Text = strings.TrimRight(strings.Join(p.buf.line, ""), "\r\n")
+	p.mu.Unlock()
+	b, _ := json.Marshal(x) // cannot fail for strings
+	return b
+}
+
+// Handler returns the HTTP handler for the web viewer page and its event stream.
+func (p *WebDisplay) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if "/" != r.URL.Path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, webPage)
+	})
+	mux.HandleFunc("/channels", webChannels)
+	mux.HandleFunc("/producers", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p.hub.Producers())
+	})
+	mux.HandleFunc("/events", p.events)
+	return mux
+}
+
+// webChannels writes the channel variants by channel name as JSON, so the viewers accept all variants in their filters.
+func webChannels(w http.ResponseWriter, _ *http.Request) {
+	m := make(map[string][]string, len(ColorChannels))
+	for i := range ColorChannels {
+		m[ColorChannels[i].name()] = ColorChannels[i].channel
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// events streams the lines as Server-Sent Events until the viewer disconnects.
+// The query parameter "producers" is a comma separated list of the producers to stream. Without it all lines are streamed.
+// The stream starts with the history.
+func (p *WebDisplay) events(w http.ResponseWriter, r *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	c := make(chan []byte, cap(p.hub.history)+webViewerBuffer)
+	unsubscribe := p.hub.Subscribe(producerList(r.URL.Query().Get("producers")), func(producer string, line []string) {
+		select {
+		case c <- p.encode(producer, line):
+		default: // viewer too slow
+		}
+	})
+	defer unsubscribe()
+	f.Flush()
+	for {
+		select {
+		case b := <-c:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); nil != err {
+				return
+			}
+			f.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ServeWeb listens on addr and serves the web viewer of p. It returns after the listener is set up.
+func ServeWeb(w io.Writer, addr string, p *WebDisplay) error {
+	ln, err := net.Listen("tcp", addr)
+	if nil != err {
+		return err
+	}
+	fmt.Fprintln(w, "web viewer @ http://"+ln.Addr().String())
+	go func() {
+		fmt.Fprintln(w, http.Serve(ln, p.Handler()))
+	}()
+	return nil
+}
+
+// webPage is the web viewer. It converts the ANSI color codes into HTML and filters the lines.
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>trice</title>
+<style>
+body { margin: 0; background: #000; color: #e5e5e5; font: 13px monospace; }
+#bar { position: sticky; top: 0; background: #222; padding: 4px; }
+#bar input[type=text] { font: inherit; background: #111; color: #e5e5e5; border: 1px solid #555; width: 12em; }
+#log { white-space: pre-wrap; padding: 4px; }
+.producer { color: #7f7f7f; }
+.blink { animation: blink 1s step-end infinite; }
+@keyframes blink { 50% { opacity: 0; } }
+</style>
+</head>
+<body>
+<div id="bar">
+producers <input type="text" id="producers" placeholder="all" list="producerList">
+<datalist id="producerList"></datalist>
+ban <input type="text" id="ban" placeholder="dbg:wrn">
+pick <input type="text" id="pick" placeholder="err:msg">
+search <input type="text" id="search">
+<label><input type="checkbox" id="follow" checked>follow</label>
+<span id="state"></span>
+</div>
+<div id="log"></div>
+<script>
+var maxLines = 10000, lines = [], variants = {}, ban = [], pick = [], search = "";
+var log = document.getElementById("log"), state = document.getElementById("state");
+var base = ["#000", "#c00", "#0c0", "#cc0", "#00e", "#c0c", "#0cc", "#e5e5e5", "#7f7f7f", "#f00", "#0f0", "#ff0", "#5c5cff", "#f0f", "#0ff", "#fff"];
+
+// xterm returns the CSS color of the 256 color palette entry n.
+function xterm(n) {
+	if (n < 16) return base[n];
+	if (n < 232) {
+		n -= 16;
+		var c = [0, 95, 135, 175, 215, 255];
+		return "rgb(" + c[Math.floor(n / 36)] + "," + c[Math.floor(n / 6) % 6] + "," + c[n % 6] + ")";
+	}
+	var g = 8 + (n - 232) * 10;
+	return "rgb(" + g + "," + g + "," + g + ")";
+}
+
+function escapeHTML(s) {
+	return s.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;").replace(/"/g, "&quot;");
+}
+
+// toHTML converts the ANSI color codes in s into HTML spans.
+function toHTML(s) {
+	var out = "", st = {}, re = /\x1b\[([0-9;]*)m/g, i = 0, m;
+	function span(t) {
+		if (!t) return;
+		var fg = st.fg, bg = st.bg, css = "";
+		if (st.inverse) { fg = st.bg || "#000"; bg = st.fg || "#e5e5e5"; }
+		if (fg) css += "color:" + fg + ";";
+		if (bg) css += "background:" + bg + ";";
+		if (st.bold) css += "font-weight:bold;";
+		if (st.underline) css += "text-decoration:underline;";
+		out += (css || st.blink) ? '<span style="' + css + '"' + (st.blink ? ' class="blink"' : '') + '>' + escapeHTML(t) + '</span>' : escapeHTML(t);
+	}
+	while ((m = re.exec(s))) {
+		span(s.slice(i, m.index));
+		i = re.lastIndex;
+		var c = m[1].split(";").map(Number);
+		for (var k = 0; k < c.length; k++) {
+			var n = c[k];
+			if (n === 0) st = {};
+			else if (n === 1) st.bold = true;
+			else if (n === 4) st.underline = true;
+			else if (n === 5) st.blink = true;
+			else if (n === 7) st.inverse = true;
+			else if (n >= 30 && n <= 37) st.fg = base[n - 30];
+			else if (n >= 90 && n <= 97) st.fg = base[n - 82];
+			else if (n >= 40 && n <= 47) st.bg = base[n - 40];
+			else if (n >= 100 && n <= 107) st.bg = base[n - 92];
+			else if (n === 39) delete st.fg;
+			else if (n === 49) delete st.bg;
+			else if ((n === 38 || n === 48) && c[k + 1] === 5) { st[n === 38 ? "fg" : "bg"] = xterm(c[k + 2]); k += 2; }
+		}
+	}
+	span(s.slice(i));
+	return out;
+}
+
+// names returns the channel names of the channel variants in s, like "dbg:wrn".
+function names(s) {
+	return s.split(/[\s,:]+/).filter(function (v) { return v; }).map(function (v) { return variants[v] || v.toLowerCase(); });
+}
+
+function visible(l) {
+	var ch = l.channels || ["default"];
+	if (ch.some(function (c) { return ban.indexOf(c) >= 0; })) return false;
+	if (pick.length && !ch.some(function (c) { return pick.indexOf(c) >= 0; })) return false;
+	return !search || l.plain.toLowerCase().indexOf(search) >= 0;
+}
+
+function filter() {
+	ban = names(document.getElementById("ban").value);
+	pick = names(document.getElementById("pick").value);
+	search = document.getElementById("search").value.toLowerCase();
+	lines.forEach(function (l) { l.div.style.display = visible(l) ? "" : "none"; });
+}
+
+function add(l) {
+	l.plain = (l.producer ? "[" + l.producer + "] " : "") + l.text.replace(/\x1b\[[0-9;]*m/g, "");
+	l.div = document.createElement("div");
+	l.div.innerHTML = (l.producer ? '<span class="producer">[' + escapeHTML(l.producer) + '] </span>' : '') + (toHTML(l.text) || "&nbsp;");
+	l.div.style.display = visible(l) ? "" : "none";
+	lines.push(l);
+	log.appendChild(l.div);
+	if (lines.length > maxLines) log.removeChild(lines.shift().div);
+	if (document.getElementById("follow").checked) window.scrollTo(0, document.body.scrollHeight);
+}
+
+["ban", "pick", "search"].forEach(function (id) { document.getElementById(id).addEventListener("input", filter); });
+fetch("channels").then(function (r) { return r.json(); }).then(function (m) {
+	for (var name in m) m[name].forEach(function (v) { variants[v] = name; });
+	filter();
+});
+// connect (re)starts the event stream for the selected producers. The server sends their history first.
+var es;
+function connect() {
+	if (es) es.close();
+	var producers = document.getElementById("producers").value.split(",").map(function (v) { return v.trim(); }).filter(function (v) { return v; });
+	es = new EventSource("events" + (producers.length ? "?producers=" + encodeURIComponent(producers.join(",")) : ""));
+	es.onopen = function () { // also after a reconnect, when the history comes again
+		lines = [];
+		log.innerHTML = "";
+		state.textContent = "connected";
+		fetch("producers").then(function (r) { return r.json(); }).then(function (list) {
+			document.getElementById("producerList").innerHTML = list.map(function (v) { return '<option value="' + escapeHTML(v) + '">'; }).join("");
+		});
+	};
+	es.onerror = function () { state.textContent = "reconnecting..."; };
+	es.onmessage = function (e) { add(JSON.parse(e.data)); };
+}
+document.getElementById("producers").addEventListener("change", connect);
+connect();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,86 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// whitebox test for package emitter.
+package emitter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebDisplay(t *testing.T) {
+	hub := NewHub(10)
+	hub.register("bench1/COM3")
+	hub.register("bench2/COM4")
+	hub.writeLine("bench1/COM3", []string{"early"})
+	hub.writeLine("bench2/COM4", []string{"other"})
+	p := NewWebDisplay(hub, "default")
+	s := httptest.NewServer(p.Handler())
+	defer s.Close()
+
+	resp, err := http.Get(s.URL)
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Body.Close())
+	assert.Contains(t, string(b), `new EventSource("events"`)
+
+	resp, err = http.Get(s.URL + "/channels")
+	assert.Nil(t, err)
+	var m map[string][]string
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&m))
+	assert.Nil(t, resp.Body.Close())
+	assert.Contains(t, m["warning"], "wrn")
+	assert.Contains(t, m["warning"], "WARNING")
+
+	resp, err = http.Get(s.URL + "/producers")
+	assert.Nil(t, err)
+	var producers []string
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&producers))
+	assert.Nil(t, resp.Body.Close())
+	assert.Equal(t, []string{"bench1/COM3", "bench2/COM4"}, producers)
+
+	resp, err = http.Get(s.URL + "/events?producers=bench1/COM3")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	for 0 == hub.viewerCount() {
+		time.Sleep(time.Millisecond)
+	}
+	hub.writeLine("bench1/COM3", []string{"wrn:hello", " world"})
+	hub.writeLine("bench2/COM4", []string{"not subscribed"})
+	hub.writeLine("bench1/COM3", []string{"plain"})
+
+	r := bufio.NewReader(resp.Body)
+	var x webLine
+	event := func() {
+		line, err := r.ReadString('\n')
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(line, "data: "))
+		x = webLine{}
+		assert.Nil(t, json.Unmarshal([]byte(line[len("data: "):]), &x))
+		empty, err := r.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, "\n", empty)
+	}
+	event() // history
+	assert.Equal(t, "bench1/COM3", x.Producer)
+	assert.Equal(t, "early", x.Text)
+	event()
+	assert.Equal(t, "bench1/COM3", x.Producer)
+	assert.Equal(t, []string{"warning"}, x.Channels)
+	assert.True(t, strings.HasPrefix(x.Text, "\x1b[")) // colored
+	assert.Contains(t, x.Text, "mhello\x1b[0m world")  // channel removed
+	event()
+	assert.Nil(t, x.Channels)
+	assert.Equal(t, "plain", x.Text)
+}
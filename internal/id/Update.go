@@ -30,16 +30,6 @@ const (
 	// patFmtString is a regex matching the first format string inside trice
 	patFmtString = `"(.*)"`
 
-	// patNbTRICE is a regex pattern matching any "TRICE*(Id(n), "", ... )". - see https://regex101.com/r/mllhNQ/1
-	// https://regex101.com/r/4hz1r8/1
-	patNbTRICE = patTypNameTRICE + `\s*\(` + patID + `\(\s*.*[0-9]\s*\)\s*,\s*` + patFmtString + `\s*.*\)`
-
-	// https://regex101.com/r/hWMjhU/3 - match any kind of trice with or without len or ID
-	patFullAnyTrice = patTypNameTRICE + `\s*\(\s*(` + patID + `\((\s*\d+)\s*\)\s*,)?\s*` + patFmtString + `\s*.*\)`
-
-	// patAnyTriceStart finds a starting trice with opening '(': https://regex101.com/r/wPuT4M/1
-	patAnyTriceStart = patTypNameTRICE + `\s*\(`
-
 	// patNextFormatSpecifier is a regex to find next format specifier in a string (exclude %%*)
 	patNextFormatSpecifier = `(?:^|[^%])(%[0-9\.#]*(b|c|d|u|x|X|o|f))`
 
@@ -48,24 +38,17 @@ const (
 
 	patID = `\s*\bId\b\s*` // `\s*\b(I|i)d\b\s*`
 
-	// patNbID is a regex pattern matching any (first in string) "Id(n)" and usable in matches of matchNbTRICE
+	// patNbID is a regex pattern matching any (first in string) "Id(n)" and usable for the first TRICE macro argument
 	patNbID = `\b` + patID + `\(\s*[0-9]*\s*\)`
-
-	// patIdInsideTrice finds if an `( Id(n) ,"` sequence exists inside trice
-	patIDInsideTrice = `(?U)\(` + patID + `\((\s*\d+)\s*\)\s*,\s*"`
 )
 
 var (
 	matchSourceFile            = regexp.MustCompile(patSourceFile)
-	matchNbTRICE               = regexp.MustCompile(patNbTRICE)
 	matchNbID                  = regexp.MustCompile(patNbID)
 	matchTypNameTRICE          = regexp.MustCompile(patTypNameTRICE)
 	matchFmtString             = regexp.MustCompile(patFmtString)
 	matchNextFormatSpecifier   = regexp.MustCompile(patNextFormatSpecifier)
-	matchFullAnyTrice          = regexp.MustCompile(patFullAnyTrice)
 	matchTriceNoLen            = regexp.MustCompile(patTriceNoLen)
-	matchIDInsideTrice         = regexp.MustCompile(patIDInsideTrice)
-	matchAnyTriceStart         = regexp.MustCompile(patAnyTriceStart)
 	ExtendMacrosWithParamCount bool
 
 	// DefaultTriceBitWidth tells the bit width of TRICE macros having no bit width in their names, like TRICE32 or TRICE8.
@@ -85,54 +68,46 @@ var (
 // text is the full filecontents, which could be modified, therefore it is also returned with a modified flag
 func updateParamCountAndID0(w io.Writer, text string, extendMacroName bool) (string, bool) {
 	var modified bool
-	subs := text[:] // create a copy of text and assign it to subs
-	for {
-		loc := matchFullAnyTrice.FindStringIndex(subs) // find the next TRICE location in file
-		if nil == loc {
-			return text, modified // done
+	var b strings.Builder
+	last := 0 // last is the text position up to which text is copied into b
+	for _, c := range findTrices(text) {
+		if !c.hasStrg {
+			continue // no TRICE macro call, but for example a function definition
 		}
-		trice := subs[loc[0]:loc[1]] // the whole TRICE*(*);
-		triceC := trice              // make a copy
-		if extendMacroName {
-			locNoLen := matchTriceNoLen.FindStringIndex(trice) // find the next TRICE no len location in trice
-			if nil != locNoLen {                               // need to add len to trice name
-				n := FormatSpecifierCount(triceC)
-				triceNameNoLen := triceC[locNoLen[0]:locNoLen[1]]
-				triceNameWithLen := addFormatSpecifierCount(w, triceNameNoLen, n)
-				// In this example `Trice8_1( id(  800), "rd:Trice8 line %d, %d\n", __LINE__, 2 );` triceNameNoLen is "Trice8",
-				// that is the Trice8 after "rd:" and triceNameWithLen is Trice8_1.
-				// The following line would replace the first `Trice8` which is part of `Trice8_1` with `Trice8_1` resulting in
-				// `Trice8_1_1( id(  800), "rd:Trice8 line %d, %d\n", __LINE__, 2 );` what is a bug.
-				// triceC = strings.Replace(triceC, triceNameNoLen, triceNameWithLen, 1) // insert _n
-				// Therefore the replacement is donne manually:
-				triceC = triceC[:locNoLen[0]] + triceNameWithLen + triceC[locNoLen[1]:] // insert _n
+		name := c.name
+		if extendMacroName && matchTriceNoLen.MatchString(c.name) { // need to add len to trice name
+			n := FormatSpecifierCount(c.strg)
+			name = addFormatSpecifierCount(w, c.name, n)
+			if name != c.name {
+				b.WriteString(text[last:c.nameStart])
+				b.WriteString(name) // insert _n
+				last = c.nameEnd
 				modified = true
 				if Verbose {
-					fmt.Fprint(w, triceNameNoLen)
+					fmt.Fprint(w, c.name)
 					fmt.Fprint(w, " -> ")
-					fmt.Fprintln(w, triceNameWithLen)
+					fmt.Fprintln(w, name)
 				}
 			}
 		}
-		// here trice name in triceC contains _n and now we need to check for Id existence.
-		// triceC could have been modified here but text is unchanged so far.
-		idLoc := matchIDInsideTrice.FindStringIndex(triceC)
-		if nil == idLoc { // no Id(n) inside trice, so we add it
-			triceO := matchAnyTriceStart.FindString(triceC) // TRICE*( part (the trice start)
-			triceU := triceO + " Id(0),"
-			triceC = strings.Replace(triceC, triceO, triceU, 1) // insert Id(0) into trice copy
+		if !c.hasID { // no Id(n) inside trice, so we add it
+			b.WriteString(text[last:c.open])
+			b.WriteString(" Id(0),")
+			last = c.open
 			modified = true
 			if Verbose {
+				triceO := name + text[c.nameEnd:c.open] // TRICE*( part (the trice start)
 				fmt.Fprint(w, triceO)
 				fmt.Fprint(w, " -> ")
-				fmt.Fprintln(w, triceU)
+				fmt.Fprintln(w, triceO+" Id(0),")
 			}
 		}
-		if modified {
-			text = strings.Replace(text, trice, triceC, 1) // this works, because a trice gets changed only once
-		}
-		subs = subs[loc[1]:] // The replacement makes text not shorter, so next search can start at loc[1]
 	}
+	if !modified {
+		return text, false
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
 }
 
 // FormatSpecifierCount parses s for format specifier and returns the found count.
@@ -164,16 +139,18 @@ func addFormatSpecifierCount(w io.Writer, s string, n int) (sl string) {
 	return
 }
 
-func isSourceFile(fi os.FileInfo) bool {
-	return matchSourceFile.MatchString(fi.Name())
-}
-
-func refreshList(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp) {
+// refreshList adds the id:tf pairs found in source tree root to lu and tflu. If si is not nil, it collects the source locations.
+// sc reads the source files.
+func refreshList(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, si *sourceInfo, sc *scanner) {
 	if Verbose {
 		fmt.Fprintln(w, "dir=", root)
 		fmt.Fprintln(w, "List=", FnJSON)
 	}
-	msg.FatalInfoOnErr(filepath.Walk(root, visitRefresh(w, lu, tflu)), "failed to walk tree")
+	msg.FatalInfoOnErr(sc.walk(w, root, func(f *srcFile) error {
+		refreshCalls(w, f.calls, lu, tflu)
+		si.recordCalls(f.path, f.calls, lu)
+		return nil
+	}), "failed to walk tree")
 }
 
 // Additional actions needed: (Option -dry-run lets do a check in advance.)
@@ -186,20 +163,28 @@ func refreshList(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp
 //   - If none of the more than 1 time used ID is in til.json set all to 0 with message.
 // - Check if in source code exist IDs not in til.json so far and extend til.json if there is no conflict.
 //  - If the ID in soure code is already used in til.json differently set the ID in source code to 0 with message.
-// NOT NEEDED: - Check if in til.json ID's not in source tree and mark them with `removed` timestamp.
-// NOT NEEDED:   - If several source trees use same til.json, the `removed` timestamp is without sense.
-// NOT NEEDED:   - If a `removed` timestamp is set, but the ID is in the source tree the `removed` timestamp is set to 0.
+// - Check if in til.json ID's not in source tree and mark them with a `Removed` date (see sourceInfo).
+//   - If several source trees use same til.json, all of them need to be passed with -src, otherwise the `Removed` date is without sense.
+//   - If a `Removed` date is set, but the ID is in the source tree, the `Removed` date is cleared.
 
 // Update is parsing source tree root which is part of Srcs and performing these actions:
 // - replace.Type( Id(0), ...) with.Type( Id(n), ...)
 // - find duplicate.Type( Id(n), ...) and replace one of them if trices are not identical
 // - extend file fnIDList
 func IDsUpdate(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) {
+	idsUpdate(w, root, lu, tflu, nil, newScanner(w, ""), make(idSpaces), pListModified)
+}
+
+// idsUpdate is like IDsUpdate. If si is not nil, it collects the source locations. sc reads the source files.
+// spaces keeps the free ID bitmaps of lu between the files.
+func idsUpdate(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, si *sourceInfo, sc *scanner, spaces idSpaces, pListModified *bool) {
 	if Verbose && FnJSON != "emptyFile" {
 		fmt.Fprintln(w, "dir=", root)
 		fmt.Fprintln(w, "List=", FnJSON)
 	}
-	msg.FatalInfoOnErr(filepath.Walk(root, visitUpdate(w, lu, tflu, pListModified)), "failed to walk tree")
+	msg.FatalInfoOnErr(sc.walk(w, root, func(f *srcFile) error {
+		return updateFile(w, f, lu, tflu, si, sc, spaces, pListModified)
+	}), "failed to walk tree")
 }
 
 func readFile(w io.Writer, path string, fi os.FileInfo, err error) (string, error) {
@@ -217,56 +202,73 @@ func readFile(w io.Writer, path string, fi os.FileInfo, err error) (string, erro
 	return text, nil
 }
 
-func visitRefresh(w io.Writer, lu TriceIDLookUp, tflu TriceFmtLookUp) filepath.WalkFunc {
-	return func(path string, fi os.FileInfo, err error) error {
-		text, err := readFile(w, path, fi, err)
-		if nil != err {
-			return err
-		}
-		refreshIDs(w, text, lu, tflu) // update IDs: Id(0) -> Id(M)
+// updateFile updates the IDs inside source file f and writes it back on changes. The files are processed one after
+// the other in walk order, so the ID assignment is deterministic even though the files are parsed in parallel.
+// A file without TRICE macros needing a change is not read again.
+func updateFile(w io.Writer, f *srcFile, lu TriceIDLookUp, tflu TriceFmtLookUp, si *sourceInfo, sc *scanner, spaces idSpaces, pListModified *bool) error {
+	refreshCalls(w, f.calls, lu, tflu) // update IDs: Id(0) -> Id(M)
+	min, max, searchMethod := Ranges.forFile(f.path)
+	if !needsChange(f.calls, lu) {
+		updateCalls(w, SharedIDs, spaces, min, max, searchMethod, "", f.calls, lu, tflu, pListModified) // only lu & tflu are updated
+		si.recordCalls(f.path, f.calls, lu)
 		return nil
 	}
-}
+	text, err := f.content()
+	if nil != err {
+		return err
+	}
+	textN, fileModified0 := updateParamCountAndID0(w, text, ExtendMacrosWithParamCount)                                         // update parameter count: TRICE* to TRICE*_n and insert missing Id(0)
+	textU, fileModified1 := updateIDsUniqOrShared(w, SharedIDs, spaces, min, max, searchMethod, textN, lu, tflu, pListModified) // update IDs: Id(0) -> Id(M)
+	si.record(f.path, textU, lu)
 
-func visitUpdate(w io.Writer, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) filepath.WalkFunc {
-	// WalkFunc is the type of the function called for each file or directory
-	// visited by Walk. The path argument contains the argument to Walk as a
-	// prefix; that is, if Walk is called with "dir", which is a directory
-	// containing the file "a", the walk function will be called with argument
-	// "dir/a". The info argument is the os.FileInfo for the named path.
-	//
-	// If there was a problem walking to the file or directory named by path, the
-	// incoming error will describe the problem and the function can decide how
-	// to handle that error (and Walk will not descend into that directory). In the
-	// case of an error, the info argument will be nil. If an error is returned,
-	// processing stops. The sole exception is when the function returns the special
-	// value SkipDir. If the function returns SkipDir when invoked on a directory,
-	// Walk skips the directory's contents entirely. If the function returns SkipDir
-	// when invoked on a non-directory file, Walk skips the remaining files in the
-	// containing directory.
-	return func(path string, fi os.FileInfo, err error) error {
-		text, err := readFile(w, path, fi, err)
+	// write out
+	fileModified := fileModified0 || fileModified1
+	if fileModified && !DryRun {
+		if Verbose {
+			fmt.Fprintln(w, "Changed: ", f.path)
+		}
+		err = ioutil.WriteFile(f.path, []byte(textU), f.fi.Mode())
 		if nil != err {
-			return err
+			return fmt.Errorf("failed to change %s: %v", f.path, err)
 		}
-		refreshIDs(w, text, lu, tflu) // update IDs: Id(0) -> Id(M)
-
-		textN, fileModified0 := updateParamCountAndID0(w, text, ExtendMacrosWithParamCount)                                 // update parameter count: TRICE* to TRICE*_n and insert missing Id(0)
-		textU, fileModified1 := updateIDsUniqOrShared(w, SharedIDs, Min, Max, SearchMethod, textN, lu, tflu, pListModified) // update IDs: Id(0) -> Id(M)
+		sc.changed(f, textU)
+	}
+	return nil
+}
 
-		// write out
-		fileModified := fileModified0 || fileModified1
-		if fileModified && !DryRun {
-			if Verbose {
-				fmt.Fprintln(w, "Changed: ", path)
-			}
-			err = ioutil.WriteFile(path, []byte(textU), fi.Mode())
-			if nil != err {
-				return fmt.Errorf("failed to change %s: %v", path, err)
+// needsChange returns true, if updateParamCountAndID0 or updateIDsUniqOrShared would change the text containing calls.
+// lu needs to be refreshed with calls before.
+func needsChange(calls []triceCall, lu TriceIDLookUp) bool {
+	own := make(TriceIDLookUp) // own holds the id:tf pairs of calls, as updateIDsUniqOrShared adds them to lu during its run
+	for _, c := range calls {
+		if !c.hasStrg {
+			continue
+		}
+		if !c.hasID {
+			return true // Id(0) insertion
+		}
+		if ExtendMacrosWithParamCount && matchTriceNoLen.MatchString(c.name) {
+			if n := FormatSpecifierCount(c.strg); 0 < n && n < 99 {
+				return true // _n extension
 			}
 		}
-		return nil
+		if !c.idOK {
+			continue
+		}
+		if 0 == c.id {
+			return true
+		}
+		tf := TriceFmt{Type: c.name, Strg: c.strg}
+		tfL, ok := own[c.id]
+		if !ok {
+			tfL, ok = lu[c.id]
+		}
+		if ok && !sameFmt(tf, tfL) {
+			return true // used differently
+		}
+		own[c.id] = tf
 	}
+	return false
 }
 
 // triceIDParse returns an extracted id and found as true if t starts with s.th. like 'TRICE*( Id(n)...'
@@ -321,21 +323,17 @@ func triceParse(t string) (nbID string, id TriceID, tf TriceFmt, found bool) {
 
 // refreshIDs parses text for valid trices tf and adds them to lu & tflu.
 func refreshIDs(w io.Writer, text string, lu TriceIDLookUp, tflu TriceFmtLookUp) {
-	subs := text[:] // create a copy of text and assign it to subs
-	for {
-		loc := matchNbTRICE.FindStringSubmatchIndex(subs) // find the next TRICE location in file
-		if nil == loc {
-			return // done
-		}
-		nbTRICE := subs[loc[0]:loc[1]] // full trice expression with Id(n)
-		// prepare subs for next loop
-		subs = subs[loc[1]:] // A possible Id(0) replacement makes subs not shorter, so next search can start at loc[1].
-		// A case like 'TRICE*( Id(                             0                              ), "");' is not expected.
+	refreshCalls(w, findTrices(text), lu, tflu)
+}
 
-		_, id, tf, found := triceParse(nbTRICE)
-		if !found {
+// refreshCalls adds the valid trices tf from calls to lu & tflu.
+func refreshCalls(w io.Writer, calls []triceCall, lu TriceIDLookUp, tflu TriceFmtLookUp) {
+	for _, c := range calls {
+		if !c.hasID || !c.idOK || !c.hasStrg {
 			continue
 		}
+		id := c.id
+		tf := TriceFmt{Type: c.name, Strg: c.strg}
 		tfS := tf
 		tfS.Type = strings.ToUpper(tfS.Type) // Lower case and upper case Type are not distinguished.
 
@@ -369,24 +367,23 @@ func refreshIDs(w io.Writer, text string, lu TriceIDLookUp, tflu TriceFmtLookUp)
 // *pListModified in result is true if any file was changed.
 // tflu holds the tf in upper case.
 // lu holds the tf in source code case. If in source code upper and lower case occur, than only one can be in lu.
-// sharedIDs, if true, reuses IDs for identical format strings.
-func updateIDsUniqOrShared(w io.Writer, sharedIDs bool, min, max TriceID, searchMethod string, text string, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) (string, bool) {
-	var fileModified bool
-	subs := text[:] // create a copy of text and assign it to subs
-	for {
-		loc := matchNbTRICE.FindStringIndex(subs) // find the next TRICE location in file
-		if nil == loc {
-			return text, fileModified // done
-		}
-		nbTRICE := subs[loc[0]:loc[1]] // full trice expression with Id(n)
-		// prepare subs for next loop
-		subs = subs[loc[1]:] // A possible Id(0) replacement makes subs not shorter, so next search can start at loc[1].
-		// A case like 'TRICE*( Id(                             0                              ), "");' is not expected.
+// sharedIDs, if true, reuses IDs for identical format strings. New IDs are searched with the free ID bitmaps in spaces, which could be nil.
+func updateIDsUniqOrShared(w io.Writer, sharedIDs bool, spaces idSpaces, min, max TriceID, searchMethod string, text string, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) (string, bool) {
+	return updateCalls(w, sharedIDs, spaces, min, max, searchMethod, text, findTrices(text), lu, tflu, pListModified)
+}
 
-		nbID, id, tf, found := triceParse(nbTRICE)
-		if !found {
+// updateCalls is like updateIDsUniqOrShared for the TRICE macros calls found inside text.
+// If needsChange(calls, lu) is false, text is not used and could be empty.
+func updateCalls(w io.Writer, sharedIDs bool, spaces idSpaces, min, max TriceID, searchMethod string, text string, calls []triceCall, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) (string, bool) {
+	var fileModified bool
+	var b strings.Builder
+	last := 0 // last is the text position up to which text is copied into b
+	for _, c := range calls {
+		if !c.hasID || !c.idOK || !c.hasStrg {
 			continue
 		}
+		id := c.id
+		tf := TriceFmt{Type: c.name, Strg: c.strg}
 		tf.Type = strings.ToUpper(tf.Type) // Lower case and upper case Type are not distinguished for normal trices in shared IDs mode.
 
 		// In lu id could point to a different tf. So we need to check that and invalidate id in that case.
@@ -402,13 +399,13 @@ func updateIDsUniqOrShared(w io.Writer, sharedIDs bool, min, max TriceID, search
 		}
 		if id <= 0 { // marked as invalid: id is 0 or inside lu used differently
 
-			invalID := nbID
-			invalTRICE := nbTRICE
+			invalID := text[c.idStart:c.idEnd]
 
+			var found bool
 			if id, found = tflu[tf]; sharedIDs && found { // yes, we can use it in shared IDs mode
 				msg.FatalInfoOnTrue(id == 0, "no id 0 allowed in map")
 			} else { // no, we need a new one
-				id = lu.newID(w, min, max, searchMethod) // a prerequisite is a in a previous step refreshed lu
+				id = lu.newID(w, spaces, min, max, searchMethod) // a prerequisite is a in a previous step refreshed lu
 				*pListModified = true
 			}
 			// patch the id into text
@@ -419,19 +416,25 @@ func updateIDsUniqOrShared(w io.Writer, sharedIDs bool, min, max TriceID, search
 				}
 				fmt.Fprintln(w, nID)
 			}
-			nbTRICE := strings.Replace(nbTRICE, invalID, nID, 1)
-			text = strings.Replace(text, invalTRICE, nbTRICE, 1)
+			b.WriteString(text[last:c.idStart])
+			b.WriteString(nID)
+			last = c.idEnd
 			fileModified = true
 		}
 		// update map: That is needed after an invalid trice or if id:tf is valid but not inside lu & tflu yet, for example after manual code changes or forgotten refresh before update.
 		lu[id] = tf
 		tflu[tf] = id // no distinction for lower and upper case Type
 	}
+	if !fileModified {
+		return text, false
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
 }
 
 // ZeroSourceTreeIds is overwriting with 0 all id's from source code tree srcRoot. It does not touch idlist.
 func ZeroSourceTreeIds(w io.Writer, srcRoot string, run bool) {
-	err := filepath.Walk(srcRoot, visitZeroSourceTreeIds(w, run))
+	err := walkSrcTree(srcRoot, visitZeroSourceTreeIds(w, run))
 	if err != nil {
 		panic(err)
 	}
@@ -454,7 +457,7 @@ func visitZeroSourceTreeIds(w io.Writer, run bool) filepath.WalkFunc {
 	// when invoked on a non-directory file, Walk skips the remaining files in the
 	// containing directory.
 	return func(path string, fi os.FileInfo, err error) error {
-		if fi.IsDir() || !isSourceFile(fi) || err != nil {
+		if err != nil || fi.IsDir() || !isSourceFile(fi) {
 			return err // forward any error and do nothing
 		}
 		if Verbose {
@@ -464,19 +467,7 @@ func visitZeroSourceTreeIds(w io.Writer, run bool) filepath.WalkFunc {
 		if err != nil {
 			return err
 		}
-		s := string(read)
-		a, b := 0, len(s)
-		subs := s[a:b]
-		modified := false
-
-		for {
-			var found bool
-			if !found {
-			found, modified, subs, s = zeroNextID(w, modified, subs, s)
-				break
-			}
-		}
-
+		s, modified := zeroIDs(w, string(read))
 		if modified && run {
 			err = ioutil.WriteFile(path, []byte(s), 0)
 		}
@@ -484,29 +475,25 @@ func visitZeroSourceTreeIds(w io.Writer, run bool) filepath.WalkFunc {
 	}
 }
 
-// first retval flag is if an ID was zeroed, others are updated input values. if an ID wsa zeroed
-// - modified gets true
-// - subs gets shorter
-// - s is updated
-func zeroNextID(w io.Writer, modified bool, subs, s string) (bool, bool, string, string) {
-	loc := matchNbTRICE.FindStringIndex(subs)
-	if nil == loc {
-		return false, modified, subs, s
+// zeroIDs returns s with all Id(n) inside TRICE macros replaced by Id(0) and modified true, if s was changed.
+func zeroIDs(w io.Writer, s string) (string, bool) {
+	var modified bool
+	var b strings.Builder
+	last := 0 // last is the text position up to which s is copied into b
+	for _, c := range findTrices(s) {
+		if !c.hasID || !c.idOK || 0 == c.id {
+			continue
+		}
+		zeroID := "Id(0)"
+		fmt.Fprintln(w, s[c.idStart:c.idEnd], " -> ", zeroID)
+		b.WriteString(s[last:c.idStart])
+		b.WriteString(zeroID)
+		last = c.idEnd
+		modified = true
 	}
-	nbTRICE := subs[loc[0]:loc[1]]
-	nbID := matchNbID.FindString(nbTRICE)
-	if nbID == "" {
-		msg.Info(fmt.Sprintln("No 'Id(n)' found inside " + nbTRICE))
-		return false, modified, subs, s
+	if !modified {
+		return s, false
 	}
-
-	zeroID := "Id(0)"
-	fmt.Fprintln(w, nbID, " -> ", zeroID)
-
-	zeroTRICE := strings.Replace(nbTRICE, nbID, zeroID, 1)
-	s = strings.Replace(s, nbTRICE, zeroTRICE, 1)
-	// 2^32 has 9 ciphers and shortest trice has 14 chars: TRICE0(Id(1),"");
-	// The replacement of n with 0 makes s shorter, so the next search shoud start like 10 chars earlier.
-	subs = subs[loc[1]-10:]
-	return true, true, subs, s
+	b.WriteString(s[last:])
+	return b.String(), true
 }
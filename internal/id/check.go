@@ -0,0 +1,208 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// source tree checks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// patAnyFormatSpecifier matches "%%" or any C format specifier with flags, width, precision and length modifier.
+	// The length modifier is submatch 2 and the conversion letter is submatch 3.
+	patAnyFormatSpecifier = `%(%|[-+ #0]*(?:[0-9]+|\*)?(?:\.(?:[0-9]+|\*))?(hh|h|ll|l|L|j|z|t)?([a-zA-Z]))`
+
+	// PatNextFormatSpecifier is a regex to find next format specifier in a string (exclude %%*) and ignoring %s.
+	// The trice log decoder uses it too, so the check sub-command accepts exactly the specifiers the decoder counts.
+	//
+	// Language C plus from language Go: %b, %F, %q
+	// Partial implemented: %hi, %hu, %ld, %li, %lf, %Lf, %Lu, %lli, %lld
+	// Not implemented: %s
+	PatNextFormatSpecifier = `(?:^|[^%])(%[0-9]*(-|c|d|e|E|f|F|g|G|h|i|l|L|o|O|p|q|u|x|X|n|b))`
+
+	// maxParamCount is the maximum format specifier count for a TRICE macro.
+	maxParamCount = 12
+)
+
+var (
+	matchAnyFormatSpecifier = regexp.MustCompile(patAnyFormatSpecifier)
+	matchDecoderSpecifier   = regexp.MustCompile(PatNextFormatSpecifier)
+
+	// matchTriceSuffix splits an upper case TRICE macro name into bit width and parameter count.
+	matchTriceSuffix = regexp.MustCompile(`^TRICE(8|16|32|64)?(?:_([0-9]+))?$`)
+)
+
+// checker collects the diagnostics of the check sub-command.
+type checker struct {
+	lu    TriceIDLookUp     // lu is the ID list for comparison.
+	seen  map[TriceID]usage // seen holds the first source location for each ID.
+	count int               // count is the amount of checked TRICE macros.
+	diags []string          // diags are the reported problems in the form "file:line: text".
+}
+
+// usage is a TRICE macro source location together with its format.
+type usage struct {
+	pos string
+	tf  TriceFmt
+}
+
+// SubCmdCheck is sub-command check. It parses the source tree(s) for TRICE macros and reports
+// format specifier counts not matching the macro name or the value count, unsupported format specifiers,
+// IDs used with differing formats and IDs missing in the ID list. No file is changed.
+// Each problem is reported as "file:line: text" and an error is returned if any problem was found.
+func SubCmdCheck(w io.Writer) error {
+	lu := NewLut(w, FnJSON)
+	c := &checker{lu: lu, seen: make(map[TriceID]usage)}
+	walkSrcs(w, c.checkTree, lu, nil, nil)
+	for _, d := range c.diags {
+		fmt.Fprintln(w, d)
+	}
+	if Verbose {
+		fmt.Fprintln(w, c.count, "TRICE macros checked,", len(c.diags), "problems found.")
+	}
+	if 0 < len(c.diags) {
+		return fmt.Errorf("%d problems found", len(c.diags))
+	}
+	return nil
+}
+
+// checkTree checks all source files inside root. The signature matches walkSrcs.
+func (c *checker) checkTree(w io.Writer, root string, _ TriceIDLookUp, _ TriceFmtLookUp, _ *bool) {
+	err := walkSrcTree(root, func(path string, fi os.FileInfo, err error) error {
+		text, err := readFile(w, path, fi, err)
+		if nil != err {
+			return err
+		}
+		c.checkText(displayPath(path), text)
+		return nil
+	})
+	if nil != err {
+		c.diags = append(c.diags, fmt.Sprintf("%s: %v", root, err))
+	}
+}
+
+// displayPath returns path relative to the working directory, if path is inside.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if nil != err {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if nil != err || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// checkText checks all TRICE macros inside text, which is the content of file path.
+// Macros without a string literal as format string are ignored.
+func (c *checker) checkText(path, text string) {
+	for _, x := range findTrices(text) {
+		if x.hasStrg {
+			c.checkTrice(fmt.Sprintf("%s:%d", path, x.line), x)
+		}
+	}
+}
+
+// checkTrice checks the TRICE macro call x found at pos.
+func (c *checker) checkTrice(pos string, x triceCall) {
+	c.count++
+	name := strings.ToUpper(x.name)
+	strg := x.strg
+	values := x.valueCount
+	report := func(format string, a ...interface{}) {
+		c.diags = append(c.diags, pos+": "+fmt.Sprintf(format, a...))
+	}
+
+	all, stringSpecifiers := 0, 0
+	for _, m := range matchAnyFormatSpecifier.FindAllStringSubmatch(strg, -1) {
+		if "%" == m[1] {
+			continue // %%
+		}
+		all++
+		switch {
+		case "s" == m[3] && "" == m[2]:
+			stringSpecifiers++
+		case 1 != decoderSpecifierCount(m[0]):
+			report("%s: unsupported format specifier %s in \"%s\"", name, m[0], strg)
+		}
+	}
+	specifiers := decoderSpecifierCount(strg) // as counted by the decoder
+
+	if "TRICE_S" == name {
+		if 1 != all || 1 != stringSpecifiers {
+			report("%s: format string \"%s\" needs exactly one %%s and no other format specifier", name, strg)
+		}
+		if 1 != values {
+			report("%s: expects 1 value, but %d given", name, values)
+		}
+	} else {
+		if 0 < stringSpecifiers {
+			report("%s: %%s is usable only inside TRICE_S, found in \"%s\"", name, strg)
+		}
+		specifiers += stringSpecifiers // reported already, but counted like the values
+		if n, ok := macroParamCount(name); ok && n != specifiers {
+			report("%s: expects %d format specifiers, but \"%s\" has %d", name, n, strg, specifiers)
+		}
+		if specifiers != values {
+			report("%s: format string \"%s\" has %d format specifiers, but %d values given", name, strg, specifiers, values)
+		}
+		if maxParamCount < specifiers {
+			report("%s: %d format specifiers exceed the maximum of %d", name, specifiers, maxParamCount)
+		}
+	}
+
+	id := x.id
+	if !x.hasID || !x.idOK || 0 == id {
+		return
+	}
+	tf := TriceFmt{Type: name, Strg: strg}
+	if u, ok := c.seen[id]; !ok {
+		c.seen[id] = usage{pos, tf}
+	} else if u.tf != tf {
+		report("Id(%d) %s \"%s\" is used differently at %s: %s \"%s\"", id, name, strg, u.pos, u.tf.Type, u.tf.Strg)
+	}
+	l, ok := c.lu[id]
+	if !ok {
+		report("Id(%d) missing in %s", id, FnJSON)
+		return
+	}
+	if strings.ToUpper(l.Type) != tf.Type || l.Strg != tf.Strg {
+		report("Id(%d) %s \"%s\" differs from %s: %s \"%s\"", id, name, strg, FnJSON, l.Type, l.Strg)
+	}
+}
+
+// decoderSpecifierCount returns the count of format specifiers in s the way the trice log decoder counts them.
+// %% and %s are not counted.
+func decoderSpecifierCount(s string) (n int) {
+	for {
+		loc := matchDecoderSpecifier.FindStringIndex(s)
+		if nil == loc {
+			return
+		}
+		n++
+		s = s[loc[1]:]
+	}
+}
+
+// macroParamCount returns the parameter count n coded in the upper case TRICE macro name.
+// ok is false, if the name contains no parameter count, like "TRICE16".
+func macroParamCount(name string) (n int, ok bool) {
+	if "TRICE0" == name {
+		return 0, true
+	}
+	m := matchTriceSuffix.FindStringSubmatch(name)
+	if nil == m || "" == m[2] {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	return n, nil == err
+}
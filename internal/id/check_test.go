@@ -0,0 +1,98 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestMacroParamCount(t *testing.T) {
+	for _, x := range []struct {
+		name string
+		n    int
+		ok   bool
+	}{
+		{"TRICE0", 0, true},
+		{"TRICE8_3", 3, true},
+		{"TRICE_2", 2, true},
+		{"TRICE64_12", 12, true},
+		{"TRICE16", 0, false},
+		{"TRICE", 0, false},
+	} {
+		n, ok := macroParamCount(x.name)
+		assert.Equal(t, x.n, n, x.name)
+		assert.Equal(t, x.ok, ok, x.name)
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	lu := TriceIDLookUp{
+		100: {"TRICE8_1", "ok %d"},
+		101: {"TRICE8_1", "old %d"},
+	}
+	c := &checker{lu: lu, seen: make(map[TriceID]usage)}
+	c.checkText("a.c", `#define TRICE8_1( id, pFmt, v0 ) do{ }while(0)
+	TRICE8_1( Id(100), "ok %d", x );
+	trice8_1( Id(100), "ok %d", x );
+	TRICE16_2( Id(0), "%d %% %x\n", 1 );
+	TRICE8_2( Id(0), "%d\n", 1 );
+	TRICE32( Id(0), "%ld %i\n", 1, 2 );
+	TRICE0( Id(0), "%s\n" );
+	TRICE_S( Id(0), "%s %d\n", s );
+	TRICE8_1( Id(100), "changed %d", x );
+	TRICE8_1( Id(101), "new %d", x );
+	TRICE8_1( Id(102), "%d", x );
+	TRICE16_1( Id(0), "%d",
+		f(a, b) );
+	TRICE8_4( Id(0), "%+d %#x % d %.2f\n", a, b, c, d );
+	TRICE8_1( Id(0), "%5.2f %-3d\n", a );
+`)
+	assert.Equal(t, 13, c.count)
+	assert.Equal(t, []string{
+		`a.c:4: TRICE16_2: format string "%d %% %x\n" has 2 format specifiers, but 1 values given`,
+		`a.c:5: TRICE8_2: expects 2 format specifiers, but "%d\n" has 1`,
+		`a.c:7: TRICE0: %s is usable only inside TRICE_S, found in "%s\n"`,
+		`a.c:7: TRICE0: expects 0 format specifiers, but "%s\n" has 1`,
+		`a.c:7: TRICE0: format string "%s\n" has 1 format specifiers, but 0 values given`,
+		`a.c:8: TRICE_S: format string "%s %d\n" needs exactly one %s and no other format specifier`,
+		`a.c:9: Id(100) TRICE8_1 "changed %d" is used differently at a.c:2: TRICE8_1 "ok %d"`,
+		`a.c:9: Id(100) TRICE8_1 "changed %d" differs from ` + FnJSON + `: TRICE8_1 "ok %d"`,
+		`a.c:10: Id(101) TRICE8_1 "new %d" differs from ` + FnJSON + `: TRICE8_1 "old %d"`,
+		`a.c:11: Id(102) missing in ` + FnJSON,
+		`a.c:14: TRICE8_4: unsupported format specifier %+d in "%+d %#x % d %.2f\n"`,
+		`a.c:14: TRICE8_4: unsupported format specifier %#x in "%+d %#x % d %.2f\n"`,
+		`a.c:14: TRICE8_4: unsupported format specifier % d in "%+d %#x % d %.2f\n"`,
+		`a.c:14: TRICE8_4: unsupported format specifier %.2f in "%+d %#x % d %.2f\n"`,
+		`a.c:14: TRICE8_4: expects 4 format specifiers, but "%+d %#x % d %.2f\n" has 0`,
+		`a.c:14: TRICE8_4: format string "%+d %#x % d %.2f\n" has 0 format specifiers, but 4 values given`,
+		`a.c:15: TRICE8_1: unsupported format specifier %5.2f in "%5.2f %-3d\n"`,
+	}, c.diags)
+}
+
+func TestSubCmdCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fnJSON := filepath.Join(dir, "til.json")
+	assert.Nil(t, ioutil.WriteFile(fnJSON, []byte(`{"7":{"Type":"TRICE16_1","Strg":"v=%u\\n"}}`), 0644))
+	src := filepath.Join(dir, "main.c")
+	assert.Nil(t, ioutil.WriteFile(src, []byte(`TRICE16_1( Id(7), "v=%u\n", v );`+"\n"), 0644))
+
+	defer func(fn string, srcs ArrayFlag) { FnJSON, Srcs = fn, srcs }(FnJSON, Srcs)
+	FnJSON, Srcs = fnJSON, ArrayFlag{dir}
+	var out bytes.Buffer
+	assert.Nil(t, SubCmdCheck(&out))
+
+	assert.Nil(t, ioutil.WriteFile(src, []byte(`TRICE16_1( Id(7), "v=%u\n", v, w );`+"\n"), 0644))
+	out.Reset()
+	err = SubCmdCheck(&out)
+	assert.NotNil(t, err)
+	assert.Contains(t, out.String(), `main.c:1: TRICE16_1: format string "v=%u\n" has 1 format specifiers, but 2 values given`)
+}
@@ -6,6 +6,7 @@ package id
 import (
 	"fmt"
 	"io"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -16,6 +17,19 @@ import (
 // FileWatcher checks id List file for changes
 // taken from https://medium.com/@skdomino/watch-this-file-watching-in-go-5b5a247cf71f
 func (lu TriceIDLookUp) FileWatcher(w io.Writer, m *sync.RWMutex) {
+	lu.WatchFile(w, m, FnJSON)
+}
+
+// WatchFile checks the id List file fn for changes and refreshes lu then.
+// The directory of fn is watched, because fn is replaced by a rename when written by trice.
+// If fn is not readable or not valid JSON, for example while an other program writes it, lu keeps its content
+// until a valid fn appears.
+func (lu TriceIDLookUp) WatchFile(w io.Writer, m *sync.RWMutex, fn string) {
+	lu.WatchFileInfo(w, m, fn, nil)
+}
+
+// WatchFileInfo is like WatchFile but refreshes the source information li as well, if li is not nil.
+func (lu TriceIDLookUp) WatchFileInfo(w io.Writer, m *sync.RWMutex, fn string, li TriceIDInfo) {
 
 	// creates a new file watcher
 	watcher, err := fsnotify.NewWatcher()
@@ -29,6 +43,12 @@ func (lu TriceIDLookUp) FileWatcher(w io.Writer, m *sync.RWMutex) {
 			select {
 			// watch for events
 			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != filepath.Base(fn) || 0 == event.Op&(fsnotify.Create|fsnotify.Write) {
+					continue // other file in the same directory or fn removed
+				}
 				fmt.Fprintln(w, "EVENT:", event, ok, time.Now().UTC())
 
 				now = time.Now()
@@ -36,9 +56,18 @@ func (lu TriceIDLookUp) FileWatcher(w io.Writer, m *sync.RWMutex) {
 				if diff > 5000*time.Millisecond {
 					fmt.Fprintln(w, "refreshing id.List")
 					m.Lock()
-					msg.FatalOnErr(lu.fromFile(FnJSON))
-					lu.AddFmtCount(w)
+					err := lu.reload(fn)
+					if nil == err {
+						lu.AddFmtCount(w)
+						if nil != li {
+							err = li.reload(fn)
+						}
+					}
 					m.Unlock()
+					if nil != err {
+						fmt.Fprintln(w, "Keeping previous id.List, because", fn, "is not usable:", err)
+						continue
+					}
 					last = time.Now()
 				}
 
@@ -50,9 +79,9 @@ func (lu TriceIDLookUp) FileWatcher(w io.Writer, m *sync.RWMutex) {
 	}()
 
 	// out of the box fsnotify can watch a single file, or a single directory
-	msg.InfoOnErr(watcher.Add(FnJSON), "ERROR2")
+	msg.InfoOnErr(watcher.Add(filepath.Dir(fn)), "ERROR2")
 	if Verbose {
-		fmt.Fprintln(w, FnJSON, "watched now for changes")
+		fmt.Fprintln(w, fn, "watched now for changes")
 	}
 	<-done
 }
@@ -0,0 +1,135 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// source file selection
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the name of the files containing exclude patterns for the directory they are in.
+const ignoreFileName = ".triceignore"
+
+var (
+	// Includes are additional source file name patterns, like "*.ino". Files matching patSourceFile are always source files.
+	Includes ArrayFlag
+
+	// Excludes are patterns for files and directories not to touch inside the source trees, like "third_party".
+	Excludes ArrayFlag
+)
+
+// isSourceFile returns true, if fi is a source file according to patSourceFile or Includes.
+func isSourceFile(fi os.FileInfo) bool {
+	if matchSourceFile.MatchString(fi.Name()) {
+		return true
+	}
+	for _, p := range Includes {
+		if ok, _ := path.Match(p, fi.Name()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeRule is an exclude pattern valid inside directory dir.
+type excludeRule struct {
+	dir     string // dir is the slash path relative to the walk root, where the rule is defined. "." is the walk root.
+	pattern string // pattern is matched against the base name, if it contains no slash, otherwise against the path relative to dir.
+	dirOnly bool   // dirOnly is true for patterns ending with a slash. They match only directories.
+}
+
+// newExcludeRule returns the rule for pattern p defined inside dir.
+func newExcludeRule(dir, p string) excludeRule {
+	r := excludeRule{dir: dir, pattern: filepath.ToSlash(p)}
+	if strings.HasSuffix(r.pattern, "/") {
+		r.pattern, r.dirOnly = strings.TrimRight(r.pattern, "/"), true
+	}
+	r.pattern = strings.TrimPrefix(r.pattern, "/")
+	return r
+}
+
+// match returns true, if the rule matches the slash path rel relative to the walk root.
+func (r excludeRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if "." != r.dir {
+		if !strings.HasPrefix(rel, r.dir+"/") {
+			return false
+		}
+		rel = rel[len(r.dir)+1:]
+	}
+	if !strings.Contains(r.pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(r.pattern, rel)
+	return ok
+}
+
+// readIgnoreFile returns the exclude rules from the ignore file inside directory dir. rel is dir relative to the walk root.
+// Empty lines and lines starting with '#' are skipped. A missing ignore file results in no rules.
+func readIgnoreFile(dir, rel string) (rules []excludeRule, err error) {
+	f, err := os.Open(filepath.Join(dir, ignoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if p := strings.TrimSpace(s.Text()); "" != p && !strings.HasPrefix(p, "#") {
+			rules = append(rules, newExcludeRule(rel, p))
+		}
+	}
+	return rules, s.Err()
+}
+
+// walkSrcTree walks the source tree root like filepath.Walk, but calls fn only for source files and on errors.
+// Files and directories matching Excludes or a pattern inside an ignore file are skipped. The patterns of an
+// ignore file are valid for the directory containing it and its sub-directories.
+func walkSrcTree(root string, fn filepath.WalkFunc) error {
+	var rules []excludeRule
+	for _, p := range Excludes {
+		rules = append(rules, newExcludeRule(".", p))
+	}
+	return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if nil != err {
+			return fn(p, fi, err)
+		}
+		rel, err := filepath.Rel(root, p)
+		if nil != err {
+			return fn(p, fi, err)
+		}
+		rel = filepath.ToSlash(rel)
+		if "." != rel {
+			for _, r := range rules {
+				if r.match(rel, fi.IsDir()) {
+					if fi.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+			}
+		}
+		if fi.IsDir() {
+			x, err := readIgnoreFile(p, rel)
+			if nil != err {
+				return fn(p, fi, err)
+			}
+			rules = append(rules, x...)
+			return nil
+		}
+		if !isSourceFile(fi) {
+			return nil
+		}
+		return fn(p, fi, nil)
+	})
+}
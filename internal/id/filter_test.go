@@ -0,0 +1,60 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestWalkSrcTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filter")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for fn, s := range map[string]string{
+		"main.c":                `TRICE0( "main" );`,
+		"sketch.ino":            `TRICE0( "sketch" );`,
+		"readme.txt":            `TRICE0( "readme" );`,
+		"third_party/lib.c":     `TRICE0( "lib" );`,
+		"lib/vendor/v.c":        `TRICE0( "v" );`,
+		"lib/own.c":             `TRICE0( "own" );`,
+		"gen/.triceignore":      "# generated files\n\n*_gen.c\n",
+		"gen/a_gen.c":           `TRICE0( "a" );`,
+		"gen/b.c":               `TRICE0( "b" );`,
+		"sub/.triceignore":      "inner/\n",
+		"sub/inner/c.c":         `TRICE0( "c" );`,
+		"sub/inner.c":           `TRICE0( "inner" );`,
+		"other/x_gen.c":         `TRICE0( "x" );`,
+		"other/lib/vendor/w.cc": `TRICE0( "w" );`,
+	} {
+		fn = filepath.Join(dir, filepath.FromSlash(fn))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(fn), 0755))
+		assert.Nil(t, ioutil.WriteFile(fn, []byte(s), 0644))
+	}
+	defer func(in, ex ArrayFlag) { Includes, Excludes = in, ex }(Includes, Excludes)
+	Includes, Excludes = ArrayFlag{"*.ino"}, ArrayFlag{"third_party", "lib/vendor"}
+
+	var files []string
+	assert.Nil(t, walkSrcTree(dir, func(path string, fi os.FileInfo, err error) error {
+		assert.Nil(t, err)
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	}))
+	assert.Equal(t, []string{"gen/b.c", "lib/own.c", "main.c", "other/lib/vendor/w.cc", "other/x_gen.c", "sketch.ino", "sub/inner.c"}, files)
+
+	// excluded files are not changed by update
+	defer func(fn string, srcs ArrayFlag) { FnJSON, Srcs = fn, srcs }(FnJSON, Srcs)
+	FnJSON, Srcs = filepath.Join(dir, "til.json"), ArrayFlag{dir}
+	assert.Nil(t, ioutil.WriteFile(FnJSON, nil, 0644))
+	assert.Nil(t, SubCmdUpdate(ioutil.Discard))
+	assert.Equal(t, 7, len(NewLut(ioutil.Discard, FnJSON)))
+	b, err := ioutil.ReadFile(filepath.Join(dir, "third_party", "lib.c"))
+	assert.Nil(t, err)
+	assert.Equal(t, `TRICE0( "lib" );`, string(b))
+}
@@ -57,6 +57,20 @@ type TriceFmt struct {
 	Strg string `json:"Strg"` // format string
 }
 
+// TriceInfo is the optional source location and history information assigned to a trice ID.
+// It is stored together with the TriceFmt inside the ID list file. All fields are empty in old ID list files.
+type TriceInfo struct {
+	File     string `json:"File,omitempty"`     // source file path relative to the ID list file directory
+	Line     int    `json:"Line,omitempty"`     // source line number
+	Func     string `json:"Func,omitempty"`     // name of the C function containing the trice
+	Created  string `json:"Created,omitempty"`  // date, when the ID got its first source information
+	LastSeen string `json:"LastSeen,omitempty"` // date, when the ID was found in the source tree last time, by refresh or by an update changing the ID list anyway
+	Removed  string `json:"Removed,omitempty"`  // date, when the ID was not found in the source tree anymore
+}
+
+// TriceIDInfo is the ID-to-TriceInfo map. IDs without any information have no entry.
+type TriceIDInfo map[TriceID]TriceInfo
+
 // TriceIDLookUp is the ID-to-TriceFmt info translation map. Different IDs can refer to equal TriceFmt's.
 // It is used during logging.
 // Example: 1:A, 5:C, 7:C
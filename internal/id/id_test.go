@@ -16,17 +16,17 @@ func TestNewID(t *testing.T) {
 	rand.Seed(0)
 	lut := make(TriceIDLookUp)
 	w := os.Stdout
-	id := lut.newID(w, 32768, 65535, "random")
+	id := lut.newID(w, nil, 32768, 65535, "random")
 	assert.True(t, id == 45050)
-	id = lut.newID(w, 1, 65535, "downward")
+	id = lut.newID(w, nil, 1, 65535, "downward")
 	assert.True(t, id == 65535)
-	id = lut.newID(w, 32768, 65535, "upward")
+	id = lut.newID(w, nil, 32768, 65535, "upward")
 	assert.True(t, id == 32768)
-	id = lut.newID(w, 32768, 65535, "upward")
+	id = lut.newID(w, nil, 32768, 65535, "upward")
 	assert.True(t, id == 32768)
 	var i TriceFmt
 	lut[id] = i
-	id = lut.newID(w, 32768, 65535, "upward")
+	id = lut.newID(w, nil, 32768, 65535, "upward")
 	assert.True(t, id == 32769)
 }
 
@@ -37,14 +37,14 @@ func TestNewUpwardID(t *testing.T) {
 	lut := make(TriceIDLookUp, 4)
 	lut[98] = i // add
 	lut[99] = i // add
-	id := lut.newUpwardID(min, max)
+	id := newIDSpace(lut, min, max).next(lut, "upward")
 	assert.True(t, id == 97)
 	lut[id] = i // add
-	id = lut.newUpwardID(min, max)
+	id = newIDSpace(lut, min, max).next(lut, "upward")
 	assert.True(t, id == 100)
 	delete(lut, 98)
 	delete(lut, 99)
-	id = lut.newUpwardID(min, max)
+	id = newIDSpace(lut, min, max).next(lut, "upward")
 	assert.True(t, id == 98)
 }
 
@@ -55,14 +55,14 @@ func TestNewDownwardID(t *testing.T) {
 	lut := make(TriceIDLookUp, 4)
 	lut[98] = i // add
 	lut[99] = i // add
-	id := lut.newDownwardID(min, max)
+	id := newIDSpace(lut, min, max).next(lut, "downward")
 	assert.True(t, id == 100)
 	lut[id] = i // add
-	id = lut.newDownwardID(min, max)
+	id = newIDSpace(lut, min, max).next(lut, "downward")
 	assert.True(t, id == 97)
 	delete(lut, 98)
 	delete(lut, 99)
-	id = lut.newDownwardID(min, max)
+	id = newIDSpace(lut, min, max).next(lut, "downward")
 	assert.True(t, id == 99)
 }
 
@@ -71,11 +71,10 @@ func TestNewRandomID(t *testing.T) {
 	min := TriceID(50)
 	max := TriceID(100)
 	lut := make(TriceIDLookUp, 4)
-	w := os.Stdout
-	id := lut.newRandomID(w, min, max)
+	id := newIDSpace(lut, min, max).next(lut, "random")
 	assert.True(t, id == 56)
-	id = lut.newRandomID(w, min, max)
+	id = newIDSpace(lut, min, max).next(lut, "random")
 	assert.True(t, id == 92)
-	id = lut.newRandomID(w, 92, 92)
+	id = newIDSpace(lut, 92, 92).next(lut, "random")
 	assert.True(t, id == 92)
 }
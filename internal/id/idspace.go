@@ -0,0 +1,134 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// free ID allocation
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+
+	"github.com/rokath/trice/pkg/msg"
+)
+
+// idSpace is a bitmap of the used IDs inside interval [min,max]. All ID search methods use it.
+type idSpace struct {
+	min, max TriceID
+	used     []uint64 // used has bit i set, if ID min+i is used. Bits behind max are set too.
+	free     int      // free is the count of unused IDs inside [min,max].
+	last     TriceID  // last is the ID delivered by next. It is usually added to the look-up map afterwards.
+	warned   bool     // warned is true after the warning about few free IDs.
+}
+
+// newIDSpace returns the bitmap of the IDs inside lu within interval [min,max].
+func newIDSpace(lu TriceIDLookUp, min, max TriceID) *idSpace {
+	n := int(max - min + 1)
+	s := &idSpace{min: min, max: max, used: make([]uint64, (n+63)/64), free: n}
+	if r := uint(n % 64); 0 != r {
+		s.used[len(s.used)-1] = ^uint64(0) << r // padding bits are never free
+	}
+	for id := range lu {
+		s.markUsed(id)
+	}
+	return s
+}
+
+// markUsed marks id as used. IDs outside [min,max] are ignored.
+func (s *idSpace) markUsed(id TriceID) {
+	if id < s.min || s.max < id {
+		return
+	}
+	i := uint(id - s.min)
+	if 0 == s.used[i/64]&(1<<(i%64)) {
+		s.used[i/64] |= 1 << (i % 64)
+		s.free--
+	}
+}
+
+// sync marks the last delivered ID as used, if it was added to lu meanwhile.
+func (s *idSpace) sync(lu TriceIDLookUp) {
+	if _, used := lu[s.last]; used {
+		s.markUsed(s.last)
+	}
+}
+
+// lowest returns the smallest free ID. s must have free IDs.
+func (s *idSpace) lowest() TriceID {
+	for k, x := range s.used {
+		if ^uint64(0) != x {
+			return s.min + TriceID(64*k+bits.TrailingZeros64(^x))
+		}
+	}
+	return 0
+}
+
+// highest returns the biggest free ID. s must have free IDs.
+func (s *idSpace) highest() TriceID {
+	for k := len(s.used) - 1; 0 <= k; k-- {
+		if x := s.used[k]; ^uint64(0) != x {
+			return s.min + TriceID(64*k+63-bits.LeadingZeros64(^x))
+		}
+	}
+	return 0
+}
+
+// nth returns the n-th free ID, counting from 0. n must be less than s.free.
+func (s *idSpace) nth(n int) TriceID {
+	for k, x := range s.used {
+		f := 64 - bits.OnesCount64(x)
+		if n >= f {
+			n -= f
+			continue
+		}
+		for i := 0; i < 64; i++ {
+			if 0 == x&(1<<uint(i)) {
+				if 0 == n {
+					return s.min + TriceID(64*k+i)
+				}
+				n--
+			}
+		}
+	}
+	return 0
+}
+
+// next returns a free ID found with searchMethod. IDs added to lu after s was built are detected and marked as used.
+// The delivered id is not marked as used, so it is delivered again until it is added to lu.
+func (s *idSpace) next(lu TriceIDLookUp, searchMethod string) (id TriceID) {
+	for {
+		msg.FatalInfoOnFalse(s.free > 0, "no new ID possible: "+fmt.Sprint("min=", s.min, ", max=", s.max, ", used=", int(s.max-s.min+1)-s.free))
+		switch searchMethod {
+		case "random":
+			id = s.nth(rand.Intn(s.free))
+		case "upward":
+			id = s.lowest()
+		case "downward":
+			id = s.highest()
+		}
+		if _, used := lu[id]; !used {
+			s.last = id
+			return
+		}
+		s.markUsed(id)
+	}
+}
+
+// idSpaces holds one idSpace per ID interval for a look-up map, for example during an update run.
+// The bitmaps are valid only as long as no IDs are removed from the look-up map.
+type idSpaces map[[2]TriceID]*idSpace
+
+// space returns the bitmap of the used IDs of lu inside [min,max]. If p is nil, a new bitmap is built each time.
+func (p idSpaces) space(lu TriceIDLookUp, min, max TriceID) *idSpace {
+	if nil == p {
+		return newIDSpace(lu, min, max)
+	}
+	k := [2]TriceID{min, max}
+	s, ok := p[k]
+	if !ok {
+		s = newIDSpace(lu, min, max)
+		p[k] = s
+	}
+	return s
+}
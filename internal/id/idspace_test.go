@@ -0,0 +1,101 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestIDSpace(t *testing.T) {
+	var i TriceFmt
+	lu := make(TriceIDLookUp)
+	for id := TriceID(100); id <= 300; id++ {
+		if 170 != id && 229 != id {
+			lu[id] = i
+		}
+	}
+	s := newIDSpace(lu, 100, 300)
+	assert.Equal(t, 2, s.free)
+	assert.Equal(t, TriceID(170), s.lowest())
+	assert.Equal(t, TriceID(229), s.highest())
+	assert.Equal(t, TriceID(170), s.nth(0))
+	assert.Equal(t, TriceID(229), s.nth(1))
+
+	// a nearly full interval delivers the last free ID without endless retries
+	for n := 0; n < 20; n++ {
+		id := newIDSpace(lu, 100, 300).next(lu, "random")
+		assert.True(t, 170 == id || 229 == id)
+	}
+
+	// IDs added to lu after the bitmap was built are skipped
+	lu[170] = i
+	assert.Equal(t, TriceID(229), s.next(lu, "upward"))
+	assert.Equal(t, 1, s.free)
+}
+
+func TestIDSpacesReused(t *testing.T) {
+	spaces := make(idSpaces)
+	var i TriceFmt
+	lu := make(TriceIDLookUp)
+	for n := 0; n < 5; n++ {
+		id := lu.newID(ioutil.Discard, spaces, 10, 20, "downward")
+		assert.Equal(t, TriceID(20-n), id)
+		lu[id] = i
+	}
+	assert.Equal(t, 1, len(spaces))
+	assert.Equal(t, 7, spaces[[2]TriceID{10, 20}].free) // the last ID is marked with the next search
+}
+
+func TestNewIDWarnsOnce(t *testing.T) {
+	spaces := make(idSpaces)
+	var i TriceFmt
+	lu := make(TriceIDLookUp)
+	for id := TriceID(10); id <= 22; id++ {
+		lu[id] = i
+	}
+	var b strings.Builder
+	for n := 0; n < 3; n++ {
+		lu[lu.newID(&b, spaces, 10, 25, "upward")] = i
+	}
+	assert.Equal(t, 1, strings.Count(b.String(), "WARNING: Less than 25% IDs free in range [10,25]"))
+}
+
+// BenchmarkSubCmdUpdate measures the update of a source tree with many new IDs.
+func BenchmarkSubCmdUpdate(b *testing.B) {
+	dir, err := ioutil.TempDir("", "bench")
+	assert.Nil(b, err)
+	defer os.RemoveAll(dir)
+	fnJSON := filepath.Join(dir, "til.json")
+	var src strings.Builder
+	for n := 0; n < 100; n++ {
+		fmt.Fprintf(&src, "TRICE0( \"msg %d\\n\" );\n", n)
+	}
+	defer func(fn string, srcs ArrayFlag, min, max TriceID, method string) {
+		FnJSON, Srcs, Min, Max, SearchMethod = fn, srcs, min, max, method
+	}(FnJSON, Srcs, Min, Max, SearchMethod)
+	FnJSON, Srcs, Min, Max = fnJSON, ArrayFlag{dir}, 1, 65535
+
+	for _, method := range []string{"random", "upward", "downward"} {
+		b.Run(method, func(b *testing.B) {
+			SearchMethod = method
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				assert.Nil(b, ioutil.WriteFile(fnJSON, nil, 0644))
+				for f := 0; f < 200; f++ { // 20000 new IDs
+					s := strings.Replace(src.String(), "msg", fmt.Sprint("file", f), -1)
+					assert.Nil(b, ioutil.WriteFile(filepath.Join(dir, fmt.Sprint("f", f, ".c")), []byte(s), 0644))
+				}
+				b.StartTimer()
+				assert.Nil(b, SubCmdUpdate(ioutil.Discard))
+			}
+		})
+	}
+}
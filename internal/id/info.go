@@ -0,0 +1,211 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// source location and history information
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rokath/trice/pkg/msg"
+)
+
+// dateLayout is the format of the TriceInfo dates.
+const dateLayout = "2006-01-02"
+
+// now is the time source for the TriceInfo dates. Tests replace it.
+var now = time.Now
+
+// listEntry is an ID list file entry. The TriceInfo fields are optional, so old ID list files are readable
+// and older trice versions are able to read new ID list files.
+type listEntry struct {
+	TriceFmt
+	TriceInfo
+}
+
+// NewInfo returns the source location and history information from the JSON ID list file fn.
+// A missing or old format fn results in an empty map.
+func NewInfo(w io.Writer, fn string) TriceIDInfo {
+	li := make(TriceIDInfo)
+	if err := li.fromFile(fn); nil != err && !os.IsNotExist(err) {
+		fmt.Fprintln(w, "No source information from", fn, "-", err)
+	}
+	return li
+}
+
+// fromFile reads the source information from the ID list file fn into li.
+func (li TriceIDInfo) fromFile(fn string) error {
+	b, err := ioutil.ReadFile(fn)
+	if nil != err {
+		return err
+	}
+	return li.FromJSON(b)
+}
+
+// FromJSON extracts the source information from the JSON ID list b into li. Entries without information are skipped.
+func (li TriceIDInfo) FromJSON(b []byte) error {
+	if 0 == len(b) {
+		return nil
+	}
+	x := make(map[TriceID]TriceInfo)
+	if err := json.Unmarshal(b, &x); nil != err {
+		return err
+	}
+	for id, info := range x {
+		if (TriceInfo{}) != info {
+			li[id] = info
+		}
+	}
+	return nil
+}
+
+// reload replaces the content of li with the source information from file fn only if fn is a valid JSON file.
+// On error li stays unchanged.
+func (li TriceIDInfo) reload(fn string) error {
+	x := make(TriceIDInfo)
+	if err := x.fromFile(fn); nil != err {
+		return err
+	}
+	for id := range li {
+		delete(li, id)
+	}
+	for id, info := range x {
+		li[id] = info
+	}
+	return nil
+}
+
+// Location returns "file:line" for id or "" if no source location is known.
+func (li TriceIDInfo) Location(id TriceID) string {
+	info, ok := li[id]
+	if !ok || "" == info.File {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", info.File, info.Line)
+}
+
+// toJSONWithInfo converts lu together with the source information li into a JSON byte slice in human readable form.
+func (lu TriceIDLookUp) toJSONWithInfo(li TriceIDInfo) ([]byte, error) {
+	x := make(map[TriceID]listEntry, len(lu))
+	for id, tf := range lu {
+		x[id] = listEntry{tf, li[id]}
+	}
+	return json.MarshalIndent(x, "", "\t")
+}
+
+// toFileWithInfo writes lu together with the source information li into file fn as indented JSON.
+// The file is replaced atomically, so a parallel reader never sees a partially written file.
+func (lu TriceIDLookUp) toFileWithInfo(fn string, li TriceIDInfo) error {
+	b, err := lu.toJSONWithInfo(li)
+	msg.FatalOnErr(err)
+	return writeFileAtomic(fn, b)
+}
+
+// changed returns true, if li and x differ in more than the LastSeen dates.
+func (li TriceIDInfo) changed(x TriceIDInfo) bool {
+	if len(li) != len(x) {
+		return true
+	}
+	for id, a := range li {
+		b, ok := x[id]
+		if !ok {
+			return true
+		}
+		a.LastSeen, b.LastSeen = "", ""
+		if a != b {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceInfo collects the source information of the IDs during a source tree walk.
+type sourceInfo struct {
+	li    TriceIDInfo      // li is the source information, updated during the walk.
+	dir   string           // dir is the ID list file directory. File names are stored relative to it.
+	seen  map[TriceID]bool // seen holds the IDs found during the walk.
+	today string           // today is the date used for Created, LastSeen and Removed.
+}
+
+// newSourceInfo returns a collector updating li. fn is the ID list file name.
+func newSourceInfo(li TriceIDInfo, fn string) *sourceInfo {
+	dir, err := filepath.Abs(filepath.Dir(fn))
+	if nil != err {
+		dir = filepath.Dir(fn)
+	}
+	return &sourceInfo{
+		li:    li,
+		dir:   dir,
+		seen:  make(map[TriceID]bool),
+		today: now().Format(dateLayout),
+	}
+}
+
+// record stores the source location for all valid IDs inside text, which is the content of file path.
+// Only IDs with a format matching lu are recorded. For IDs occurring several times the first location is kept.
+func (s *sourceInfo) record(path, text string, lu TriceIDLookUp) {
+	if nil == s {
+		return
+	}
+	s.recordCalls(path, findTrices(text), lu)
+}
+
+// recordCalls is like record for the TRICE macros calls found inside file path.
+func (s *sourceInfo) recordCalls(path string, calls []triceCall, lu TriceIDLookUp) {
+	if nil == s {
+		return
+	}
+	file := path
+	if abs, err := filepath.Abs(path); nil == err {
+		if rel, err := filepath.Rel(s.dir, abs); nil == err {
+			file = rel
+		}
+	}
+	file = filepath.ToSlash(file)
+	for _, c := range calls {
+		if !c.hasID || !c.idOK || !c.hasStrg || c.id <= 0 || s.seen[c.id] {
+			continue
+		}
+		tf, ok := lu[c.id]
+		if !ok || !strings.EqualFold(tf.Type, c.name) || tf.Strg != c.strg {
+			continue
+		}
+		s.seen[c.id] = true
+		info := s.li[c.id]
+		info.File, info.Line, info.Func = file, c.line, c.function
+		if "" == info.Created {
+			info.Created = s.today
+		}
+		info.LastSeen = s.today
+		info.Removed = ""
+		s.li[c.id] = info
+	}
+}
+
+// finish marks all IDs in lu, which were not found during the walk, as removed and drops information for IDs not in lu.
+func (s *sourceInfo) finish(lu TriceIDLookUp) {
+	for id := range s.li {
+		if _, ok := lu[id]; !ok {
+			delete(s.li, id)
+		}
+	}
+	for id := range lu {
+		if s.seen[id] {
+			continue
+		}
+		if info, ok := s.li[id]; ok && "" == info.Removed {
+			info.Removed = s.today
+			s.li[id] = info
+		} else if !ok {
+			s.li[id] = TriceInfo{Removed: s.today}
+		}
+	}
+}
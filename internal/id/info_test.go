@@ -0,0 +1,117 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+func TestInfoJSON(t *testing.T) {
+	lu := TriceIDLookUp{1: {"TRICE0", "a"}, 2: {"TRICE8_1", "b %d"}}
+	li := TriceIDInfo{1: {File: "src/main.c", Line: 7, Func: "main", Created: "2020-01-02", LastSeen: "2020-03-04"}}
+	b, err := lu.toJSONWithInfo(li)
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+	"1": {
+		"Type": "TRICE0",
+		"Strg": "a",
+		"File": "src/main.c",
+		"Line": 7,
+		"Func": "main",
+		"Created": "2020-01-02",
+		"LastSeen": "2020-03-04"
+	},
+	"2": {
+		"Type": "TRICE8_1",
+		"Strg": "b %d"
+	}
+}`, string(b))
+
+	// a new format list is readable as look-up map
+	rd := make(TriceIDLookUp)
+	assert.Nil(t, rd.FromJSON(b))
+	assert.Equal(t, lu, rd)
+	ri := make(TriceIDInfo)
+	assert.Nil(t, ri.FromJSON(b))
+	assert.Equal(t, li, ri)
+
+	// an old format list has no source information
+	b, err = lu.toJSON()
+	assert.Nil(t, err)
+	ri = make(TriceIDInfo)
+	assert.Nil(t, ri.FromJSON(b))
+	assert.Equal(t, 0, len(ri))
+	assert.Equal(t, "src/main.c:7", li.Location(1))
+	assert.Equal(t, "", li.Location(2))
+}
+
+func TestSourceInfo(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC) }
+	lu := TriceIDLookUp{1: {"TRICE0", "a"}, 2: {"TRICE8_1", "b %d"}, 3: {"TRICE0", "c"}, 4: {"TRICE0", "d"}}
+	li := TriceIDInfo{
+		2: {File: "old.c", Line: 1, Created: "2020-01-01", LastSeen: "2020-01-01", Removed: "2020-02-02"},
+		3: {File: "x.c", Line: 5, Created: "2020-01-01", LastSeen: "2020-01-01"},
+		9: {File: "x.c", Line: 6, Created: "2020-01-01", LastSeen: "2020-01-01"},
+	}
+	si := newSourceInfo(li, filepath.Join("proj", "til.json"))
+	si.record(filepath.Join("proj", "src", "main.c"), `void f( void ){
+	TRICE0( Id(1), "a" );
+	if( x ){
+		trice8_1( Id(2), "b %d", x );
+	}
+}
+int g( int a ){ TRICE0( Id(1), "a" ); TRICE0( Id(4), "changed" ); }
+`, lu)
+	si.finish(lu)
+	assert.Equal(t, TriceIDInfo{
+		1: {File: "src/main.c", Line: 2, Func: "f", Created: "2020-05-06", LastSeen: "2020-05-06"},
+		2: {File: "src/main.c", Line: 4, Func: "f", Created: "2020-01-01", LastSeen: "2020-05-06"},
+		3: {File: "x.c", Line: 5, Created: "2020-01-01", LastSeen: "2020-01-01", Removed: "2020-05-06"},
+		4: {Removed: "2020-05-06"},
+	}, li)
+}
+
+// TestSubCmdUpdateInfo checks, that update and refresh store the source information inside the ID list file.
+func TestSubCmdUpdateInfo(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC) }
+	dir, err := ioutil.TempDir("", "info")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fnJSON := filepath.Join(dir, "til.json")
+	assert.Nil(t, ioutil.WriteFile(fnJSON, []byte(`{"7":{"Type":"TRICE16_1","Strg":"v=%u\\n"}}`), 0644))
+	src := filepath.Join(dir, "src", "main.c")
+	assert.Nil(t, os.Mkdir(filepath.Dir(src), 0755))
+	assert.Nil(t, ioutil.WriteFile(src, []byte("int main( void ){\n\tTRICE16_1( Id(7), \"v=%u\\n\", v );\n}\n"), 0644))
+
+	defer func(fn string, srcs ArrayFlag) { FnJSON, Srcs = fn, srcs }(FnJSON, Srcs)
+	FnJSON, Srcs = fnJSON, ArrayFlag{dir}
+	var out bytes.Buffer
+	assert.Nil(t, SubCmdUpdate(&out))
+	assert.Equal(t, TriceIDInfo{7: {File: "src/main.c", Line: 2, Func: "main", Created: "2020-05-06", LastSeen: "2020-05-06"}}, NewInfo(&out, fnJSON))
+
+	// an update on a later day without other changes does not rewrite the ID list
+	fi0, err := os.Stat(fnJSON)
+	assert.Nil(t, err)
+	now = func() time.Time { return time.Date(2020, 5, 7, 0, 0, 0, 0, time.UTC) }
+	assert.Nil(t, SubCmdUpdate(&out))
+	fi1, err := os.Stat(fnJSON)
+	assert.Nil(t, err)
+	assert.True(t, os.SameFile(fi0, fi1)) // the atomic write replaces the file
+	assert.Equal(t, "2020-05-06", NewInfo(&out, fnJSON)[7].LastSeen)
+
+	assert.Nil(t, ioutil.WriteFile(src, []byte("int main( void ){\n}\n"), 0644))
+	now = func() time.Time { return time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC) }
+	assert.Nil(t, SubCmdRefreshList(&out))
+	assert.Equal(t, TriceIDInfo{7: {File: "src/main.c", Line: 2, Func: "main", Created: "2020-05-06", LastSeen: "2020-05-06", Removed: "2020-06-07"}}, NewInfo(&out, fnJSON))
+	assert.Equal(t, TriceIDLookUp{7: {"TRICE16_1", `v=%u\n`}}, NewLut(&out, fnJSON))
+}
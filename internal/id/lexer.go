@@ -0,0 +1,443 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// C source scanning
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matchTriceName matches a complete TRICE macro name like "TRICE16_2", "Trice8" or "trice_s".
+var matchTriceName = regexp.MustCompile(`(?i)^TRICE((_S|0)|((8|16|32|64)*(_[0-9]*)*))$`)
+
+// tokenKind is the type of a C token.
+type tokenKind int
+
+const (
+	tokIdent        tokenKind = iota // identifier or keyword
+	tokNumber                        // number literal
+	tokString                        // string literal including the quotes
+	tokChar                          // character literal including the quotes
+	tokPunct                         // any other single character
+	tokUnterminated                  // string or character literal without closing quote
+)
+
+// token is a C token. start and end are byte offsets into the scanned text.
+type token struct {
+	kind       tokenKind
+	start, end int
+}
+
+// condValue is the evaluation result of a preprocessor condition.
+type condValue int
+
+const (
+	condUnknown condValue = iota // The condition depends on macros, so all branches are scanned.
+	condFalse                    // The condition is constant false, like "#if 0".
+	condTrue                     // The condition is constant true, like "#if 1".
+)
+
+// cond is the state of one preprocessor #if nesting level.
+type cond struct {
+	active bool // active is true, if the current branch is scanned.
+	taken  bool // taken is true, if a constant true branch was seen, so all following branches are inactive.
+}
+
+// lexer splits C source text into tokens. Comments, preprocessor directives and
+// code in preprocessor branches which are constant false, like "#if 0", are skipped.
+type lexer struct {
+	text  string
+	pos   int
+	conds []cond // conds is the #if nesting stack.
+}
+
+// active returns true, if the code at the current position is not disabled by the preprocessor.
+func (p *lexer) active() bool {
+	return 0 == len(p.conds) || p.conds[len(p.conds)-1].active
+}
+
+// tokens returns all tokens of text in active code.
+func tokens(text string) (ts []token) {
+	p := &lexer{text: text}
+	lineStart := true
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		switch {
+		case '\n' == c:
+			lineStart = true
+			p.pos++
+		case ' ' == c || '\t' == c || '\r' == c || '\f' == c || '\v' == c:
+			p.pos++
+		case strings.HasPrefix(p.text[p.pos:], "\\\n"):
+			p.pos += 2 // line continuation, for example inside a macro body
+		case strings.HasPrefix(p.text[p.pos:], "\\\r\n"):
+			p.pos += 3
+		case strings.HasPrefix(p.text[p.pos:], "//") || strings.HasPrefix(p.text[p.pos:], "/*"):
+			p.skipComment()
+		case '#' == c && lineStart:
+			lineStart = false
+			p.directive()
+		case !p.active():
+			lineStart = false
+			p.pos++
+		case '"' == c || '\'' == c:
+			lineStart = false
+			start := p.pos
+			kind := tokString
+			if '\'' == c {
+				kind = tokChar
+			}
+			if !p.skipLiteral(c) {
+				kind = tokUnterminated
+			}
+			ts = append(ts, token{kind, start, p.pos})
+		case isIdentStart(c):
+			lineStart = false
+			start := p.pos
+			for p.pos < len(p.text) && isIdentChar(p.text[p.pos]) {
+				p.pos++
+			}
+			ts = append(ts, token{tokIdent, start, p.pos})
+		case '0' <= c && c <= '9':
+			lineStart = false
+			start := p.pos
+			for p.pos < len(p.text) && (isIdentChar(p.text[p.pos]) || '.' == p.text[p.pos]) {
+				p.pos++
+			}
+			ts = append(ts, token{tokNumber, start, p.pos})
+		default:
+			lineStart = false
+			ts = append(ts, token{tokPunct, p.pos, p.pos + 1})
+			p.pos++
+		}
+	}
+	return
+}
+
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '_' == c
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9'
+}
+
+// skipComment moves behind the comment at the current position. A line comment ends before the newline.
+func (p *lexer) skipComment() {
+	if strings.HasPrefix(p.text[p.pos:], "//") {
+		for p.pos < len(p.text) && '\n' != p.text[p.pos] {
+			if '\\' == p.text[p.pos] && p.pos+1 < len(p.text) && '\n' == p.text[p.pos+1] {
+				p.pos++ // line continuation
+			}
+			p.pos++
+		}
+		return
+	}
+	i := strings.Index(p.text[p.pos+2:], "*/")
+	if i < 0 {
+		p.pos = len(p.text)
+		return
+	}
+	p.pos += i + 4
+}
+
+// skipLiteral moves behind the string or character literal starting with quote q at the current position.
+// An unterminated literal ends at the line end and the result is false.
+func (p *lexer) skipLiteral(q byte) (terminated bool) {
+	p.pos++
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case q:
+			p.pos++
+			return true
+		case '\n':
+			return false
+		}
+		p.pos++
+	}
+	p.pos = len(p.text)
+	return false
+}
+
+// directive reads the preprocessor directive at the current position up to the line end and updates the #if nesting stack.
+// Directives produce no tokens with the exception of macro bodies: For "#define" only the macro name and its
+// parameter list are skipped, so a TRICE macro definition is not taken as TRICE macro call, but TRICE macro calls
+// inside a macro body are found.
+func (p *lexer) directive() {
+	var b strings.Builder
+	p.pos++ // '#'
+	if p.active() && p.skipDefineHead() {
+		return
+	}
+	for p.pos < len(p.text) && '\n' != p.text[p.pos] {
+		switch {
+		case strings.HasPrefix(p.text[p.pos:], "//") || strings.HasPrefix(p.text[p.pos:], "/*"):
+			p.skipComment()
+			b.WriteByte(' ')
+		case strings.HasPrefix(p.text[p.pos:], "\\\n"):
+			p.pos += 2
+			b.WriteByte(' ')
+		case strings.HasPrefix(p.text[p.pos:], "\\\r\n"):
+			p.pos += 3
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(p.text[p.pos])
+			p.pos++
+		}
+	}
+	f := strings.Fields(b.String())
+	if 0 == len(f) {
+		return
+	}
+	expr := strings.Join(f[1:], " ")
+	parentActive := p.active()
+	last := len(p.conds) - 1
+	switch f[0] {
+	case "if":
+		v := evalCondition(expr)
+		p.conds = append(p.conds, cond{active: parentActive && condFalse != v, taken: condTrue == v})
+	case "ifdef", "ifndef":
+		p.conds = append(p.conds, cond{active: parentActive})
+	case "elif":
+		if last < 0 {
+			return
+		}
+		if p.conds[last].taken {
+			p.conds[last].active = false
+			return
+		}
+		v := evalCondition(expr)
+		p.conds[last].active = p.outerActive() && condFalse != v
+		p.conds[last].taken = condTrue == v
+	case "else":
+		if last < 0 {
+			return
+		}
+		p.conds[last].active = p.outerActive() && !p.conds[last].taken
+		p.conds[last].taken = true
+	case "endif":
+		if last < 0 {
+			return
+		}
+		p.conds = p.conds[:last]
+	}
+}
+
+// skipDefineHead moves behind the macro name and the optional parameter list, if a "define" follows at the current position.
+// It returns false and keeps the position otherwise.
+func (p *lexer) skipDefineHead() bool {
+	i := p.skipBlanks(p.pos)
+	if !strings.HasPrefix(p.text[i:], "define") || len(p.text) > i+6 && isIdentChar(p.text[i+6]) {
+		return false
+	}
+	i = p.skipBlanks(i + 6)
+	for i < len(p.text) && isIdentChar(p.text[i]) {
+		i++
+	}
+	if i < len(p.text) && '(' == p.text[i] { // function-like macro
+		if k := strings.IndexAny(p.text[i:], ")\n"); 0 <= k && ')' == p.text[i+k] {
+			i += k + 1
+		}
+	}
+	p.pos = i
+	return true
+}
+
+// skipBlanks returns the position of the first character at or after i, which is no space or tab.
+func (p *lexer) skipBlanks(i int) int {
+	for i < len(p.text) && (' ' == p.text[i] || '\t' == p.text[i]) {
+		i++
+	}
+	return i
+}
+
+// outerActive returns true, if the code around the innermost #if is active.
+func (p *lexer) outerActive() bool {
+	for _, c := range p.conds[:len(p.conds)-1] {
+		if !c.active {
+			return false
+		}
+	}
+	return true
+}
+
+// evalCondition evaluates an #if expression, if it is a constant number like "0" or "(1)".
+func evalCondition(expr string) condValue {
+	s := strings.TrimSpace(expr)
+	for strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	s = strings.TrimRight(s, "uUlL")
+	n, err := strconv.ParseInt(s, 0, 64)
+	switch {
+	case nil != err:
+		return condUnknown
+	case 0 == n:
+		return condFalse
+	default:
+		return condTrue
+	}
+}
+
+// triceCall is a TRICE macro call found in a source text. All positions are byte offsets into the text.
+type triceCall struct {
+	name       string  // name is the macro name as written in the source, like "Trice8_1".
+	nameStart  int     // nameStart is the position of the macro name.
+	nameEnd    int     // nameEnd is the position after the macro name.
+	open       int     // open is the position after the opening bracket.
+	end        int     // end is the position after the closing bracket.
+	line       int     // line is the line number of the macro name, starting with 1.
+	function   string  // function is the name of the C function containing the call or "".
+	hasID      bool    // hasID is true, if the first argument is like "Id(n)".
+	idOK       bool    // idOK is true, if n inside "Id(n)" is a decimal number.
+	id         TriceID // id is the n inside "Id(n)".
+	idStart    int     // idStart is the position of the "Id(n)" argument.
+	idEnd      int     // idEnd is the position after the "Id(n)" argument.
+	hasStrg    bool    // hasStrg is true, if a format string literal follows the optional ID.
+	strg       string  // strg is the format string without quotes. Adjacent string literals are joined. Escape sequences are kept.
+	valueCount int     // valueCount is the count of arguments after the format string.
+}
+
+// findTrices returns all TRICE macro calls in active code of text in source order.
+// Calls spread over several lines are found as well as format strings containing escaped quotes
+// or consisting of several adjacent string literals. Calls inside comments or inside "#if 0" branches are ignored.
+func findTrices(text string) (calls []triceCall) {
+	ts := tokens(text)
+	var blocks []string // blocks holds the function name for each open curly bracket.
+	for i := 0; i < len(ts); i++ {
+		t := ts[i]
+		if tokPunct == t.kind {
+			switch text[t.start] {
+			case '{':
+				fn := functionName(text, ts, i)
+				if 0 < len(blocks) && "" != blocks[len(blocks)-1] {
+					fn = blocks[len(blocks)-1] // inner block
+				}
+				blocks = append(blocks, fn)
+			case '}':
+				if 0 < len(blocks) {
+					blocks = blocks[:len(blocks)-1]
+				}
+			}
+			continue
+		}
+		if tokIdent != t.kind || i+1 == len(ts) || "(" != text[ts[i+1].start:ts[i+1].end] || !matchTriceName.MatchString(text[t.start:t.end]) {
+			continue
+		}
+		args, end, ok := splitArgs(text, ts, i+2)
+		if !ok {
+			continue
+		}
+		c := triceCall{
+			name:      text[t.start:t.end],
+			nameStart: t.start,
+			nameEnd:   t.end,
+			open:      ts[i+1].end,
+			end:       ts[end].end,
+			line:      1 + strings.Count(text[:t.start], "\n"),
+		}
+		if 0 < len(blocks) {
+			c.function = blocks[len(blocks)-1]
+		}
+		c.parseArgs(text, args)
+		calls = append(calls, c)
+		i = end
+	}
+	return
+}
+
+// functionName returns the function name, if the curly bracket token ts[k] starts a function body like in "int f( int a ){".
+func functionName(text string, ts []token, k int) string {
+	i := k - 1
+	if i < 0 || ")" != text[ts[i].start:ts[i].end] {
+		return ""
+	}
+	for depth := 0; 0 <= i; i-- {
+		switch text[ts[i].start:ts[i].end] {
+		case ")":
+			depth++
+		case "(":
+			depth--
+		}
+		if 0 == depth {
+			break
+		}
+	}
+	if i < 1 || tokIdent != ts[i-1].kind {
+		return ""
+	}
+	switch name := text[ts[i-1].start:ts[i-1].end]; name {
+	case "if", "for", "while", "switch":
+		return ""
+	default:
+		return name
+	}
+}
+
+// splitArgs returns the macro arguments as token slices starting at token index k, which is after the opening bracket.
+// end is the index of the closing bracket token. ok is false, if no closing bracket was found.
+func splitArgs(text string, ts []token, k int) (args [][]token, end int, ok bool) {
+	depth := 0
+	begin := k
+	for i := k; i < len(ts); i++ {
+		if tokPunct != ts[i].kind {
+			continue
+		}
+		switch text[ts[i].start] {
+		case '(', '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ')':
+			if 0 < depth {
+				depth--
+				continue
+			}
+			if begin < i || 0 < len(args) {
+				args = append(args, ts[begin:i])
+			}
+			return args, i, true
+		case ',':
+			if 0 == depth {
+				args = append(args, ts[begin:i])
+				begin = i + 1
+			}
+		case ';':
+			if 0 == depth {
+				return nil, 0, false // statement end before closing bracket
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// parseArgs evaluates the optional "Id(n)" and the format string in the macro arguments args.
+func (c *triceCall) parseArgs(text string, args [][]token) {
+	if 0 < len(args) && 2 <= len(args[0]) && "Id" == text[args[0][0].start:args[0][0].end] && "(" == text[args[0][1].start:args[0][1].end] {
+		a := args[0]
+		c.hasID = true
+		c.idStart, c.idEnd = a[0].start, a[len(a)-1].end
+		_, c.id, c.idOK = triceIDParse(text[c.idStart:c.idEnd])
+		args = args[1:]
+	}
+	if 0 == len(args) || 0 == len(args[0]) {
+		return
+	}
+	var b strings.Builder
+	for _, t := range args[0] {
+		if tokString != t.kind || t.end-t.start < 2 || '"' != text[t.end-1] {
+			return // no complete string literal
+		}
+		b.WriteString(text[t.start+1 : t.end-1])
+	}
+	c.hasStrg = true
+	c.strg = b.String()
+	c.valueCount = len(args) - 1
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+// found is the relevant information of a triceCall for test comparisons.
+type found struct {
+	name   string
+	line   int
+	id     TriceID
+	strg   string
+	values int
+}
+
+func checkFindTrices(t *testing.T, text string, exp []found) {
+	var act []found
+	for _, c := range findTrices(text) {
+		act = append(act, found{c.name, c.line, c.id, c.strg, c.valueCount})
+	}
+	assert.Equal(t, exp, act)
+}
+
+func TestFindTricesMultiLine(t *testing.T) {
+	text := `TRICE16_2( Id(12),
+		"hi %d, %u\n",
+		a,
+		f(b, c) );
+	TRICE8_1( Id(13), "x=%d",
+		(int)(a + b) ); TRICE0( Id(14), "y" );
+`
+	checkFindTrices(t, text, []found{
+		{"TRICE16_2", 1, 12, `hi %d, %u\n`, 2},
+		{"TRICE8_1", 5, 13, "x=%d", 1},
+		{"TRICE0", 6, 14, "y", 0},
+	})
+}
+
+func TestFindTricesLiterals(t *testing.T) {
+	text := `TRICE0( Id(1), "say \"hi\", (%%)" );
+	TRICE8_2( Id(2), "a=%d, "
+	                 "b=%d\n", a, b );
+	TRICE_S( Id(3), "%s\n", "x)y,z" );
+	TRICE8_1( Id(4), ',' == c ? "yes %d" : "no %d", 7 );
+`
+	checkFindTrices(t, text, []found{
+		{"TRICE0", 1, 1, `say \"hi\", (%%)`, 0},
+		{"TRICE8_2", 2, 2, `a=%d, b=%d\n`, 2},
+		{"TRICE_S", 4, 3, `%s\n`, 1},
+		{"TRICE8_1", 5, 4, "", 0}, // no format string literal
+	})
+}
+
+// TestFindTricesUnterminated checks, that a malformed source line with an unterminated string literal is no format string.
+func TestFindTricesUnterminated(t *testing.T) {
+	checkFindTrices(t, "void f(void){ TRICE0( \"\n); }\n", []found{
+		{"TRICE0", 1, 0, "", 0},
+	})
+	checkFindTrices(t, "TRICE8_1( Id(5), \"x=%d\n\", a \"\n);", []found{
+		{"TRICE8_1", 1, 5, "", 0},
+	})
+}
+
+func TestFindTricesSkipped(t *testing.T) {
+	text := `// TRICE0( Id(1), "line comment" );
+	/* TRICE0( Id(2), "block comment" );
+	   TRICE0( Id(3), "block comment" ); */
+	#define TRICE8_1( id, pFmt, v0 ) TRICE8_1_fn( id, pFmt, v0 )
+	#define LOG TRICE0( Id(4), "macro body" ); \
+		TRICE0( Id(5), "continued macro body" )
+	#if 0 // disabled
+	TRICE0( Id(6), "disabled" );
+	#if 1
+	TRICE0( Id(7), "nested disabled" );
+	#endif
+	#else
+	TRICE0( Id(8), "else" );
+	#endif
+	#ifdef SOMETHING
+	TRICE0( Id(9), "unknown condition" );
+	#elif 0
+	TRICE0( Id(10), "elif 0" );
+	#else
+	TRICE0( Id(11), "else of unknown condition" );
+	#endif
+	#if (1)
+	TRICE0( Id(12), "enabled" );
+	#elif UNKNOWN
+	TRICE0( Id(13), "after true branch" );
+	#endif
+	char* s = "TRICE0( Id(14), \"in string\" );";
+`
+	checkFindTrices(t, text, []found{
+		{"TRICE0", 5, 4, "macro body", 0},
+		{"TRICE0", 6, 5, "continued macro body", 0},
+		{"TRICE0", 13, 8, "else", 0},
+		{"TRICE0", 16, 9, "unknown condition", 0},
+		{"TRICE0", 20, 11, "else of unknown condition", 0},
+		{"TRICE0", 23, 12, "enabled", 0},
+	})
+}
+
+func TestFindTricesID(t *testing.T) {
+	for _, x := range []struct {
+		text       string
+		hasID, ok  bool
+		id         TriceID
+		idS        string
+		hasStrg    bool
+		valueCount int
+	}{
+		{`TRICE0( "hi" );`, false, false, 0, "", true, 0},
+		{`TRICE0( Id( 17 ), "hi" );`, true, true, 17, "Id( 17 )", true, 0},
+		{`TRICE0( Id(0x0), "hi" );`, true, false, 0, "Id(0x0)", true, 0},
+		{`TRICE0( Id(-1), "hi" );`, true, false, 0, "Id(-1)", true, 0},
+		{`TRICE0( id(5), "hi" );`, false, false, 0, "", false, 0},
+		{`TRICE0();`, false, false, 0, "", false, 0},
+	} {
+		calls := findTrices(x.text)
+		assert.Equal(t, 1, len(calls), x.text)
+		c := calls[0]
+		assert.Equal(t, x.hasID, c.hasID, x.text)
+		assert.Equal(t, x.ok, c.idOK, x.text)
+		assert.Equal(t, x.id, c.id, x.text)
+		assert.Equal(t, x.idS, x.text[c.idStart:c.idEnd], x.text)
+		assert.Equal(t, x.hasStrg, c.hasStrg, x.text)
+		assert.Equal(t, x.valueCount, c.valueCount, x.text)
+	}
+}
+
+func TestUpdateParamCountAndID0Lexer(t *testing.T) {
+	tt := []struct{ text, exp string }{
+		{
+			"TRICE8(\n\t\"hi %d, \"\n\t\"%u\", a,\n\tb );",
+			"TRICE8_2( Id(0),\n\t\"hi %d, \"\n\t\"%u\", a,\n\tb );"},
+		{
+			`// TRICE8( "hi %d", 5);` + "\n" + `TRICE8( "ho %d", 5);`,
+			`// TRICE8( "hi %d", 5);` + "\n" + `TRICE8_1( Id(0), "ho %d", 5);`},
+		{
+			"#if 0\nTRICE8( \"hi %d\", 5);\n#endif\n",
+			"#if 0\nTRICE8( \"hi %d\", 5);\n#endif\n"},
+		{
+			`TRICE8( "say \"%d\"", 5);`,
+			`TRICE8_1( Id(0), "say \"%d\"", 5);`},
+	}
+	checkTestTable(t, tt, true)
+}
+
+func TestRefreshIDsLexer(t *testing.T) {
+	text := `TRICE8_2( Id(12), "a=%d, "
+		"b=%d\n", a, b ); // TRICE0( Id(13), "comment" );
+	TRICE_S( Id(14), "%s\n", "x" );
+`
+	el := make(TriceIDLookUp)
+	el[12] = TriceFmt{Type: "TRICE8_2", Strg: `a=%d, b=%d\n`}
+	el[14] = TriceFmt{Type: "TRICE_S", Strg: `%s\n`}
+	checkTil(t, text, el)
+}
+
+func TestZeroIDs(t *testing.T) {
+	text := "TRICE0( Id(12), \"a\" );\n// TRICE0( Id(13), \"b\" );\nTRICE8_1( Id(  14 ),\n \"c %d\", 1 );"
+	exp := "TRICE0( Id(0), \"a\" );\n// TRICE0( Id(13), \"b\" );\nTRICE8_1( Id(0),\n \"c %d\", 1 );"
+	act, modified := zeroIDs(os.Stdout, text)
+	assert.True(t, modified)
+	assert.Equal(t, exp, act)
+	_, modified = zeroIDs(os.Stdout, act)
+	assert.False(t, modified)
+}
@@ -13,8 +13,6 @@ import (
 	"io"
 	"os"
 	"reflect"
-
-	"github.com/rokath/trice/pkg/msg"
 )
 
 // ScZero does replace all ID's in source tree with 0
@@ -34,8 +32,13 @@ func ScZero(w io.Writer, SrcZ string, cmd *flag.FlagSet) error {
 // If any TRICE* is found without Id(n) or with Id(0) it is ignored.
 // SubCmdUpdate needs to know which IDs are used in the source tree to reliable add new IDs.
 func SubCmdReNewList(w io.Writer) (err error) {
+	unlock, err := lockList(w, FnJSON)
+	if nil != err {
+		return err
+	}
+	defer unlock()
 	lu := make(TriceIDLookUp)
-	return updateList(w, lu)
+	return updateList(w, lu, NewInfo(w, FnJSON))
 }
 
 // SubCmdRefreshList refreshes the trice id list parsing the source tree without changing any source file.
@@ -45,15 +48,17 @@ func SubCmdReNewList(w io.Writer) (err error) {
 // If any TRICE* is found without Id(n) or with Id(0) it is ignored.
 // SubCmdUpdate needs to know which IDs are used in the source tree to reliable add new IDs.
 func SubCmdRefreshList(w io.Writer) (err error) {
+	unlock, err := lockList(w, FnJSON)
+	if nil != err {
+		return err
+	}
+	defer unlock()
 	lu := NewLut(w, FnJSON)
-	return updateList(w, lu)
-}
-
-func refreshListAdapter(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, _ *bool) {
-	refreshList(w, root, lu, tflu)
+	return updateList(w, lu, NewInfo(w, FnJSON))
 }
 
-func updateList(w io.Writer, lu TriceIDLookUp) error {
+// updateList refreshes lu and the source information li from the source tree and writes FnJSON on changes.
+func updateList(w io.Writer, lu TriceIDLookUp, li TriceIDInfo) error {
 	tflu := lu.reverse()
 
 	// keep a copy
@@ -61,40 +66,85 @@ func updateList(w io.Writer, lu TriceIDLookUp) error {
 	for k, v := range lu {
 		lu0[k] = v
 	}
+	li0 := make(TriceIDInfo)
+	for k, v := range li {
+		li0[k] = v
+	}
+	si := newSourceInfo(li, FnJSON)
+	sc := newScanner(w, scanCacheName(FnJSON))
 	var listModified bool
-	walkSrcs(w, refreshListAdapter, lu, tflu, &listModified)
+	walkSrcs(w, func(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, _ *bool) {
+		refreshList(w, root, lu, tflu, si, sc)
+	}, lu, tflu, &listModified)
+	si.finish(lu)
 
 	// listModified does not help here, because it indicates that some sources are updated and therefore the list needs an update too.
 	// But here we are only scanning the source tree, so if there would be some changes they are not relevant because sources are not changed here.
-	eq := reflect.DeepEqual(lu0, lu)
+	eq := reflect.DeepEqual(lu0, lu) && reflect.DeepEqual(li0, li)
 
 	if Verbose {
 		fmt.Fprintln(w, len(lu0), " -> ", len(lu), "ID's in List", FnJSON)
 	}
 	if !eq && !DryRun {
-		msg.FatalOnErr(lu.toFile(FnJSON))
+		if err := lu.toFileWithInfo(FnJSON, li); nil != err {
+			return err
+		}
+	}
+	if !DryRun {
+		saveScanCache(w, sc)
 	}
 
 	return nil // SubCmdUpdate() // todo?
 }
 
-// SubCmdUpdate is sub-command update
+// SubCmdUpdate is sub-command update. FnJSON is locked during the update against parallel trice runs.
 func SubCmdUpdate(w io.Writer) error {
+	if err := Ranges.overlapsGlobal(Min, Max); nil != err {
+		return err
+	}
+	unlock, err := lockList(w, FnJSON)
+	if nil != err {
+		return err
+	}
+	defer unlock()
 	lu := NewLut(w, FnJSON)
 	tflu := lu.reverse()
+	spaces := make(idSpaces) // lu only grows during the update, so the free ID bitmaps are reusable
+	li := NewInfo(w, FnJSON)
+	li0 := make(TriceIDInfo)
+	for k, v := range li {
+		li0[k] = v
+	}
+	si := newSourceInfo(li, FnJSON)
+	sc := newScanner(w, scanCacheName(FnJSON))
 	var listModified bool
 	o := len(lu)
-	walkSrcs(w, IDsUpdate, lu, tflu, &listModified)
+	walkSrcs(w, func(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) {
+		idsUpdate(w, root, lu, tflu, si, sc, spaces, pListModified)
+	}, lu, tflu, &listModified)
+	si.finish(lu)
 	if Verbose {
 		fmt.Fprintln(w, len(lu), "ID's in List", FnJSON, "listModified=", listModified)
 	}
 
-	if (len(lu) != o || listModified) && !DryRun {
-		msg.FatalOnErr(lu.toFile(FnJSON))
+	if (len(lu) != o || listModified || li0.changed(li)) && !DryRun { // a new LastSeen date alone is no reason for a rewrite
+		if err := lu.toFileWithInfo(FnJSON, li); nil != err {
+			return err
+		}
+	}
+	if !DryRun {
+		saveScanCache(w, sc)
 	}
 	return nil
 }
 
+// saveScanCache writes the cache of sc. A failure is reported only, because the cache is not essential.
+func saveScanCache(w io.Writer, sc *scanner) {
+	if err := sc.save(); nil != err {
+		fmt.Fprintln(w, "Scan cache not written:", err)
+	}
+}
+
 func walkSrcs(w io.Writer, f func(w io.Writer, root string, lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool), lu TriceIDLookUp, tflu TriceFmtLookUp, pListModified *bool) {
 	if len(Srcs) == 0 {
 		Srcs = append(Srcs, "./") // default value
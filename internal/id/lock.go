@@ -0,0 +1,128 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// ID list file locking and atomic writing
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rokath/trice/pkg/msg"
+)
+
+var (
+	// LockTimeout is the maximum time to wait for a lock file held by a different trice process.
+	LockTimeout = 30 * time.Second
+
+	// LockStale is the age after which a lock file is considered as left over from a crashed trice process and removed.
+	LockStale = 5 * time.Minute
+)
+
+// lockPollInterval is the time between two attempts to get the lock file.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockList creates the advisory lock file fn.lock for the ID list file fn.
+// It waits up to LockTimeout, if a different process holds the lock, and removes lock files older than LockStale.
+// Only trice processes respect the lock. The returned unlock function removes the lock file.
+// The lock file is removed also, when a msg.Fatal function ends the program while the lock is held.
+func lockList(w io.Writer, fn string) (unlock func(), err error) {
+	lockName := fn + ".lock"
+	deadline := time.Now().Add(LockTimeout)
+	var waiting bool
+	for {
+		var f *os.File
+		f, err = os.OpenFile(lockName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if nil == err {
+			host, _ := os.Hostname()
+			_, err = fmt.Fprintln(f, "pid", os.Getpid(), "on", host, "since", time.Now().Format(time.RFC3339))
+			if e := f.Close(); nil == err {
+				err = e
+			}
+			if nil != err {
+				_ = os.Remove(lockName)
+				return nil, err
+			}
+			release := func() { _ = os.Remove(lockName) }
+			remove := msg.AtExit(release)
+			return func() { remove(); release() }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if removeStaleLock(w, lockName) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by %s since more than %v, remove it if no other trice process is running", fn, lockName, LockTimeout)
+		}
+		if !waiting {
+			waiting = true
+			fmt.Fprintln(w, "Waiting for", lockName, "...")
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// removeStaleLock removes lockName, if it is older than LockStale, and reports true then.
+// The lock file is taken over with an atomic rename first, so only one of several trice processes
+// finding the same stale lock file removes it. A fresh lock file renamed in the meantime is put back.
+func removeStaleLock(w io.Writer, lockName string) bool {
+	fi, err := os.Stat(lockName)
+	if nil != err || time.Since(fi.ModTime()) <= LockStale {
+		return false
+	}
+	stale := fmt.Sprintf("%s.stale.%d", lockName, os.Getpid())
+	if nil != os.Rename(lockName, stale) {
+		return false // a different process was faster
+	}
+	if taken, err := os.Stat(stale); nil == err && time.Since(taken.ModTime()) <= LockStale { // not the checked lock file anymore
+		if nil == os.Link(stale, lockName) { // put it back, if no newer lock file exists
+			_ = os.Remove(stale)
+			return false
+		}
+	}
+	fmt.Fprintln(w, "Removing stale lock file", lockName, "from", fi.ModTime().Format(time.RFC3339))
+	_ = os.Remove(stale)
+	return true
+}
+
+// writeFileAtomic writes b into a temporary file in the directory of fn and renames it to fn afterwards.
+// This way a reader sees either the old or the new content of fn but never a partially written file.
+// The file mode of an existing fn is kept.
+func writeFileAtomic(fn string, b []byte) (err error) {
+	mode := os.FileMode(0644)
+	if fi, e := os.Stat(fn); nil == e {
+		mode = fi.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(fn), filepath.Base(fn)+".*.tmp")
+	if nil != err {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		if nil != err {
+			_ = os.Remove(tmp)
+		}
+	}()
+	if _, err = f.Write(b); nil != err {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); nil != err {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); nil != err {
+		return err
+	}
+	if err = os.Chmod(tmp, mode); nil != err {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+func TestLockList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "til.json")
+	defer func(timeout, stale time.Duration) { LockTimeout, LockStale = timeout, stale }(LockTimeout, LockStale)
+	LockTimeout, LockStale = 300*time.Millisecond, time.Hour
+
+	unlock, err := lockList(ioutil.Discard, fn)
+	assert.Nil(t, err)
+	_, err = lockList(ioutil.Discard, fn)
+	assert.NotNil(t, err) // timeout
+	unlock()
+
+	unlock, err = lockList(ioutil.Discard, fn)
+	assert.Nil(t, err)
+	go func(unlock func()) {
+		time.Sleep(100 * time.Millisecond)
+		unlock()
+	}(unlock)
+	unlock2, err := lockList(ioutil.Discard, fn) // waits for the release
+	assert.Nil(t, err)
+	unlock2()
+
+	// stale lock file
+	assert.Nil(t, ioutil.WriteFile(fn+".lock", nil, 0644))
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(fn+".lock", old, old))
+	unlock, err = lockList(ioutil.Discard, fn)
+	assert.Nil(t, err)
+	unlock()
+	_, err = os.Stat(fn + ".lock")
+	assert.True(t, os.IsNotExist(err))
+	taken, err := filepath.Glob(fn + ".lock.stale.*")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(taken))
+
+	// a fresh lock file is not removed
+	assert.Nil(t, ioutil.WriteFile(fn+".lock", nil, 0644))
+	assert.False(t, removeStaleLock(ioutil.Discard, fn+".lock"))
+	_, err = os.Stat(fn + ".lock")
+	assert.Nil(t, err)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomic")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "til.json")
+	assert.Nil(t, ioutil.WriteFile(fn, []byte("{}"), 0640))
+	assert.Nil(t, writeFileAtomic(fn, []byte(`{"1":{"Type":"TRICE0","Strg":"a"}}`)))
+	b, err := ioutil.ReadFile(fn)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"1":{"Type":"TRICE0","Strg":"a"}}`, string(b))
+	fi, err := os.Stat(fn)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files)) // no temporary file left
+}
+
+// chanWriter sends each write as string to its channel. It never blocks, so a full channel loses writes.
+type chanWriter chan string
+
+func (c chanWriter) Write(b []byte) (int, error) {
+	select {
+	case c <- string(b):
+	default:
+	}
+	return len(b), nil
+}
+
+// waitFor reads from c until a text containing s arrives or 5 seconds passed.
+func (c chanWriter) waitFor(t *testing.T, s string) {
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case x := <-c:
+			if strings.Contains(x, s) {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("no %q within 5s", s)
+		}
+	}
+}
+
+// TestWatchFileKeepsTableOnParseError checks, that a half written ID list file does not change the table
+// and a valid file written atomically afterwards is taken.
+func TestWatchFileKeepsTableOnParseError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "til.json")
+	lu := TriceIDLookUp{1: {"TRICE0", "a"}}
+	assert.Nil(t, lu.toFile(fn))
+	m := new(sync.RWMutex)
+	defer func(v bool) { Verbose = v }(Verbose)
+	Verbose = true
+	c := make(chanWriter, 100)
+	go lu.WatchFile(c, m, fn)
+	c.waitFor(t, "watched now for changes")
+
+	assert.Nil(t, ioutil.WriteFile(fn, []byte(`{"2":{"Type":"TRIC`), 0644)) // half written
+	c.waitFor(t, "Keeping previous id.List")
+	m.RLock()
+	assert.Equal(t, TriceIDLookUp{1: {"TRICE0", "a"}}, lu)
+	m.RUnlock()
+
+	x := TriceIDLookUp{1: {"TRICE0", "a"}, 2: {"TRICE0", "b"}}
+	assert.Nil(t, x.toFile(fn))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m.RLock()
+		n := len(lu)
+		m.RUnlock()
+		if 2 == n || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.RLock()
+	defer m.RUnlock()
+	assert.Equal(t, x, lu)
+}
@@ -8,11 +8,10 @@ package id
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"math/rand"
-	"os"
 	"strings"
 
 	"github.com/rokath/trice/pkg/msg"
@@ -34,91 +33,25 @@ func NewLut(w io.Writer, fn string) TriceIDLookUp {
 // newID() gets a new ID not used so far.
 // The delivered id is usable as key for lu, but not added. So calling fn twice without adding to lu could give the same value back.
 // It is important that lu was refreshed before with all sources to avoid finding as a new ID an ID which is already used in the source tree.
-func (lu TriceIDLookUp) newID(w io.Writer, min, max TriceID, searchMethod string) TriceID {
+// The free ID bitmaps are taken from spaces, which must not be used for a different lu.
+func (lu TriceIDLookUp) newID(w io.Writer, spaces idSpaces, min, max TriceID, searchMethod string) TriceID {
 	if Verbose {
 		fmt.Fprintln(w, "IDMin=", min, "IDMax=", max, "IDMethod=", searchMethod)
 	}
+	s := spaces.space(lu, min, max)
+	s.sync(lu)
+	if interval := int(max - min + 1); !s.warned && 0 < s.free && s.free < interval>>2 { // less than 25%, reported once per interval
+		s.warned = true
+		fmt.Fprintf(w, "WARNING: Less than 25%% IDs free in range [%d,%d]: %d of %d free!\n", min, max, s.free, interval)
+	}
 	switch searchMethod {
-	case "random":
-		return lu.newRandomID(w, min, max)
-	case "upward":
-		return lu.newUpwardID(min, max)
-	case "downward":
-		return lu.newDownwardID(min, max)
+	case "random", "upward", "downward":
+		return s.next(lu, searchMethod)
 	}
 	msg.Info(fmt.Sprint("ERROR:", searchMethod, "is unknown ID search method."))
 	return 0
 }
 
-// newRandomID provides a random free ID inside interval [min,max].
-// The delivered id is usable as key for lu, but not added. So calling fn twice without adding to lu could give the same value back.
-func (lu TriceIDLookUp) newRandomID(w io.Writer, min, max TriceID) (id TriceID) {
-	interval := int(max - min + 1)
-	freeIDs := interval - len(lu)
-	msg.FatalInfoOnFalse(freeIDs > 0, "no new ID possible, "+fmt.Sprint(min, max, len(lu)))
-	wrnLimit := interval >> 2 // 25%
-	msg.InfoOnTrue(freeIDs < wrnLimit, "WARNING: Less than 25% IDs free!")
-	id = min + TriceID(rand.Intn(interval))
-	if len(lu) == 0 {
-		return
-	}
-	for {
-	nextTry:
-		for k := range lu {
-			if id == k { // id used
-				fmt.Fprintln(w, "ID", id, "used, next try...")
-				id = min + TriceID(rand.Intn(interval))
-				goto nextTry
-			}
-		}
-		return
-	}
-}
-
-// newUpwardID provides the smallest free ID inside interval [min,max].
-// The delivered id is usable as key for lut, but not added. So calling fn twice without adding to lu gives the same value back.
-func (lu TriceIDLookUp) newUpwardID(min, max TriceID) (id TriceID) {
-	interval := int(max - min + 1)
-	freeIDs := interval - len(lu)
-	msg.FatalInfoOnFalse(freeIDs > 0, "no new ID possible: "+fmt.Sprint("min=", min, ", max=", max, ", used=", len(lu)))
-	id = min
-	if len(lu) == 0 {
-		return
-	}
-	for {
-	nextTry:
-		for k := range lu {
-			if id == k { // id used
-				id++
-				goto nextTry
-			}
-		}
-		return
-	}
-}
-
-// newDownwardID provides the biggest free ID inside interval [min,max].
-// The delivered id is usable as key for lut, but not added. So calling fn twice without adding to lu gives the same value back.
-func (lu TriceIDLookUp) newDownwardID(min, max TriceID) (id TriceID) {
-	interval := int(max - min + 1)
-	freeIDs := interval - len(lu)
-	msg.FatalInfoOnFalse(freeIDs > 0, "no new ID possible: "+fmt.Sprint("min=", min, ", max=", max, ", used=", len(lu)))
-	id = max
-	if len(lu) == 0 {
-		return
-	}
-	for {
-	nextTry:
-		for k := range lu {
-			if id == k { // id used
-				id--
-				goto nextTry
-			}
-		}
-		return
-	}
-}
-
 // FromJSON converts JSON byte slice to lu.
 func (lu TriceIDLookUp) FromJSON(b []byte) (err error) {
 	if 0 < len(b) {
@@ -154,19 +87,32 @@ func (lu TriceIDLookUp) toJSON() ([]byte, error) {
 	return json.MarshalIndent(lu, "", "\t")
 }
 
-// toFile writes lut into file fn as indented JSON.
+// reload reads file fn into a new table and copies its content into lu only if fn is a non-empty valid JSON file.
+// On error lu stays unchanged. That happens for example, when fn is read while a different program writes it.
+func (lu TriceIDLookUp) reload(fn string) error {
+	b, err := ioutil.ReadFile(fn)
+	if nil != err {
+		return err
+	}
+	if 0 == len(b) { // probably truncated by a writer not finished yet
+		return errors.New("empty file")
+	}
+	x := make(TriceIDLookUp)
+	if err = x.FromJSON(b); nil != err {
+		return err
+	}
+	for id, tf := range x {
+		lu[id] = tf
+	}
+	return nil
+}
+
+// toFile writes lut into file fn as indented JSON. Source information already inside fn is kept.
+// The file is replaced atomically, so a parallel reader never sees a partially written file.
 func (lu TriceIDLookUp) toFile(fn string) (err error) {
-	var b []byte
-	b, err = lu.toJSON()
-	msg.FatalOnErr(err)
-	var f *os.File
-	f, err = os.Create(fn)
-	msg.FatalOnErr(err)
-	defer func() {
-		err = f.Close()
-	}()
-	_, err = f.Write(b)
-	return
+	li := make(TriceIDInfo)
+	_ = li.fromFile(fn) // a missing or invalid fn has no source information
+	return lu.toFileWithInfo(fn, li)
 }
 
 // reverse returns a reversed map. If different triceID's assigned to several equal TriceFmt only one of the TriceID gets it into tflu.
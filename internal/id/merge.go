@@ -0,0 +1,216 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// three-way merge of ID lists
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// MergeBase is the ID list file of the common ancestor for the merge sub-command. It may be empty or missing.
+	MergeBase string
+
+	// MergeOurs is the ID list file of the own branch for the merge sub-command.
+	MergeOurs string
+
+	// MergeTheirs is the ID list file of the other branch for the merge sub-command.
+	MergeTheirs string
+
+	// MergeOut is the merge result file. If empty, MergeOurs is overwritten, what a git merge driver has to do.
+	MergeOut string
+
+	// Renumber, if true, gives the colliding formats of MergeTheirs new IDs and patches them into the Srcs source trees.
+	Renumber bool
+)
+
+// collision is an ID used on both merge sides for different formats.
+type collision struct {
+	id     TriceID  // id is the colliding ID. In the merge result it keeps the ours format.
+	ours   TriceFmt // ours is the format of id in the own branch.
+	theirs TriceFmt // theirs is the format of id in the other branch.
+	newID  TriceID  // newID is the ID for the theirs format after renumbering or 0.
+}
+
+// sameFmt returns true, if a and b are equal. Lower case and upper case Type are not distinguished.
+func sameFmt(a, b TriceFmt) bool {
+	return strings.EqualFold(a.Type, b.Type) && a.Strg == b.Strg
+}
+
+// merge3 does a three-way merge of the ID lists ours and theirs with their common ancestor base.
+// For an ID changed on only one side since base the changed side wins. IDs are never dropped,
+// because existing target firmware could still send them. An ID with different formats on both sides
+// keeps the ours format and is returned as collision. The collisions are sorted by ID.
+func merge3(base, ours, theirs TriceIDLookUp) (lu TriceIDLookUp, cs []collision) {
+	lu = make(TriceIDLookUp, len(ours)+len(theirs))
+	for id, tf := range theirs {
+		lu[id] = tf
+	}
+	for id, o := range ours {
+		t, ok := theirs[id]
+		if !ok || sameFmt(o, t) {
+			lu[id] = o
+			continue
+		}
+		if b, ok := base[id]; ok && sameFmt(b, o) { // changed only on their side
+			continue
+		} else if ok && sameFmt(b, t) { // changed only on our side
+			lu[id] = o
+			continue
+		}
+		lu[id] = o
+		cs = append(cs, collision{id: id, ours: o, theirs: t})
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].id < cs[j].id })
+	return
+}
+
+// readList reads the ID list file fn into lu. Unlike fromFile it returns all errors, so a missing file is detectable.
+func (lu TriceIDLookUp) readList(fn string) error {
+	b, err := ioutil.ReadFile(fn)
+	if nil != err {
+		return err
+	}
+	return lu.FromJSON(b)
+}
+
+// SubCmdMerge is sub-command merge. It merges the ID lists MergeOurs and MergeTheirs with their common ancestor MergeBase
+// into MergeOut or, if MergeOut is empty, into MergeOurs. The source information is kept.
+// ID collisions are reported and result in an error, so git as merge driver caller marks til.json as conflicted.
+// With Renumber the colliding formats of MergeTheirs get new IDs inside the merge result and inside the Srcs source trees.
+func SubCmdMerge(w io.Writer) error {
+	if "" == MergeTheirs {
+		return fmt.Errorf("no -theirs ID list file given")
+	}
+	out := MergeOut
+	if "" == out {
+		out = MergeOurs
+	}
+	base, ours, theirs := make(TriceIDLookUp), make(TriceIDLookUp), make(TriceIDLookUp)
+	if err := base.readList(MergeBase); nil != err && !os.IsNotExist(err) {
+		return fmt.Errorf("base ID list: %v", err)
+	}
+	if err := ours.readList(MergeOurs); nil != err {
+		return fmt.Errorf("ours ID list: %v", err)
+	}
+	if err := theirs.readList(MergeTheirs); nil != err {
+		return fmt.Errorf("theirs ID list: %v", err)
+	}
+	lu, cs := merge3(base, ours, theirs)
+
+	liOurs, liTheirs := NewInfo(w, MergeOurs), NewInfo(w, MergeTheirs)
+	li := make(TriceIDInfo)
+	for id, tf := range lu {
+		if info, ok := liOurs[id]; ok && sameFmt(tf, ours[id]) {
+			li[id] = info
+		} else if info, ok := liTheirs[id]; ok && sameFmt(tf, theirs[id]) {
+			li[id] = info
+		}
+	}
+
+	if Renumber && 0 < len(cs) {
+		if err := Ranges.overlapsGlobal(Min, Max); nil != err {
+			return err
+		}
+		spaces := make(idSpaces) // lu only grows during the renumbering
+		dir := filepath.Dir(out) // the source file names are relative to the ID list file
+		for i := range cs {
+			c := &cs[i]
+			min, max, searchMethod := Min, Max, SearchMethod
+			info, ok := liTheirs[c.id]
+			if ok && "" != info.File { // the new ID is taken from the range of the source file, like in an update
+				min, max, searchMethod = Ranges.forFile(filepath.Join(dir, filepath.FromSlash(info.File)))
+			}
+			c.newID = lu.newID(w, spaces, min, max, searchMethod)
+			lu[c.newID] = c.theirs
+			if ok {
+				li[c.newID] = info
+			}
+			fmt.Fprintf(w, "Id(%d) %s %q -> Id(%d)\n", c.id, c.theirs.Type, c.theirs.Strg, c.newID)
+		}
+		var err error
+		walkSrcs(w, func(w io.Writer, root string, _ TriceIDLookUp, _ TriceFmtLookUp, _ *bool) {
+			if nil == err {
+				err = walkSrcTree(root, visitRenumber(w, cs))
+			}
+		}, lu, nil, nil)
+		if nil != err {
+			return err
+		}
+	}
+
+	if !DryRun {
+		unlock, err := lockList(w, out)
+		if nil != err {
+			return err
+		}
+		defer unlock()
+		if err := lu.toFileWithInfo(out, li); nil != err {
+			return err
+		}
+	}
+	if Verbose {
+		fmt.Fprintln(w, len(lu), "IDs merged into", out)
+	}
+	if Renumber || 0 == len(cs) {
+		return nil
+	}
+	for _, c := range cs {
+		fmt.Fprintf(w, "ID collision: Id(%d) is %s %q in ours and %s %q in theirs\n", c.id, c.ours.Type, c.ours.Strg, c.theirs.Type, c.theirs.Strg)
+	}
+	return fmt.Errorf("%d ID collisions, the merge result keeps the ours formats. Use -renumber to give the theirs formats new IDs", len(cs))
+}
+
+// visitRenumber returns a WalkFunc patching the new IDs of the collisions cs into the TRICE macros with the theirs formats.
+func visitRenumber(w io.Writer, cs []collision) filepath.WalkFunc {
+	return func(path string, fi os.FileInfo, err error) error {
+		text, err := readFile(w, path, fi, err)
+		if nil != err || "" == text {
+			return err
+		}
+		textR, modified := renumberIDs(text, cs)
+		if !modified || DryRun {
+			return nil
+		}
+		if Verbose {
+			fmt.Fprintln(w, "Changed: ", path)
+		}
+		if err := ioutil.WriteFile(path, []byte(textR), fi.Mode()); nil != err {
+			return fmt.Errorf("failed to change %s: %v", path, err)
+		}
+		return nil
+	}
+}
+
+// renumberIDs replaces inside text the IDs of the TRICE macros with a colliding theirs format by the new ID.
+// It returns the changed text and true if text was changed.
+func renumberIDs(text string, cs []collision) (string, bool) {
+	var b strings.Builder
+	last := 0 // last is the text position up to which text is copied into b
+	for _, c := range findTrices(text) {
+		if !c.hasID || !c.idOK || !c.hasStrg {
+			continue
+		}
+		for _, x := range cs {
+			if c.id == x.id && sameFmt(TriceFmt{Type: c.name, Strg: c.strg}, x.theirs) {
+				b.WriteString(text[last:c.idStart])
+				fmt.Fprintf(&b, "Id(%5d)", x.newID)
+				last = c.idEnd
+				break
+			}
+		}
+	}
+	if 0 == last {
+		return text, false
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
+}
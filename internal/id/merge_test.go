@@ -0,0 +1,91 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestMerge3(t *testing.T) {
+	base := TriceIDLookUp{1: {"TRICE0", "a"}, 2: {"TRICE0", "b"}, 3: {"TRICE0", "c"}, 4: {"TRICE0", "d"}}
+	ours := TriceIDLookUp{1: {"TRICE0", "a"}, 2: {"TRICE0", "b2"}, 3: {"TRICE0", "c"}, 5: {"TRICE0", "e"}, 6: {"TRICE0", "f"}, 7: {"trice0", "g"}}
+	theirs := TriceIDLookUp{1: {"TRICE0", "a"}, 2: {"TRICE0", "b"}, 3: {"TRICE0", "c3"}, 4: {"TRICE0", "d"}, 6: {"TRICE0", "x"}, 7: {"TRICE0", "g"}, 8: {"TRICE0", "h"}}
+	lu, cs := merge3(base, ours, theirs)
+	assert.Equal(t, TriceIDLookUp{
+		1: {"TRICE0", "a"},
+		2: {"TRICE0", "b2"}, // changed on our side
+		3: {"TRICE0", "c3"}, // changed on their side
+		4: {"TRICE0", "d"},  // removed on our side, but kept
+		5: {"TRICE0", "e"},
+		6: {"TRICE0", "f"}, // collision
+		7: {"trice0", "g"},
+		8: {"TRICE0", "h"},
+	}, lu)
+	assert.Equal(t, []collision{{id: 6, ours: TriceFmt{"TRICE0", "f"}, theirs: TriceFmt{"TRICE0", "x"}}}, cs)
+}
+
+// TestSubCmdMerge checks the merge of two branches both adding ID 7 for different formats.
+func TestSubCmdMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "merge")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fnBase, fnOurs, fnTheirs := filepath.Join(dir, "base.json"), filepath.Join(dir, "ours.json"), filepath.Join(dir, "theirs.json")
+	assert.Nil(t, ioutil.WriteFile(fnBase, []byte(`{"1":{"Type":"TRICE0","Strg":"a"}}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(fnOurs, []byte(`{"1":{"Type":"TRICE0","Strg":"a"},"7":{"Type":"TRICE0","Strg":"ours","File":"ours.c","Line":1}}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(fnTheirs, []byte(`{"1":{"Type":"TRICE0","Strg":"a"},"7":{"Type":"TRICE0","Strg":"theirs","File":"theirs.c","Line":2}}`), 0644))
+	src := filepath.Join(dir, "theirs.c")
+	assert.Nil(t, ioutil.WriteFile(src, []byte("\n\tTRICE0( Id(7), \"theirs\" );\n\tTRICE0( Id(7), \"ours\" );\n"), 0644))
+
+	defer func(base, ours, theirs, out string, renumber bool, srcs ArrayFlag, min, max TriceID, method string) {
+		MergeBase, MergeOurs, MergeTheirs, MergeOut, Renumber, Srcs, Min, Max, SearchMethod = base, ours, theirs, out, renumber, srcs, min, max, method
+	}(MergeBase, MergeOurs, MergeTheirs, MergeOut, Renumber, Srcs, Min, Max, SearchMethod)
+	MergeBase, MergeOurs, MergeTheirs, Srcs, Min, Max, SearchMethod = fnBase, fnOurs, fnTheirs, ArrayFlag{dir}, 10, 20, "upward"
+
+	// a collision is an error, the result is written nevertheless
+	MergeOut, Renumber = filepath.Join(dir, "out.json"), false
+	var out bytes.Buffer
+	assert.NotNil(t, SubCmdMerge(&out))
+	assert.Contains(t, out.String(), `ID collision: Id(7) is TRICE0 "ours" in ours and TRICE0 "theirs" in theirs`)
+	assert.Equal(t, TriceIDLookUp{1: {"TRICE0", "a"}, 7: {"TRICE0", "ours"}}, NewLut(&out, MergeOut))
+
+	// renumbering gives the theirs format a new ID inside the list and the sources
+	MergeOut, Renumber = "", true
+	assert.Nil(t, SubCmdMerge(&out))
+	assert.Equal(t, TriceIDLookUp{1: {"TRICE0", "a"}, 7: {"TRICE0", "ours"}, 10: {"TRICE0", "theirs"}}, NewLut(&out, fnOurs))
+	assert.Equal(t, TriceIDInfo{7: {File: "ours.c", Line: 1}, 10: {File: "theirs.c", Line: 2}}, NewInfo(&out, fnOurs))
+	b, err := ioutil.ReadFile(src)
+	assert.Nil(t, err)
+	assert.Equal(t, "\n\tTRICE0( Id(   10), \"theirs\" );\n\tTRICE0( Id(7), \"ours\" );\n", string(b))
+}
+
+// TestSubCmdMergeRanges checks, that a renumbered trice gets its new ID from the range matching its source file.
+func TestSubCmdMergeRanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "merge")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fnOurs, fnTheirs := filepath.Join(dir, "ours.json"), filepath.Join(dir, "theirs.json")
+	assert.Nil(t, ioutil.WriteFile(fnOurs, []byte(`{"7":{"Type":"TRICE0","Strg":"ours"}}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(fnTheirs, []byte(`{"7":{"Type":"TRICE0","Strg":"theirs","File":"boot/main.c","Line":1},"8":{"Type":"TRICE0","Strg":"ours"}}`), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "boot"), 0755))
+	src := filepath.Join(dir, "boot", "main.c")
+	assert.Nil(t, ioutil.WriteFile(src, []byte(`TRICE0( Id(7), "theirs" );`), 0644))
+
+	defer func(base, ours, theirs, out string, renumber bool, srcs ArrayFlag, r IDRanges, min, max TriceID, method string) {
+		MergeBase, MergeOurs, MergeTheirs, MergeOut, Renumber, Srcs, Ranges, Min, Max, SearchMethod = base, ours, theirs, out, renumber, srcs, r, min, max, method
+	}(MergeBase, MergeOurs, MergeTheirs, MergeOut, Renumber, Srcs, Ranges, Min, Max, SearchMethod)
+	MergeBase, MergeOurs, MergeTheirs, MergeOut, Renumber, Srcs, Ranges, Min, Max, SearchMethod = "", fnOurs, fnTheirs, "", true, ArrayFlag{dir}, nil, 10, 20, "upward"
+	assert.Nil(t, Ranges.Set(filepath.Join(dir, "boot")+"=30:39:downward"))
+	var out bytes.Buffer
+	assert.Nil(t, SubCmdMerge(&out))
+	assert.Equal(t, TriceIDLookUp{7: {"TRICE0", "ours"}, 8: {"TRICE0", "ours"}, 39: {"TRICE0", "theirs"}}, NewLut(&out, fnOurs))
+	b, err := ioutil.ReadFile(src)
+	assert.Nil(t, err)
+	assert.Equal(t, `TRICE0( Id(   39), "theirs" );`, string(b))
+}
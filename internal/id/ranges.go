@@ -0,0 +1,157 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// ID ranges per source path
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IDRange assigns the ID interval [Min,Max] and the search method to all source files matching Pattern.
+type IDRange struct {
+	Pattern      string  // Pattern is a source path or a path.Match pattern, relative to the working directory.
+	Min, Max     TriceID // Min and Max are the interval ends for new IDs.
+	SearchMethod string  // SearchMethod is the search method for new IDs. If empty, the global SearchMethod is used.
+}
+
+// IDRanges is a list of ID ranges. For a source file the first matching range is used.
+// Source files without matching range get new IDs from the global interval [Min,Max].
+type IDRanges []IDRange
+
+// Ranges are the ID ranges for the update sub-command.
+var Ranges IDRanges
+
+// String is needed for flag.Value interface satisfaction.
+func (r *IDRanges) String() string {
+	var s []string
+	for _, x := range *r {
+		s = append(s, x.String())
+	}
+	return strings.Join(s, " ")
+}
+
+// String returns x in the command line form "pattern=min:max[:method]".
+func (x IDRange) String() string {
+	s := fmt.Sprintf("%s=%d:%d", x.Pattern, x.Min, x.Max)
+	if "" != x.SearchMethod {
+		s += ":" + x.SearchMethod
+	}
+	return s
+}
+
+// Set appends the range given in the form "pattern=min:max[:method]", like "boot/*=1000:1999:upward".
+// A range overlapping an already given range is rejected, because both would hand out the same IDs.
+func (r *IDRanges) Set(value string) error {
+	x, err := ParseIDRange(value)
+	if nil != err {
+		return err
+	}
+	for _, y := range *r {
+		if x.Min <= y.Max && y.Min <= x.Max {
+			return fmt.Errorf("ID range %q overlaps ID range %q", x.String(), y.String())
+		}
+	}
+	*r = append(*r, x)
+	return nil
+}
+
+// overlapsGlobal returns an error, if a range in r overlaps the global interval [min,max].
+// The global interval is for source files without matching range, so an overlap would hand out the same IDs twice.
+// It is checked after all flags are parsed, because -IDMin and -IDMax can follow -IDRange.
+func (r IDRanges) overlapsGlobal(min, max TriceID) error {
+	for _, x := range r {
+		if x.Min <= max && min <= x.Max {
+			return fmt.Errorf("ID range %q overlaps the ID range [%d,%d] of -IDMin and -IDMax", x.String(), min, max)
+		}
+	}
+	return nil
+}
+
+// ParseIDRange parses s in the form "pattern=min:max[:method]".
+func ParseIDRange(s string) (x IDRange, err error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return x, fmt.Errorf("ID range %q is not in the form pattern=min:max[:method]", s)
+	}
+	x.Pattern = s[:i]
+	if _, err = path.Match(filepath.ToSlash(x.Pattern), ""); nil != err {
+		return x, fmt.Errorf("ID range %q: %v", s, err)
+	}
+	f := strings.Split(s[i+1:], ":")
+	if len(f) < 2 || 3 < len(f) {
+		return x, fmt.Errorf("ID range %q is not in the form pattern=min:max[:method]", s)
+	}
+	min, err := strconv.Atoi(f[0])
+	if nil != err {
+		return x, fmt.Errorf("ID range %q: %v", s, err)
+	}
+	max, err := strconv.Atoi(f[1])
+	if nil != err {
+		return x, fmt.Errorf("ID range %q: %v", s, err)
+	}
+	if min <= 0 || max < min {
+		return x, fmt.Errorf("ID range %q: need 0 < min <= max", s)
+	}
+	x.Min, x.Max = TriceID(min), TriceID(max)
+	if 3 == len(f) {
+		switch f[2] {
+		case "random", "upward", "downward":
+			x.SearchMethod = f[2]
+		default:
+			return x, fmt.Errorf("ID range %q: unknown ID search method %q", s, f[2])
+		}
+	}
+	return x, nil
+}
+
+// forFile returns the ID interval and search method for the source file fn.
+// It returns the global Min, Max and SearchMethod, if no range in r matches fn.
+func (r IDRanges) forFile(fn string) (min, max TriceID, searchMethod string) {
+	p := slashPath(fn)
+	for _, x := range r {
+		if matchPath(slashPath(x.Pattern), p) {
+			searchMethod = x.SearchMethod
+			if "" == searchMethod {
+				searchMethod = SearchMethod
+			}
+			return x.Min, x.Max, searchMethod
+		}
+	}
+	return Min, Max, SearchMethod
+}
+
+// slashPath returns fn relative to the working directory in slash form, if fn is inside, otherwise fn absolute.
+func slashPath(fn string) string {
+	abs, err := filepath.Abs(fn)
+	if nil != err {
+		return filepath.ToSlash(filepath.Clean(fn))
+	}
+	if wd, err := os.Getwd(); nil == err {
+		if rel, err := filepath.Rel(wd, abs); nil == err && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(abs)
+}
+
+// matchPath returns true, if pattern matches p or one of its parent directories.
+// Example: "lib/*" matches "lib/uart/uart.c", because "lib/uart" matches.
+func matchPath(pattern, p string) bool {
+	for {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		d := path.Dir(p)
+		if d == p || "." == d || "/" == d {
+			return false
+		}
+		p = d
+	}
+}
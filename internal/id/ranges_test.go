@@ -0,0 +1,117 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestParseIDRange(t *testing.T) {
+	x, err := ParseIDRange("lib/*=2000:4999:upward")
+	assert.Nil(t, err)
+	assert.Equal(t, IDRange{"lib/*", 2000, 4999, "upward"}, x)
+	assert.Equal(t, "lib/*=2000:4999:upward", x.String())
+	x, err = ParseIDRange("a=b=1:2")
+	assert.Nil(t, err)
+	assert.Equal(t, IDRange{"a=b", 1, 2, ""}, x)
+	for _, s := range []string{"boot", "=1:2", "boot=1", "boot=2:1", "boot=0:9", "boot=1:x", "boot=1:2:sideward", "[=1:2"} {
+		_, err = ParseIDRange(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestIDRangesOverlap(t *testing.T) {
+	var r IDRanges
+	assert.Nil(t, r.Set("boot=1000:1999"))
+	assert.Nil(t, r.Set("lib=2000:2999"))
+	for _, s := range []string{"app=1999:2000", "app=1500:1600", "app=500:1000", "app=2999:3000", "app=1:9999", "boot=1000:1999"} {
+		assert.NotNil(t, r.Set(s), s)
+	}
+	assert.Nil(t, r.Set("app=3000:3999"))
+	assert.Equal(t, 3, len(r))
+}
+
+func TestIDRangesOverlapGlobal(t *testing.T) {
+	var r IDRanges
+	assert.Nil(t, r.Set("boot=1000:1999"))
+	assert.Nil(t, r.Set("lib=2000:2999"))
+	assert.Nil(t, r.overlapsGlobal(3000, 65535))
+	assert.Nil(t, r.overlapsGlobal(1, 999))
+	for _, x := range [][2]TriceID{{1, 1000}, {2999, 3000}, {1500, 1600}, {1, 65535}} {
+		assert.NotNil(t, r.overlapsGlobal(x[0], x[1]), x)
+	}
+
+	defer func(r IDRanges, min, max TriceID) { Ranges, Min, Max = r, min, max }(Ranges, Min, Max)
+	Ranges, Min, Max = r, 1, 65535
+	assert.NotNil(t, SubCmdUpdate(ioutil.Discard))
+}
+
+func TestIDRangesForFile(t *testing.T) {
+	defer func(min, max TriceID, method string) { Min, Max, SearchMethod = min, max, method }(Min, Max, SearchMethod)
+	Min, Max, SearchMethod = 100, 199, "random"
+	var r IDRanges
+	assert.Nil(t, r.Set("boot=1000:1999"))
+	assert.Nil(t, r.Set("lib/*=2000:4999:upward"))
+	assert.Nil(t, r.Set("*.h=5000:5999"))
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	for _, x := range []struct {
+		fn       string
+		min, max TriceID
+		method   string
+	}{
+		{"boot/main.c", 1000, 1999, "random"},
+		{filepath.Join(wd, "boot", "src", "x.c"), 1000, 1999, "random"},
+		{"lib/uart/uart.c", 2000, 4999, "upward"},
+		{"lib.c", 100, 199, "random"},
+		{"app/main.c", 100, 199, "random"},
+		{"app.h", 5000, 5999, "random"},
+	} {
+		min, max, method := r.forFile(x.fn)
+		assert.Equal(t, x.min, min, x.fn)
+		assert.Equal(t, x.max, max, x.fn)
+		assert.Equal(t, x.method, method, x.fn)
+	}
+}
+
+// TestSubCmdUpdateRanges checks, that new IDs are taken from the range matching the source file and a nearly exhausted range is reported.
+func TestSubCmdUpdateRanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ranges")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fnJSON := filepath.Join(dir, "til.json")
+	lu := make(TriceIDLookUp)
+	for id := TriceID(10); id <= 16; id++ {
+		lu[id] = TriceFmt{"TRICE0", fmt.Sprint("old", id)}
+	}
+	assert.Nil(t, lu.toFile(fnJSON))
+	for _, d := range []string{"boot", "app"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, d), 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, d, "main.c"), []byte(`TRICE0( "`+d+`" );`), 0644))
+	}
+
+	defer func(fn string, srcs ArrayFlag, r IDRanges, method string) {
+		FnJSON, Srcs, Ranges, SearchMethod = fn, srcs, r, method
+	}(FnJSON, Srcs, Ranges, SearchMethod)
+	FnJSON, Srcs, Ranges, SearchMethod = fnJSON, ArrayFlag{dir}, nil, "random"
+	assert.Nil(t, Ranges.Set(filepath.Join(dir, "boot")+"=10:17:upward"))
+	assert.Nil(t, Ranges.Set(filepath.Join(dir, "app")+"=20:29:downward"))
+	var out bytes.Buffer
+	assert.Nil(t, SubCmdUpdate(&out))
+	assert.Contains(t, out.String(), "WARNING: Less than 25% IDs free in range [10,17]: 1 of 8 free!")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "boot", "main.c"))
+	assert.Nil(t, err)
+	assert.Equal(t, `TRICE0( Id(   17), "boot" );`, string(b))
+	b, err = ioutil.ReadFile(filepath.Join(dir, "app", "main.c"))
+	assert.Nil(t, err)
+	assert.Equal(t, `TRICE0( Id(   29), "app" );`, string(b))
+}
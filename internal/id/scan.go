@@ -0,0 +1,278 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+// parallel source scanning with cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// scanCacheVersion is stored inside the cache file. Increment it on changes of cacheEntry or of the lexer results.
+const scanCacheVersion = 1
+
+var (
+	// ScanCache is the cache file for the TRICE macros found per source file.
+	// "auto" means a file inside the user cache directory, named after the absolute ID list file path. "off" or "none" disable the cache.
+	ScanCache = "auto"
+
+	// userCacheDir returns the user cache directory. Tests replace it.
+	userCacheDir = os.UserCacheDir
+
+	// ScanWorkers is the count of source files read and parsed in parallel.
+	ScanWorkers = runtime.NumCPU()
+)
+
+// cachedCall is the cached part of a triceCall. The text positions are not cached, because
+// a cached file is not changed. A file needing changes is parsed again.
+type cachedCall struct {
+	Name       string
+	Line       int
+	Func       string
+	HasID      bool
+	IDOK       bool
+	ID         TriceID
+	HasStrg    bool
+	Strg       string
+	ValueCount int
+}
+
+// cacheEntry holds the TRICE macros found inside a source file together with the file state.
+type cacheEntry struct {
+	ModTime int64  // ModTime is the file modification time in ns since 1970.
+	Size    int64  // Size is the file size in bytes.
+	Hash    string // Hash is the SHA-256 of the file content in hex.
+	Calls   []cachedCall
+}
+
+// scanCacheFile is the gob encoded cache file content.
+type scanCacheFile struct {
+	Version int
+	Written int64                 // Written is the cache file write time in ns since 1970.
+	Files   map[string]cacheEntry // Files are the cache entries keyed by absolute file path.
+}
+
+// srcFile is a scanned source file.
+type srcFile struct {
+	path   string
+	key    string // key is the absolute path for the cache, so the cache entries do not depend on the working directory.
+	fi     os.FileInfo
+	text   string      // text is the file content, if loaded is true.
+	loaded bool        // loaded is false, if the file was not read, because its cache entry is valid.
+	calls  []triceCall // calls are the found TRICE macros. Taken from the cache they have no text positions.
+	entry  cacheEntry  // entry is the cache entry for the file.
+	err    error       // err is the read error, if any.
+}
+
+// scanner reads and parses source files in parallel and caches the results in a file.
+type scanner struct {
+	fn      string                // fn is the cache file name or "" for no cache file.
+	written int64                 // written is the write time of the read cache file.
+	old     map[string]cacheEntry // old are the entries from the cache file.
+	new     map[string]cacheEntry // new are the entries for all files scanned so far.
+	dirty   bool                  // dirty is true, if new differs from old.
+}
+
+// newScanner returns a scanner using cache file fn. If fn is "", the cache is not used.
+// A missing or unreadable cache file results in an empty cache.
+func newScanner(w io.Writer, fn string) *scanner {
+	s := &scanner{fn: fn, old: make(map[string]cacheEntry), new: make(map[string]cacheEntry)}
+	if "" == fn {
+		return s
+	}
+	b, err := ioutil.ReadFile(fn)
+	if nil != err {
+		return s
+	}
+	var c scanCacheFile
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&c); nil != err || scanCacheVersion != c.Version {
+		if Verbose {
+			fmt.Fprintln(w, "Ignoring scan cache", fn)
+		}
+		return s
+	}
+	s.written, s.old = c.Written, c.Files
+	if nil == s.old {
+		s.old = make(map[string]cacheEntry)
+	}
+	return s
+}
+
+// scanCacheName returns the cache file name according to ScanCache for the ID list file fnJSON.
+// The "auto" cache file is outside the project, so it never gets under version control. Its name contains a hash of
+// the absolute fnJSON path, so different projects use different cache files. Without user cache directory no cache is used.
+func scanCacheName(fnJSON string) string {
+	switch ScanCache {
+	case "off", "none":
+		return ""
+	case "auto":
+		if "emptyFile" == fnJSON { // reserved name for tests only
+			return ""
+		}
+		dir, err := userCacheDir()
+		if nil != err {
+			return ""
+		}
+		abs, err := filepath.Abs(fnJSON)
+		if nil != err {
+			return ""
+		}
+		h := sha256.Sum256([]byte(abs))
+		return filepath.Join(dir, "trice", filepath.Base(abs)+"-"+hex.EncodeToString(h[:8])+".cache")
+	}
+	return ScanCache
+}
+
+// walk calls fn for each source file inside root in filepath.Walk order. The files are read and parsed in parallel before.
+func (s *scanner) walk(w io.Writer, root string, fn func(f *srcFile) error) error {
+	var files []*srcFile
+	err := walkSrcTree(root, func(path string, fi os.FileInfo, err error) error {
+		if nil != err {
+			return err
+		}
+		key, err := filepath.Abs(path)
+		if nil != err {
+			return err
+		}
+		files = append(files, &srcFile{path: path, key: key, fi: fi})
+		return nil
+	})
+	if nil != err {
+		return err
+	}
+	s.load(files)
+	for _, f := range files {
+		if Verbose {
+			fmt.Fprintln(w, f.path)
+		}
+		if nil != f.err {
+			return f.err
+		}
+		s.new[f.key] = f.entry
+		if e, ok := s.old[f.key]; !ok || e.ModTime != f.entry.ModTime || e.Size != f.entry.Size || e.Hash != f.entry.Hash {
+			s.dirty = true
+		}
+		if err := fn(f); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads and parses files with ScanWorkers goroutines. Files with a valid cache entry are not read.
+func (s *scanner) load(files []*srcFile) {
+	n := ScanWorkers
+	if n < 1 {
+		n = 1
+	}
+	ch := make(chan *srcFile)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for f := range ch {
+				s.loadFile(f)
+			}
+		}()
+	}
+	for _, f := range files {
+		ch <- f
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// loadFile fills f from the cache, if modification time and size match and the file was not changed
+// after the cache was written. Otherwise f is read and parsed only if its content hash differs from the cache entry.
+func (s *scanner) loadFile(f *srcFile) {
+	mt, size := f.fi.ModTime().UnixNano(), f.fi.Size()
+	e, ok := s.old[f.key]
+	if ok && e.ModTime == mt && e.Size == size && mt < s.written {
+		f.entry, f.calls = e, e.triceCalls()
+		return
+	}
+	b, err := ioutil.ReadFile(f.path)
+	if nil != err {
+		f.err = err
+		return
+	}
+	f.text, f.loaded = string(b), true
+	h := sha256.Sum256(b)
+	hash := hex.EncodeToString(h[:])
+	if ok && e.Hash == hash {
+		f.calls = e.triceCalls()
+	} else {
+		f.calls = findTrices(f.text)
+	}
+	f.entry = cacheEntry{ModTime: mt, Size: size, Hash: hash, Calls: cachedCalls(f.calls)}
+}
+
+// content returns the text of f and reads it, if not done yet.
+func (f *srcFile) content() (string, error) {
+	if !f.loaded {
+		b, err := ioutil.ReadFile(f.path)
+		if nil != err {
+			return "", err
+		}
+		f.text, f.loaded = string(b), true
+	}
+	return f.text, nil
+}
+
+// changed updates the cache entry of f after text was written into it.
+func (s *scanner) changed(f *srcFile, text string) {
+	s.dirty = true
+	fi, err := os.Stat(f.path)
+	if nil != err {
+		delete(s.new, f.key)
+		return
+	}
+	h := sha256.Sum256([]byte(text))
+	s.new[f.key] = cacheEntry{ModTime: fi.ModTime().UnixNano(), Size: fi.Size(), Hash: hex.EncodeToString(h[:]), Calls: cachedCalls(findTrices(text))}
+}
+
+// save writes the entries of all scanned files into the cache file, if they changed. Entries for files not scanned anymore are dropped.
+func (s *scanner) save() error {
+	if "" == s.fn || !s.dirty && len(s.new) == len(s.old) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.fn), 0755); nil != err {
+		return err
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(scanCacheFile{Version: scanCacheVersion, Written: time.Now().UnixNano(), Files: s.new}); nil != err {
+		return err
+	}
+	return writeFileAtomic(s.fn, b.Bytes())
+}
+
+// cachedCalls converts cs into its cached form.
+func cachedCalls(cs []triceCall) []cachedCall {
+	x := make([]cachedCall, len(cs))
+	for i, c := range cs {
+		x[i] = cachedCall{c.name, c.line, c.function, c.hasID, c.idOK, c.id, c.hasStrg, c.strg, c.valueCount}
+	}
+	return x
+}
+
+// triceCalls converts the cached calls of e back. The text positions are 0.
+func (e cacheEntry) triceCalls() []triceCall {
+	x := make([]triceCall, len(e.Calls))
+	for i, c := range e.Calls {
+		x[i] = triceCall{name: c.Name, line: c.Line, function: c.Func, hasID: c.HasID, idOK: c.IDOK, id: c.ID, hasStrg: c.HasStrg, strg: c.Strg, valueCount: c.ValueCount}
+	}
+	return x
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package id
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+// TestScanCache checks, that only changed files are read again.
+func TestScanCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scan")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fnCache := filepath.Join(dir, "til.json.cache")
+	old := time.Now().Add(-time.Minute)
+	for i, s := range []string{`TRICE0( Id(1), "a" );`, `TRICE0( Id(2), "b" );`, `TRICE0( Id(3), "c" );`} {
+		fn := filepath.Join(dir, fmt.Sprint("f", i, ".c"))
+		assert.Nil(t, ioutil.WriteFile(fn, []byte(s), 0644))
+		assert.Nil(t, os.Chtimes(fn, old, old))
+	}
+	scan := func() (loaded []bool, ids []TriceID) {
+		sc := newScanner(ioutil.Discard, fnCache)
+		assert.Nil(t, sc.walk(ioutil.Discard, dir, func(f *srcFile) error {
+			loaded = append(loaded, f.loaded)
+			ids = append(ids, f.calls[0].id)
+			return nil
+		}))
+		assert.Nil(t, sc.save())
+		return
+	}
+	loaded, ids := scan()
+	assert.Equal(t, []bool{true, true, true}, loaded)
+	assert.Equal(t, []TriceID{1, 2, 3}, ids)
+
+	loaded, ids = scan()
+	assert.Equal(t, []bool{false, false, false}, loaded)
+	assert.Equal(t, []TriceID{1, 2, 3}, ids)
+
+	fn := filepath.Join(dir, "f1.c")
+	assert.Nil(t, ioutil.WriteFile(fn, []byte(`TRICE0( Id(9), "b" );`), 0644))                        // changed content
+	assert.Nil(t, os.Chtimes(filepath.Join(dir, "f2.c"), old.Add(time.Second), old.Add(time.Second))) // touched only
+	loaded, ids = scan()
+	assert.Equal(t, []bool{false, true, true}, loaded)
+	assert.Equal(t, []TriceID{1, 9, 3}, ids)
+
+	wd, err := os.Getwd() // a relative source path uses the same cache entries
+	assert.Nil(t, err)
+	defer os.Chdir(wd)
+	assert.Nil(t, os.Chdir(filepath.Dir(dir)))
+	sc := newScanner(ioutil.Discard, fnCache)
+	for k := range sc.old {
+		assert.True(t, filepath.IsAbs(k), k)
+	}
+	assert.Nil(t, sc.walk(ioutil.Discard, filepath.Base(dir), func(f *srcFile) error {
+		assert.False(t, f.loaded, f.path)
+		return nil
+	}))
+}
+
+// TestSubCmdUpdateDeterministic checks, that the IDs do not depend on the worker count and that a cached run gives the same result.
+func TestSubCmdUpdateDeterministic(t *testing.T) {
+	defer func(fn string, srcs ArrayFlag, min, max TriceID, method string, n int) {
+		FnJSON, Srcs, Min, Max, SearchMethod, ScanWorkers = fn, srcs, min, max, method, n
+	}(FnJSON, Srcs, Min, Max, SearchMethod, ScanWorkers)
+	Min, Max, SearchMethod = 100, 999, "upward"
+
+	var results []string
+	for _, n := range []int{1, 8, 8} {
+		dir, err := ioutil.TempDir("", "deterministic")
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+		FnJSON, Srcs, ScanWorkers = filepath.Join(dir, "til.json"), ArrayFlag{dir}, n
+		assert.Nil(t, ioutil.WriteFile(FnJSON, nil, 0644))
+		for i := 0; i < 20; i++ {
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("f%02d.c", i)), []byte(fmt.Sprintf("TRICE0( \"a%d\" );\nTRICE0( \"b%d\" );\n", i, i)), 0644))
+		}
+		assert.Nil(t, SubCmdUpdate(ioutil.Discard))
+		if 2 == len(results) { // second run with cache
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "f07.c"), []byte("TRICE0( \"new\" );\n"), 0644))
+			assert.Nil(t, SubCmdUpdate(ioutil.Discard))
+		}
+		b, err := ioutil.ReadFile(FnJSON)
+		assert.Nil(t, err)
+		results = append(results, string(b))
+	}
+	assert.Equal(t, results[0], results[1])
+	assert.Contains(t, results[2], `"Strg": "new"`)
+	assert.Equal(t, 41, len(NewLut(ioutil.Discard, FnJSON)))
+}
+
+// TestScanCacheName checks, that the "auto" cache file is inside the user cache directory and distinct per ID list path.
+func TestScanCacheName(t *testing.T) {
+	defer func(cache string) { ScanCache = cache }(ScanCache)
+	dir, err := userCacheDir()
+	assert.Nil(t, err)
+	ScanCache = "auto"
+	a, b := scanCacheName(filepath.Join("a", "til.json")), scanCacheName(filepath.Join("b", "til.json"))
+	assert.True(t, strings.HasPrefix(a, filepath.Join(dir, "trice")+string(filepath.Separator)), a)
+	assert.True(t, strings.HasSuffix(a, ".cache"), a)
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, scanCacheName(filepath.Join("a", ".", "til.json")))
+	assert.Equal(t, "", scanCacheName("emptyFile"))
+	ScanCache = "off"
+	assert.Equal(t, "", scanCacheName("til.json"))
+	ScanCache = "my.cache"
+	assert.Equal(t, "my.cache", scanCacheName("til.json"))
+}
+
+// BenchmarkSubCmdUpdateUnchanged measures an update of a source tree without changes, like in an incremental build.
+func BenchmarkSubCmdUpdateUnchanged(b *testing.B) {
+	dir, err := ioutil.TempDir("", "bench")
+	assert.Nil(b, err)
+	defer os.RemoveAll(dir)
+	defer func(fn string, srcs ArrayFlag, cache string) { FnJSON, Srcs, ScanCache = fn, srcs, cache }(FnJSON, Srcs, ScanCache)
+	FnJSON, Srcs = filepath.Join(dir, "til.json"), ArrayFlag{dir}
+	assert.Nil(b, ioutil.WriteFile(FnJSON, nil, 0644))
+	for f := 0; f < 200; f++ {
+		var s string
+		for n := 0; n < 100; n++ {
+			s += fmt.Sprintf("void f%d_%d( void ){\n\tTRICE8_1( \"file %d msg %d %%d\\n\", n );\n}\n", f, n, f, n)
+		}
+		assert.Nil(b, ioutil.WriteFile(filepath.Join(dir, fmt.Sprint("f", f, ".c")), []byte(s), 0644))
+	}
+	assert.Nil(b, SubCmdUpdate(ioutil.Discard)) // assign IDs
+	for _, cache := range []string{"off", "auto"} {
+		b.Run("cache="+cache, func(b *testing.B) {
+			ScanCache = cache
+			assert.Nil(b, SubCmdUpdate(ioutil.Discard)) // fill cache
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				assert.Nil(b, SubCmdUpdate(ioutil.Discard))
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package id
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
@@ -13,7 +14,13 @@ import (
 
 func TestMain(m *testing.M) {
 	ExtendMacrosWithParamCount = true
+	cacheDir, err := ioutil.TempDir("", "cache") // the tests do not fill the user cache directory
+	if nil != err {
+		panic(err)
+	}
+	userCacheDir = func() (string, error) { return cacheDir, nil }
 	i := m.Run()
+	os.RemoveAll(cacheDir)
 	if i != 0 {
 		os.Exit(i)
 	}
@@ -71,7 +78,7 @@ func checkList(t *testing.T, sharedIDs bool, min, max TriceID, searchMethod stri
 	for _, x := range tt {
 		act0, _ := updateParamCountAndID0(os.Stdout, x.text, extend)
 		listModified := false
-		act, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, min, max, searchMethod, act0, lu, tflu, &listModified)
+		act, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, nil, min, max, searchMethod, act0, lu, tflu, &listModified)
 		assert.Equal(t, x.fileMod, fileModified)
 		assert.Equal(t, x.listMod, listModified)
 		assert.Equal(t, x.exp, act)
@@ -95,7 +102,7 @@ func checkList2(t *testing.T, sharedIDs bool, min, max TriceID, searchMethod str
 	for _, x := range tt {
 		act0, _ := updateParamCountAndID0(os.Stdout, x.text, extendMacroName)
 		listModified := false
-		act, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, min, max, searchMethod, act0, lu, tflu, &listModified)
+		act, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, nil, min, max, searchMethod, act0, lu, tflu, &listModified)
 		assert.Equal(t, x.fileMod, fileModified)
 		assert.Equal(t, x.listMod, listModified)
 		assert.Equal(t, x.exp, act)
@@ -133,7 +140,7 @@ func checkList3(t *testing.T, sharedIDs bool, min, max TriceID, searchMethod str
 	for _, x := range tt {
 		act0, _ := updateParamCountAndID0(os.Stdout, x.text, extendMacroName)
 		listModified := false
-		act, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, min, max, searchMethod, act0, lu, tflu, &listModified)
+		act, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, nil, min, max, searchMethod, act0, lu, tflu, &listModified)
 		assert.Equal(t, x.fileMod, fileModified)
 		assert.Equal(t, x.listMod, listModified)
 		assert.Equal(t, x.exp, act)
@@ -155,7 +162,7 @@ func checkList4(t *testing.T, sharedIDs bool, min, max TriceID, searchMethod str
 	for _, x := range tt {
 		act0, _ := updateParamCountAndID0(os.Stdout, x.text, extendMacroName)
 		listModified := false
-		_, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, min, max, searchMethod, act0, lu, tflu, &listModified)
+		_, fileModified := updateIDsUniqOrShared(os.Stdout, sharedIDs, nil, min, max, searchMethod, act0, lu, tflu, &listModified)
 		assert.Equal(t, x.fileMod, fileModified)
 		assert.Equal(t, x.listMod, listModified)
 	}
@@ -49,7 +49,7 @@ func NewDevice(w io.Writer, port, arguments string) *Device {
 	case "JLINK", "J-LINK":
 		p.Exec = "JLinkRTTLogger"
 		p.Lib = "JLinkARM"
-	case "STLINK, ST-LINK":
+	case "STLINK", "ST-LINK":
 		p.Exec = "stRttLogger"
 		p.Lib = "libusb-1.0"
 	}
@@ -0,0 +1,177 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package link
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rokath/trice/internal/tcp"
+)
+
+const (
+	// tclCommandEnd terminates each command and each response on the OpenOCD Tcl server port.
+	tclCommandEnd = 0x1a
+
+	// tclTimeout is the maximum wait time for an OpenOCD Tcl server response.
+	tclTimeout = 5 * time.Second
+)
+
+// OpenOCD is an RTT reader using a running OpenOCD (or pyOCD) instance instead of an RTT logger executable.
+//
+// During Open the RTT control block search and an RTT TCP server for the RTT channel are set up
+// with commands over the OpenOCD Tcl server port. Afterwards the RTT channel bytes are read directly from the RTT TCP server.
+// No executable and no temporary file are needed.
+type OpenOCD struct {
+	w            io.Writer
+	host         string // host is the OpenOCD host name or IP address.
+	tclPort      int    // tclPort is the OpenOCD Tcl server port. 0 means, that the RTT server is set up already.
+	dataPort     int    // dataPort is the TCP port of the RTT server delivering the RTT channel bytes.
+	channel      int    // channel is the RTT channel number.
+	searchAddr   string // searchAddr is the start address of the RTT control block search range.
+	searchSize   string // searchSize is the size of the RTT control block search range.
+	controlBlock string // controlBlock is the RTT control block ID.
+	*tcp.Client
+}
+
+// NewOpenOCD creates an RTT reader instance for an OpenOCD RTT server.
+//
+// arguments is a space separated list of options:
+//
+//	-host name: OpenOCD host, default "localhost".
+//	-tclPort n: OpenOCD Tcl server port, default 6666. Use 0, if the RTT server is set up already, for example by pyOCD or an OpenOCD config file.
+//	-dataPort n: RTT server TCP port, default 19021.
+//	-RTTChannel n: RTT channel, default 0.
+//	-RTTSearchRanges addr_size: RTT control block search range, default "0x20000000_0x1000".
+//	-RTTControlBlock id: RTT control block ID, default "SEGGER RTT".
+//
+// Other options, like "-Device", are ignored to allow the same args as for J-LINK.
+func NewOpenOCD(w io.Writer, arguments string) (p *OpenOCD, err error) {
+	p = &OpenOCD{
+		w:            w,
+		host:         "localhost",
+		tclPort:      6666,
+		dataPort:     19021,
+		searchAddr:   "0x20000000",
+		searchSize:   "0x1000",
+		controlBlock: "SEGGER RTT",
+	}
+	args := strings.Fields(arguments)
+	for i := 0; i < len(args); i++ {
+		var v string
+		if i+1 < len(args) {
+			v = args[i+1]
+		} else if isOpenOCDOption(args[i]) {
+			return p, fmt.Errorf("OpenOCD option %s without value", args[i])
+		}
+		switch args[i] {
+		case "-host":
+			p.host = v
+		case "-tclPort":
+			p.tclPort, err = strconv.Atoi(v)
+		case "-dataPort":
+			p.dataPort, err = strconv.Atoi(v)
+		case "-RTTChannel":
+			p.channel, err = strconv.Atoi(v)
+		case "-RTTSearchRanges":
+			r := strings.Split(v, "_")
+			if 2 != len(r) {
+				err = fmt.Errorf("expecting -RTTSearchRanges like 0x20000000_0x1000, got %s", v)
+				break
+			}
+			p.searchAddr, p.searchSize = r[0], r[1]
+		case "-RTTControlBlock":
+			p.controlBlock = strings.ReplaceAll(v, "_", " ")
+		default:
+			continue
+		}
+		if nil != err {
+			return
+		}
+		i++ // value consumed
+	}
+	p.Client = tcp.NewClient(w, net.JoinHostPort(p.host, strconv.Itoa(p.dataPort)))
+	if Verbose {
+		fmt.Fprintln(w, "OpenOCD RTT channel", p.channel, "over", net.JoinHostPort(p.host, strconv.Itoa(p.tclPort)), "and data port", p.dataPort)
+	}
+	return
+}
+
+// isOpenOCDOption returns true, if s is an option evaluated by NewOpenOCD.
+func isOpenOCDOption(s string) bool {
+	switch s {
+	case "-host", "-tclPort", "-dataPort", "-RTTChannel", "-RTTSearchRanges", "-RTTControlBlock":
+		return true
+	}
+	return false
+}
+
+// Open sets up the RTT server over the OpenOCD Tcl server port, if configured, and connects to the RTT server.
+func (p *OpenOCD) Open() error {
+	if 0 != p.tclPort {
+		if err := p.setup(); nil != err {
+			return err
+		}
+	}
+	return p.Client.Open()
+}
+
+// setup sends the RTT commands to the OpenOCD Tcl server.
+func (p *OpenOCD) setup() error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(p.host, strconv.Itoa(p.tclPort)), tcp.DialTimeout)
+	if nil != err {
+		return err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	cmds := []string{
+		fmt.Sprintf("rtt setup %s %s {%s}", p.searchAddr, p.searchSize, p.controlBlock),
+		"rtt start",
+		fmt.Sprintf("rtt server stop %d", p.dataPort), // allows a repeated Open, the response is not relevant
+		fmt.Sprintf("rtt server start %d %d", p.dataPort, p.channel),
+	}
+	for _, cmd := range cmds {
+		resp, err := tclCommand(conn, r, cmd)
+		if nil != err {
+			return err
+		}
+		if Verbose {
+			fmt.Fprintln(p.w, cmd, "->", resp)
+		}
+		if strings.HasPrefix(cmd, "rtt server stop") {
+			continue
+		}
+		if isTclError(resp) {
+			return fmt.Errorf("OpenOCD command '%s' failed: %s", cmd, resp)
+		}
+	}
+	return nil
+}
+
+// tclCommand sends cmd over conn to the OpenOCD Tcl server and returns the response read with r.
+func tclCommand(conn net.Conn, r *bufio.Reader, cmd string) (string, error) {
+	if err := conn.SetDeadline(time.Now().Add(tclTimeout)); nil != err {
+		return "", err
+	}
+	if _, err := conn.Write(append([]byte(cmd), tclCommandEnd)); nil != err {
+		return "", err
+	}
+	resp, err := r.ReadBytes(tclCommandEnd)
+	if nil != err {
+		return "", err
+	}
+	return string(bytes.TrimSpace(resp[:len(resp)-1])), nil
+}
+
+// isTclError returns true, if the OpenOCD response resp reports an error.
+func isTclError(resp string) bool {
+	s := strings.ToLower(resp)
+	return strings.HasPrefix(s, "error") || strings.Contains(s, "invalid command name") || strings.Contains(s, "failed")
+}
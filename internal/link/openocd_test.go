@@ -0,0 +1,141 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package link_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rokath/trice/internal/link"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOpenOCD is a local stand-in for an OpenOCD Tcl server and its RTT server.
+type fakeOpenOCD struct {
+	tcl, data net.Listener
+	mu        sync.Mutex
+	cmds      []string // cmds are the received Tcl commands
+}
+
+// newFakeOpenOCD starts the Tcl server answering each command with the result of answer and the RTT server sending b once.
+func newFakeOpenOCD(t *testing.T, b []byte, answer func(cmd string) string) *fakeOpenOCD {
+	p := &fakeOpenOCD{}
+	var err error
+	p.tcl, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	p.data, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		conn, err := p.tcl.Accept()
+		if nil != err {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			cmd, err := r.ReadString(0x1a)
+			if nil != err {
+				return
+			}
+			cmd = strings.TrimSuffix(cmd, "\x1a")
+			p.mu.Lock()
+			p.cmds = append(p.cmds, cmd)
+			p.mu.Unlock()
+			_, _ = conn.Write(append([]byte(answer(cmd)), 0x1a))
+		}
+	}()
+	go func() {
+		conn, err := p.data.Accept()
+		if nil != err {
+			return
+		}
+		_, _ = conn.Write(b)
+		_ = conn.Close()
+	}()
+	return p
+}
+
+// args returns the OPENOCD port args for p.
+func (p *fakeOpenOCD) args(more string) string {
+	return fmt.Sprint("-host 127.0.0.1 -tclPort ", p.tcl.Addr().(*net.TCPAddr).Port, " -dataPort ", p.data.Addr().(*net.TCPAddr).Port, " ", more)
+}
+
+func (p *fakeOpenOCD) close() {
+	_ = p.tcl.Close()
+	_ = p.data.Close()
+}
+
+func TestOpenOCD(t *testing.T) {
+	exp := []byte{2, 1, 1, 1, 3, 208, 7, 1, 0}
+	srv := newFakeOpenOCD(t, exp, func(string) string { return "" })
+	defer srv.close()
+	var out bytes.Buffer
+	p, err := link.NewOpenOCD(&out, srv.args("-Device STM32F030R8 -RTTChannel 1 -RTTSearchRanges 0x20001000_0x800"))
+	assert.Nil(t, err)
+	assert.Nil(t, p.Open())
+	act, err := ioutil.ReadAll(p)
+	assert.NotNil(t, err) // connection lost at the end
+	assert.Equal(t, exp, act)
+	assert.Nil(t, p.Close())
+
+	dataPort := srv.data.Addr().(*net.TCPAddr).Port
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Equal(t, []string{
+		"rtt setup 0x20001000 0x800 {SEGGER RTT}",
+		"rtt start",
+		fmt.Sprint("rtt server stop ", dataPort),
+		fmt.Sprint("rtt server start ", dataPort, " 1"),
+	}, srv.cmds)
+}
+
+func TestOpenOCDSetupError(t *testing.T) {
+	srv := newFakeOpenOCD(t, nil, func(cmd string) string {
+		if "rtt start" == cmd {
+			return "Error: control block not found"
+		}
+		return ""
+	})
+	defer srv.close()
+	var out bytes.Buffer
+	p, err := link.NewOpenOCD(&out, srv.args(""))
+	assert.Nil(t, err)
+	err = p.Open()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "control block not found")
+}
+
+func TestOpenOCDWithoutTclPort(t *testing.T) {
+	exp := []byte{1, 2, 3}
+	srv := newFakeOpenOCD(t, exp, nil)
+	defer srv.close()
+	var out bytes.Buffer
+	p, err := link.NewOpenOCD(&out, srv.args("-tclPort 0"))
+	assert.Nil(t, err)
+	assert.Nil(t, p.Open())
+	act, _ := ioutil.ReadAll(p)
+	assert.Equal(t, exp, act)
+	assert.Nil(t, p.Close())
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Nil(t, srv.cmds)
+}
+
+func TestNewOpenOCDBadArgs(t *testing.T) {
+	var out bytes.Buffer
+	_, err := link.NewOpenOCD(&out, "-RTTSearchRanges 0x20000000")
+	assert.NotNil(t, err)
+	_, err = link.NewOpenOCD(&out, "-dataPort x")
+	assert.NotNil(t, err)
+	_, err = link.NewOpenOCD(&out, "-RTTChannel 1 -dataPort")
+	assert.NotNil(t, err)
+	_, err = link.NewOpenOCD(&out, "-RTTChannel 1 -Device")
+	assert.Nil(t, err) // other options are ignored
+}
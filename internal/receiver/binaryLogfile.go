@@ -0,0 +1,87 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package receiver
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rokath/trice/pkg/msg"
+)
+
+var (
+	// BinaryLogfileName is the filename for the raw input bytes capture. "off" or "none" inhibits capturing.
+	BinaryLogfileName = "off"
+
+	// DefaultBinaryLogfileName is the pattern for the default binary logfile name. The timestamp is replaced with the actual time.
+	DefaultBinaryLogfileName = "2006-01-02_1504-05_trice.bin"
+)
+
+// OpenBinaryLogfile opens the binary logfile fn for appending and returns its handle.
+// If fn is "off" or "none", nil is returned.
+// If fn is "auto", DefaultBinaryLogfileName with the actual time is used as filename.
+func OpenBinaryLogfile(w io.Writer, verbose bool, fn string) (f *os.File, err error) {
+	if "none" == fn || "off" == fn {
+		return
+	}
+	if "auto" == fn {
+		fn = DefaultBinaryLogfileName
+	}
+	if DefaultBinaryLogfileName == fn {
+		fn = time.Now().Format(fn) // replace timestamp in default binary logfile name
+	}
+	f, err = os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if nil == err && verbose {
+		fmt.Fprintf(w, "Writing raw input bytes to binary logfile %s...\n", fn)
+	}
+	return
+}
+
+// matchFileNameUnsafe matches the characters not used in port names inside file names.
+var matchFileNameUnsafe = regexp.MustCompile(`[^0-9A-Za-z._-]+`)
+
+// PortBinaryLogfileName returns the binary logfile name for port, if several ports are captured at once.
+// The port name is inserted in front of the file extension of fn, like "capture_COM3.bin" for fn "capture.bin".
+// Characters like ':' or '/' in the port name are replaced by '_'. If fn is "off" or "none", it is returned unchanged.
+// If fn is "auto", DefaultBinaryLogfileName with the actual time is used.
+func PortBinaryLogfileName(fn, port string) string {
+	if "none" == fn || "off" == fn {
+		return fn
+	}
+	if "auto" == fn || DefaultBinaryLogfileName == fn {
+		fn = time.Now().Format(DefaultBinaryLogfileName)
+	}
+	ext := filepath.Ext(fn)
+	return fn[:len(fn)-len(ext)] + "_" + strings.Trim(matchFileNameUnsafe.ReplaceAllString(port, "_"), "_") + ext
+}
+
+// binaryLogger is a ReadCloser copying all read bytes into a binary logfile.
+type binaryLogger struct {
+	r  io.ReadCloser
+	bl io.Writer
+}
+
+// NewBinaryLogger returns a ReadCloser `in` which is internally using reader `from`.
+// All bytes read with `in` are written additionally to bl, so a session can be replayed later using the "FILE" port.
+// Closing `in` closes `from` but not bl.
+func NewBinaryLogger(bl io.Writer, from io.ReadCloser) (in io.ReadCloser) {
+	return &binaryLogger{from, bl}
+}
+
+func (p *binaryLogger) Read(buf []byte) (count int, err error) {
+	count, err = p.r.Read(buf)
+	if 0 < count {
+		_, e := p.bl.Write(buf[:count])
+		msg.OnErr(e) // a capture problem should not end the log session
+	}
+	return
+}
+
+// Close closes the internal reader.
+func (p *binaryLogger) Close() error { return p.r.Close() }
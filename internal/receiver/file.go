@@ -0,0 +1,84 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package receiver
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// followPollInterval is the wait time before checking a followed file again for new bytes.
+const followPollInterval = 100 * time.Millisecond
+
+// fileFollower is a ReadCloser reading a file which is possibly still growing, like `tail -f`.
+type fileFollower struct {
+	f    *os.File
+	done chan struct{} // done is closed on Close to end a waiting Read.
+}
+
+// newFileFollower returns a ReadCloser for f, which does not return io.EOF but waits for more bytes.
+func newFileFollower(f *os.File) io.ReadCloser {
+	return &fileFollower{f, make(chan struct{})}
+}
+
+// Read blocks until at least one byte is read from the followed file, an error other than io.EOF occurs, or Close is called.
+// After Close Read returns io.EOF.
+func (p *fileFollower) Read(buf []byte) (n int, err error) {
+	for {
+		select {
+		case <-p.done:
+			return 0, io.EOF
+		default:
+		}
+		n, err = p.f.Read(buf)
+		if 0 < n || (nil != err && io.EOF != err) {
+			return
+		}
+		select {
+		case <-p.done:
+			return 0, io.EOF
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// Close ends following and closes the file.
+func (p *fileFollower) Close() error {
+	select {
+	case <-p.done: // already closed
+		return nil
+	default:
+		close(p.done)
+	}
+	return p.f.Close()
+}
+
+// fileEnd is a ReadCloser for a not followed file. It returns io.EOF also on a Read without bytes and without error,
+// so a reader loop ends at the end of the data, like with special files not reporting io.EOF.
+type fileEnd struct {
+	io.ReadCloser
+}
+
+// Read reads from the file and returns io.EOF, when no more bytes are available.
+func (p fileEnd) Read(buf []byte) (n int, err error) {
+	n, err = p.ReadCloser.Read(buf)
+	if 0 == n && nil == err && 0 < len(buf) {
+		err = io.EOF
+	}
+	return
+}
+
+// newFileReadCloser opens the file fn for reading.
+// If follow is true, the returned ReadCloser waits for appended bytes instead of returning io.EOF. Otherwise it returns io.EOF at the end of the data.
+func newFileReadCloser(fn string, follow bool) (r io.ReadCloser, err error) {
+	f, err := os.Open(fn)
+	if nil != err {
+		return
+	}
+	if follow {
+		return newFileFollower(f), nil
+	}
+	return fileEnd{f}, nil
+}
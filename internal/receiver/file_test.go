@@ -0,0 +1,37 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package receiver
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+// noBytes is a Reader returning neither bytes nor an error, after its data are read.
+type noBytes struct {
+	io.Reader
+}
+
+func (p noBytes) Read(buf []byte) (int, error) {
+	n, err := p.Reader.Read(buf)
+	if io.EOF == err {
+		err = nil
+	}
+	return n, err
+}
+
+func TestFileEnd(t *testing.T) {
+	r := fileEnd{ioutil.NopCloser(noBytes{strings.NewReader("ab")})}
+	b := make([]byte, 10)
+	n, err := r.Read(b)
+	assert.Nil(t, err)
+	assert.Equal(t, "ab", string(b[:n]))
+	n, err = r.Read(b)
+	assert.Equal(t, 0, n)
+	assert.True(t, io.EOF == err)
+}
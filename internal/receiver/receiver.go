@@ -38,11 +38,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"strings"
 	"unicode"
 
 	"github.com/rokath/trice/internal/com"
 	"github.com/rokath/trice/internal/link"
+	"github.com/rokath/trice/internal/tcp"
 	"github.com/rokath/trice/pkg/msg"
 )
 
@@ -55,8 +57,14 @@ var (
 
 	// PortArguments are the trice receiver device specific arguments.
 	PortArguments string
+
+	// Follow if set, keeps a "FILE" port open at the file end and waits for appended bytes like `tail -f`.
+	Follow bool
 )
 
+// tcpPrefix starts a port name like "TCP:localhost:19021".
+const tcpPrefix = "TCP:"
+
 // spaceStringsBuilder returns str without whitespaces.
 //
 // Code cpoied from https://stackoverflow.com/questions/32081808/strip-all-whitespace-from-a-string
@@ -113,7 +121,19 @@ func scanBytes(s string) (buf []byte) {
 // When port is "BUFFER", args is expected to be a decimal byte sequence in the same format as for example coming from one of the other ports.
 // When port is "JLINK" args contains JLinkRTTLogger.exe specific parameters described inside UM08001_JLink.pdf.
 // When port is "STLINK" args has the same format as for "JLINK"
+// When port is "OPENOCD" args contains the OpenOCD RTT server parameters described at link.NewOpenOCD.
+// When port is "TCP:host:port" a TCP connection to host:port is established and args is ignored.
+// When port is "FILE", args is expected to be the name of a file containing a recorded binary trice stream.
+// When port is "STDIN", the binary trice stream is read from standard input and args is ignored.
 func NewReadCloser(w io.Writer, verbose bool, port, args string) (r io.ReadCloser, err error) {
+	if strings.HasPrefix(port, tcpPrefix) {
+		c := tcp.NewClient(w, port[len(tcpPrefix):])
+		if e := c.Open(); nil != e {
+			err = fmt.Errorf("can not open %s: %v", port, e)
+		}
+		r = c
+		return
+	}
 	switch port {
 	case "JLINK", "STLINK", "J-LINK", "ST-LINK":
 		l := link.NewDevice(w, port, args)
@@ -122,6 +142,17 @@ func NewReadCloser(w io.Writer, verbose bool, port, args string) (r io.ReadClose
 		}
 		r = l
 		return
+	case "OPENOCD":
+		var o *link.OpenOCD
+		o, err = link.NewOpenOCD(w, args)
+		if nil != err {
+			return
+		}
+		if e := o.Open(); nil != e {
+			err = fmt.Errorf("can not open %s with args %s: %v", port, args, e)
+		}
+		r = o
+		return
 	case "DUMP":
 		var buf []byte
 		buf, err = scanHexDump(args)
@@ -131,6 +162,15 @@ func NewReadCloser(w io.Writer, verbose bool, port, args string) (r io.ReadClose
 		buf := scanBytes(args)
 		r = ioutil.NopCloser(bytes.NewBuffer(buf))
 		return
+	case "FILE":
+		r, err = newFileReadCloser(args, Follow)
+		if nil != err {
+			err = fmt.Errorf("can not open %s %s: %v", port, args, err)
+		}
+		return
+	case "STDIN":
+		r = ioutil.NopCloser(os.Stdin)
+		return
 	default: // assuming serial port
 		var c com.COMport   // interface type
 		if "TARM" == args { // for comparing dynamic behaviour
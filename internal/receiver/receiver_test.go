@@ -5,10 +5,16 @@ package receiver_test
 
 import (
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rokath/trice/internal/receiver"
+	"github.com/rokath/trice/internal/tcp"
+	"github.com/rokath/trice/pkg/tst"
 	"github.com/tj/assert"
 )
 
@@ -26,3 +32,119 @@ func Test1(t *testing.T) {
 	assert.True(t, io.EOF == err)
 	assert.Nil(t, rc.Close())
 }
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		conn, err := ln.Accept()
+		if nil != err {
+			return
+		}
+		_, _ = conn.Write([]byte{7})
+		_ = conn.Close()
+		_ = ln.Close()
+	}()
+	verbose := false
+	rc, err := receiver.NewReadCloser(os.Stdout, verbose, "TCP:"+ln.Addr().String(), "")
+	assert.Nil(t, err)
+	b := make([]byte, 100)
+	n, err := rc.Read(b)
+	assert.Nil(t, err)
+	assert.True(t, 1 == n)
+	assert.True(t, 7 == b[0])
+	n, err = rc.Read(b)
+	assert.True(t, 0 == n)
+	assert.True(t, tcp.ErrConnectionLost == err)
+	assert.Nil(t, rc.Close())
+}
+
+func TestFILE(t *testing.T) {
+	fn := tst.TempFileName("TestFILE*.bin")
+	defer os.Remove(fn)
+	assert.Nil(t, ioutil.WriteFile(fn, []byte{1, 2, 3}, 0644))
+	rc, err := receiver.NewReadCloser(os.Stdout, false, "FILE", fn)
+	assert.Nil(t, err)
+	b := make([]byte, 100)
+	n, err := rc.Read(b)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, b[:n])
+	n, err = rc.Read(b)
+	assert.True(t, 0 == n)
+	assert.True(t, io.EOF == err)
+	assert.Nil(t, rc.Close())
+}
+
+func TestFILEFollow(t *testing.T) {
+	fn := tst.TempFileName("TestFILEFollow*.bin")
+	defer os.Remove(fn)
+	assert.Nil(t, ioutil.WriteFile(fn, []byte{1, 2, 3}, 0644))
+	receiver.Follow = true
+	defer func() { receiver.Follow = false }()
+	rc, err := receiver.NewReadCloser(os.Stdout, false, "FILE", fn)
+	assert.Nil(t, err)
+	b := make([]byte, 100)
+	n, err := rc.Read(b)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, b[:n])
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		f, err := os.OpenFile(fn, os.O_APPEND|os.O_WRONLY, 0644)
+		if nil != err {
+			return
+		}
+		_, _ = f.Write([]byte{4, 5})
+		_ = f.Close()
+	}()
+	n, err = rc.Read(b) // waits for the appended bytes
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{4, 5}, b[:n])
+	assert.Nil(t, rc.Close())
+	n, err = rc.Read(b)
+	assert.True(t, 0 == n)
+	assert.True(t, io.EOF == err)
+}
+
+func TestFILENotExisting(t *testing.T) {
+	_, err := receiver.NewReadCloser(os.Stdout, false, "FILE", "notExistingFile.bin")
+	assert.NotNil(t, err)
+}
+
+func TestBinaryLogger(t *testing.T) {
+	fn := tst.TempFileName("TestBinaryLogger*.bin")
+	defer os.Remove(fn)
+	bl, err := receiver.OpenBinaryLogfile(os.Stdout, false, fn)
+	assert.Nil(t, err)
+	rc, err := receiver.NewReadCloser(os.Stdout, false, "BUFFER", "7 8 0")
+	assert.Nil(t, err)
+	rc = receiver.NewBinaryLogger(bl, rc)
+	b := make([]byte, 100)
+	n, err := rc.Read(b)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{7, 8, 0}, b[:n])
+	assert.Nil(t, rc.Close())
+	assert.Nil(t, bl.Close())
+
+	// replay
+	rc, err = receiver.NewReadCloser(os.Stdout, false, "FILE", fn)
+	assert.Nil(t, err)
+	n, err = rc.Read(b)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{7, 8, 0}, b[:n])
+	assert.Nil(t, rc.Close())
+}
+
+func TestBinaryLogfileOff(t *testing.T) {
+	bl, err := receiver.OpenBinaryLogfile(os.Stdout, false, "off")
+	assert.Nil(t, err)
+	assert.Nil(t, bl)
+}
+
+func TestPortBinaryLogfileName(t *testing.T) {
+	assert.Equal(t, "off", receiver.PortBinaryLogfileName("off", "COM3"))
+	assert.Equal(t, "capture_COM3.bin", receiver.PortBinaryLogfileName("capture.bin", "COM3"))
+	assert.Equal(t, "logs/capture_TCP_192.168.1.7_2000", receiver.PortBinaryLogfileName("logs/capture", "TCP:192.168.1.7:2000"))
+	assert.Equal(t, "capture_dev_ttyUSB0.bin", receiver.PortBinaryLogfileName("capture.bin", "/dev/ttyUSB0"))
+	fn := receiver.PortBinaryLogfileName("auto", "COM3")
+	assert.True(t, strings.HasSuffix(fn, "_trice_COM3.bin"), fn)
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package tcp reads from a TCP endpoint.
+//
+// It is used when the trice bytes are forwarded over the network, for example by ser2net,
+// an OpenOCD RTT server or a gateway board. It makes no assumptions about the delivered data.
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// Verbose shows additional information if set true.
+	Verbose bool
+
+	// DialTimeout is the maximum time waiting for a connection.
+	DialTimeout = 3 * time.Second
+
+	// ErrConnectionLost is returned by Read when the remote endpoint closed the connection or the connection broke.
+	// The caller is expected to close the Client and to set up a new one.
+	ErrConnectionLost = errors.New("tcp connection lost")
+)
+
+// Client is a TCP trice receiver.
+type Client struct {
+	w       io.Writer
+	address string // host:port
+	conn    net.Conn
+	mu      sync.Mutex // mu protects conn against concurrent Close calls.
+}
+
+// NewClient creates an instance of a TCP trice receiver for address, which is expected in the form "host:port".
+func NewClient(w io.Writer, address string) *Client {
+	p := &Client{
+		w:       w,
+		address: address,
+	}
+	if Verbose {
+		fmt.Fprintln(w, "NewClient:", address)
+	}
+	return p
+}
+
+// Open dials the TCP endpoint.
+func (p *Client) Open() (err error) {
+	if Verbose {
+		fmt.Fprintln(p.w, "dialing", p.address, "...")
+	}
+	conn, err := net.DialTimeout("tcp", p.address, DialTimeout)
+	if nil != err {
+		return
+	}
+	p.mu.Lock()
+	p.conn = conn
+	p.mu.Unlock()
+	if Verbose {
+		fmt.Fprintln(p.w, "...", p.address, "connected.")
+	}
+	return
+}
+
+// Read blocks until (at least) one byte is received or an error occurs.
+// When the connection is closed by the remote side or breaks, the connection is closed
+// and ErrConnectionLost is returned, also on all following calls.
+func (p *Client) Read(buf []byte) (n int, err error) {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	if nil == conn {
+		return 0, ErrConnectionLost
+	}
+	n, err = conn.Read(buf)
+	if nil != err {
+		if Verbose {
+			fmt.Fprintln(p.w, p.address, err)
+		}
+		_ = p.Close()
+		err = ErrConnectionLost
+	}
+	return
+}
+
+// Close releases the connection. It is safe to call Close several times.
+func (p *Client) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if nil == p.conn {
+		return nil
+	}
+	if Verbose {
+		fmt.Fprintln(p.w, "Closing TCP connection to", p.address)
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package tcp_test is a blackbox test.
+package tcp_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/rokath/trice/internal/tcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveOnce accepts one connection on a local listener, writes b and closes the connection.
+func serveOnce(t *testing.T, b []byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if nil != err {
+			return
+		}
+		_, _ = conn.Write(b)
+		_ = conn.Close()
+	}()
+	return ln.Addr().String()
+}
+
+func TestClientReadAndConnectionLost(t *testing.T) {
+	exp := []byte{2, 1, 1, 1, 3, 208, 7, 1, 0}
+	var out bytes.Buffer
+	p := tcp.NewClient(&out, serveOnce(t, exp))
+	assert.Nil(t, p.Open())
+	act := make([]byte, 0, 100)
+	b := make([]byte, 100)
+	var err error
+	for nil == err {
+		var n int
+		n, err = p.Read(b)
+		act = append(act, b[:n]...)
+	}
+	assert.Equal(t, exp, act)
+	assert.Equal(t, tcp.ErrConnectionLost, err)
+	n, err := p.Read(b)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, tcp.ErrConnectionLost, err)
+	assert.Nil(t, p.Close())
+	assert.Nil(t, p.Close())
+}
+
+func TestClientOpenFails(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close()) // nobody listens anymore
+	var out bytes.Buffer
+	p := tcp.NewClient(&out, addr)
+	assert.NotNil(t, p.Open())
+	assert.Equal(t, "", out.String())
+}
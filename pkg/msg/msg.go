@@ -145,9 +145,40 @@ const (
 )
 
 var (
-	logFatalf = log.Fatalf // https://stackoverflow.com/questions/30688554/how-to-test-go-function-containing-log-fatal/45380105
+	logFatalf = fatalf // https://stackoverflow.com/questions/30688554/how-to-test-go-function-containing-log-fatal/45380105
+
+	exitMu    sync.Mutex
+	exitFuncs = make(map[*func()]bool) // exitFuncs are called before the Fatal functions end the program.
 )
 
+// AtExit registers f to be called before a Fatal function ends the program with os.Exit, which skips deferred calls.
+// This way resources like lock files are released also on fatal errors. The returned function unregisters f.
+func AtExit(f func()) (remove func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitFuncs[&f] = true
+	return func() {
+		exitMu.Lock()
+		delete(exitFuncs, &f)
+		exitMu.Unlock()
+	}
+}
+
+// runAtExit calls the functions registered with AtExit.
+func runAtExit() {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	for f := range exitFuncs {
+		(*f)()
+	}
+}
+
+// fatalf calls the AtExit functions and log.Fatalf afterwards.
+func fatalf(format string, v ...interface{}) {
+	runAtExit()
+	log.Fatalf(format, v...)
+}
+
 func fmtMessage(pc uintptr, fn string, line int, ok bool, err error) {
 	funcName := runtime.FuncForPC(pc).Name()
 	fileName := filepath.Base(fn)
@@ -86,3 +86,17 @@ func TestFatalInfoOnFalse(t *testing.T) {
 	e = e[:0] // clear
 
 }
+
+func TestAtExit(t *testing.T) {
+	var n int
+	remove := AtExit(func() { n++ })
+	runAtExit()
+	if n != 1 {
+		t.Errorf("expected one call, actual %v", n)
+	}
+	remove()
+	runAtExit()
+	if n != 1 {
+		t.Errorf("expected no further call, actual %v", n)
+	}
+}
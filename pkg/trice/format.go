@@ -0,0 +1,55 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+package trice
+
+import (
+	"regexp"
+
+	"github.com/rokath/trice/internal/id"
+)
+
+const (
+	// patNextFormatUSpecifier is a regex to find next format u specifier in a string
+	// It does also match %%u positions! so an additional check must follow.
+	patNextFormatUSpecifier = `(?:%[0-9]*u)`
+
+	// patNextFormatXSpecifier is a regex to find next format x specifier in a string
+	// It does also match %%x positions! so an additional check must follow.
+	patNextFormatXSpecifier = `(?:%[0-9]*(l|o|O|x|X|b))`
+)
+
+var (
+	matchNextFormatSpecifier  = regexp.MustCompile(id.PatNextFormatSpecifier) // the same specifiers as the check sub-command
+	matchNextFormatUSpecifier = regexp.MustCompile(patNextFormatUSpecifier)
+	matchNextFormatXSpecifier = regexp.MustCompile(patNextFormatXSpecifier)
+)
+
+// uReplaceN checks all format specifier in i and replaces %nu with %nd and returns that result as o.
+//
+// If a replacement took place on position k u[k] is true. Afterwards len(u) is amount of found format specifiers.
+// Additionall, if unsigned is true, for FormatX specifiers u[k] is also true.
+func uReplaceN(i string, unsigned bool) (o string, u []bool) {
+	o = i
+	s := i
+	var offset int
+	for {
+		loc := matchNextFormatSpecifier.FindStringIndex(s)
+		if nil == loc { // no (more) fm found
+			return
+		}
+		offset += loc[1] // track position
+		fm := s[loc[0]:loc[1]]
+		locU := matchNextFormatUSpecifier.FindStringIndex(fm)
+		locX := matchNextFormatXSpecifier.FindStringIndex(fm)
+		if nil != locU { // a %nu found
+			o = o[:offset-1] + "d" + o[offset:] // replace %nu -> %nd
+			u = append(u, true)
+		} else if nil != locX && unsigned { // a %nx, %nX or, %no, %nO or %nb found
+			u = append(u, true) // no negative values
+		} else { // keep sign
+			u = append(u, false)
+		}
+		s = i[offset:] // remove processed part
+	}
+}
@@ -0,0 +1,564 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package trice decodes COBS encoded trice byte streams into typed trice events.
+//
+// It is the library API for embedding trice decoding into other Go programs, like test harnesses.
+// All decoding state is kept inside a Decoder, so several decoders can run in one process independently.
+package trice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dim13/cobs"
+	"github.com/rokath/trice/internal/id"
+)
+
+const (
+	// headSize is 4; each trice message starts with a head of 4 bytes.
+	headSize = 4
+
+	// Hints is the help information in case of errors.
+	Hints = "att:Hints:Baudrate? Overflow? Encoding? Interrupt? til.json? Password?"
+)
+
+// TriceID is the trice ID referencing to a TriceFmt.
+type TriceID = id.TriceID
+
+// TriceFmt contains the trice type and the format string of a trice.
+type TriceFmt = id.TriceFmt
+
+// TriceIDLookUp is the ID-to-TriceFmt info translation map, usually read from a til.json file.
+type TriceIDLookUp = id.TriceIDLookUp
+
+// NewLookUp returns a TriceIDLookUp generated from the JSON content of a til.json file.
+func NewLookUp(til []byte) (TriceIDLookUp, error) {
+	lu := make(TriceIDLookUp)
+	if err := lu.FromJSON(til); nil != err {
+		return nil, err
+	}
+	lu.AddFmtCount(ioutil.Discard)
+	return lu, nil
+}
+
+// Options control a Decoder. The zero value decodes an unencrypted little endian stream.
+type Options struct {
+	BigEndian       bool                      // BigEndian is true for a big endian encoded trice stream.
+	Unsigned        bool                      // Unsigned, if true, forces hex, octal and bin values to be unsigned.
+	DefaultBitWidth string                    // DefaultBitWidth is the bit width of trice macros without bit width in their names. "" means "32".
+	Decrypt         func(dst, src []byte) int // Decrypt, if not nil, is used to decrypt each decoded COBS package in place.
+	LutMutex        *sync.RWMutex             // LutMutex, if not nil, guards the look-up map against concurrent changes.
+	Debug           io.Writer                 // Debug, if not nil, receives hex dumps of the decoding steps.
+	OnPackage       func(raw []byte)          // OnPackage, if not nil, is called with each raw COBS package including its terminating 0.
+	Now             func() time.Time          // Now, if not nil, replaces time.Now for the Trice.PCTime value.
+}
+
+// Trice is a single decoded trice event.
+type Trice struct {
+	PCTime             time.Time     // PCTime is the reception time.
+	TargetTimestamp    uint32        // TargetTimestamp is valid only if HasTargetTimestamp is true.
+	HasTargetTimestamp bool          // HasTargetTimestamp is true, when the target sends timestamps.
+	ID                 TriceID       // ID is the trice ID.
+	Type               string        // Type is the trice type like "TRICE16_2".
+	Fmt                string        // Fmt is the format string as inside til.json.
+	Args               []interface{} // Args are the decoded parameter values.
+	Text               string        // Text is the rendered trice string.
+	Messages           []string      // Messages are diagnostic lines like "CYCLE:..." or "WARNING:...", each ending with a newline.
+	Valid              bool          // Valid is false, when only Messages are delivered, for example for an unknown ID.
+}
+
+// Decoder reads a COBS encoded trice byte stream and delivers Trice events.
+type Decoder struct {
+	in                    io.Reader     // in is the inner reader, which is used to get raw bytes
+	lut                   TriceIDLookUp // lut is the id look-up map for translation
+	opt                   Options       // opt holds the decoder options
+	iBuf                  []byte        // iBuf holds unprocessed (raw) bytes for interpretation.
+	b                     []byte        // b holds a single decoded COBS package, which can contain several trices.
+	cycle                 uint8         // cycle is the expected cycle counter value: c0...bf
+	initialCycle          bool          // initialCycle is a helper for the cycle counter automatic.
+	cycleCounter          bool          // cycleCounter is true after a cycle value other than 0xc0, so the target has a cycle counter.
+	modeDescriptor        uint32        // modeDescriptor is 0 for no target timestamps and 1 for target timestamps
+	targetTimestamp       uint32        // targetTimestamp is the last received target timestamp
+	targetTimestampExists bool          // targetTimestampExists is true after the first target timestamp
+	paramSpace            int           // paramSpace is the trice payload size after head
+	sLen                  int           // sLen is the string length for TRICE_S
+	trice                 TriceFmt      // trice is the actual received trice
+	pFmt                  string        // pFmt is the modified trice format string: %u -> %d
+	u                     []bool        // u are the modified format string positions: %u -> %d
+	args                  []interface{} // args are the decoded parameter values of the actual trice
+	stats                 Stats         // stats are the counters of the decoding goroutine
+	published             Stats         // published is a copy of stats for other goroutines, guarded by statsMutex
+	statsMutex            sync.Mutex    // statsMutex guards published
+}
+
+// Stats are the counters of a Decoder. They describe the link quality.
+type Stats struct {
+	Bytes        int `json:"bytes"`        // Bytes is the count of received bytes.
+	Packages     int `json:"packages"`     // Packages is the count of received COBS packages, valid or not.
+	Trices       int `json:"trices"`       // Trices is the count of decoded trices.
+	Errors       int `json:"errors"`       // Errors is the count of dropped invalid COBS packages and trices.
+	UnknownIDs   int `json:"unknownIDs"`   // UnknownIDs is the count of trices with an ID not inside the look-up map.
+	CycleGaps    int `json:"cycleGaps"`    // CycleGaps is the count of cycle counter mismatches, each meaning lost data.
+	TargetResets int `json:"targetResets"` // TargetResets is the count of detected target resets.
+}
+
+// Add returns the sums of the counters in s and x.
+func (s Stats) Add(x Stats) Stats {
+	s.Bytes += x.Bytes
+	s.Packages += x.Packages
+	s.Trices += x.Trices
+	s.Errors += x.Errors
+	s.UnknownIDs += x.UnknownIDs
+	s.CycleGaps += x.CycleGaps
+	s.TargetResets += x.TargetResets
+	return s
+}
+
+// NewDecoder returns a Decoder reading raw bytes from in and translating them with lut.
+func NewDecoder(in io.Reader, lut TriceIDLookUp, opt Options) *Decoder {
+	p := &Decoder{in: in, lut: lut, opt: opt}
+	p.iBuf = make([]byte, 0, 4096)
+	p.cycle = 0xc0 // start value
+	p.initialCycle = true
+	if "" == p.opt.DefaultBitWidth {
+		p.opt.DefaultBitWidth = "32"
+	}
+	if nil == p.opt.Now {
+		p.opt.Now = time.Now
+	}
+	return p
+}
+
+// SetInput allows switching the input stream to a different source while keeping the decoder state.
+func (p *Decoder) SetInput(in io.Reader) {
+	p.in = in
+}
+
+// Next returns the next decoded trice.
+//
+// When no complete COBS package is available, the inner reader error is returned, for example io.EOF.
+// Next can be called again after an io.EOF, when the inner reader delivers more data later.
+// Decoding problems do not cause an error. They are reported inside Trice.Messages and the affected data are dropped.
+// Invalid data never stop the decoding. They are counted, see Errors.
+func (p *Decoder) Next() (t Trice, err error) {
+	defer p.publish()
+	for len(p.b) < p.minTriceSize() { // last decoded COBS package exhausted
+		if 0 < len(p.b) {
+			t.addMessage("ERROR:package rest", p.b, "is too short for a trice - ignoring it")
+			p.b = p.b[:0]
+			p.stats.Errors++
+			return
+		}
+		var ok bool
+		ok, err = p.nextPackage(&t)
+		if !ok {
+			if 0 < len(t.Messages) {
+				err = nil // deliver messages first
+			}
+			return
+		}
+		if 0 < len(t.Messages) && len(p.b) < p.minTriceSize() {
+			return // deliver messages about a dropped package
+		}
+	}
+	t.PCTime = p.opt.Now()
+	if p.modeDescriptor == 1 {
+		p.targetTimestamp = p.readU32(p.b)
+		p.targetTimestampExists = true
+		p.b = p.b[4:] // drop target timestamp
+	}
+	t.TargetTimestamp = p.targetTimestamp
+	t.HasTargetTimestamp = p.targetTimestampExists
+	head := p.readU32(p.b)
+	p.checkCycle(&t, uint8(head))
+
+	p.paramSpace = int((0x0000FF00 & head) >> 6)
+	triceSize := headSize + p.paramSpace
+	t.ID = TriceID(uint16(head >> 16))
+	if len(p.b) < triceSize {
+		t.addMessage("ERROR:package len", len(p.b), "is <", triceSize, " - ignoring package", p.b)
+		p.b = p.b[:0]
+		p.stats.Errors++
+		return
+	}
+	if nil != p.opt.Debug {
+		fmt.Fprint(p.opt.Debug, "TRICE -> ")
+		dump(p.opt.Debug, p.b[:triceSize])
+	}
+	var ok bool
+	if nil != p.opt.LutMutex {
+		p.opt.LutMutex.RLock()
+	}
+	p.trice, ok = p.lut[t.ID]
+	if nil != p.opt.LutMutex {
+		p.opt.LutMutex.RUnlock()
+	}
+	if !ok {
+		t.Messages = append(t.Messages, fmt.Sprintln("WARNING:unknown ID ", t.ID, "- ignoring trice", p.b[:triceSize]))
+		t.Messages = append(t.Messages, fmt.Sprintln(Hints))
+		p.b = p.b[triceSize:]
+		p.stats.UnknownIDs++
+		return
+	}
+	p.b = p.b[headSize:] // drop used head info
+	p.args = nil
+	t.Text, t.Valid = p.sprintTrice()
+	if !t.Valid {
+		p.stats.Errors++
+		t.Messages = append(t.Messages, t.Text)
+		t.Text = ""
+	} else {
+		p.stats.Trices++
+		t.Type = p.trice.Type
+		t.Fmt = p.trice.Strg
+		t.Args = p.args
+	}
+	if len(p.b) < p.paramSpace {
+		t.addMessage("ERROR:ignoring data garbage")
+		p.b = p.b[:0]
+		p.stats.Errors++
+	} else {
+		p.b = p.b[p.paramSpace:] // drop param info
+	}
+	return
+}
+
+// Errors returns the count of invalid COBS packages and trices dropped so far.
+// Unknown IDs are not counted, because they result from a not matching ID list and not from invalid data.
+func (p *Decoder) Errors() int {
+	return p.Stats().Errors
+}
+
+// Stats returns the counters after the last Next call. Stats can be called concurrently to Next.
+func (p *Decoder) Stats() Stats {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	return p.published
+}
+
+// publish makes the actual counters visible for Stats.
+func (p *Decoder) publish() {
+	p.statsMutex.Lock()
+	p.published = p.stats
+	p.statsMutex.Unlock()
+}
+
+// minTriceSize returns the minimum byte count of a trice inside the actual COBS package.
+// With target timestamps each trice head is preceded by a 4 byte timestamp.
+func (p *Decoder) minTriceSize() int {
+	if p.modeDescriptor == 1 {
+		return 4 + headSize
+	}
+	return headSize
+}
+
+// addMessage appends a message line built from a and the hints line to t.Messages.
+func (t *Trice) addMessage(a ...interface{}) {
+	t.Messages = append(t.Messages, fmt.Sprintln(a...), fmt.Sprintln(Hints))
+}
+
+// checkCycle performs the cycle counter automatic & check and adds a message to t on a mismatch.
+func (p *Decoder) checkCycle(t *Trice, cycle uint8) {
+	if cycle == 0xc0 && p.cycle != 0xc0 && p.cycleCounter { // the cycle counter started again, a target without cycle counter sends always 0xc0
+		p.stats.TargetResets++
+	}
+	if cycle == 0xc0 && p.cycle != 0xc0 && p.initialCycle { // with cycle counter and seems to be a target reset
+		t.Messages = append(t.Messages, fmt.Sprintln("warning:   Target Reset?   "))
+		p.cycle = cycle + 1 // adjust cycle
+		p.initialCycle = false
+	}
+	if cycle == 0xc0 && p.cycle != 0xc0 && !p.initialCycle { // with cycle counter and seems to be a target reset
+		p.cycle = cycle + 1 // adjust cycle
+	}
+	if cycle == 0xc0 && p.cycle == 0xc0 && p.initialCycle { // with or without cycle counter and seems to be a target reset
+		p.cycle = cycle + 1 // adjust cycle
+		p.initialCycle = false
+	}
+	if cycle == 0xc0 && p.cycle == 0xc0 && !p.initialCycle { // with or without cycle counter and seems to be a normal case
+		p.cycle = cycle + 1 // adjust cycle
+	}
+	if cycle != 0xc0 { // with cycle counter and s.th. lost
+		if cycle != p.cycle { // no cycle check for 0xc0 to avoid messages on every target reset and when no cycle counter is active
+			t.Messages = append(t.Messages, fmt.Sprintln("CYCLE:", cycle, "not equal expected value", p.cycle, "- adjusting."))
+			p.stats.CycleGaps++
+			p.cycle = cycle // adjust cycle
+		}
+		p.initialCycle = false
+		p.cycleCounter = true
+		p.cycle++
+	}
+}
+
+// nextPackage reads with the inner reader a COBS encoded byte stream and decodes the next COBS package into p.b.
+//
+// When no terminating 0 is found in the incoming bytes, nextPackage returns false and the inner reader error.
+// Some arrived bytes are kept internally and concatenated with the following bytes in a next call.
+// Invalid packages are dropped with a message into t.
+func (p *Decoder) nextPackage(t *Trice) (ok bool, err error) {
+	index := bytes.IndexByte(p.iBuf, 0) // find terminating 0
+	if index == -1 {                    // p.iBuf has no complete COBS data, so try to read more input
+		bb := make([]byte, 1024) // intermediate buffer
+		m, err := p.in.Read(bb)  // use bb as bytes read buffer
+		p.stats.Bytes += m
+		p.iBuf = append(p.iBuf, bb[:m]...) // merge with leftovers
+		index = bytes.IndexByte(p.iBuf, 0) // find terminating 0
+		if index == -1 {                   // p.iBuf has no complete COBS data, so leave
+			return false, err
+		}
+	}
+	if nil != p.opt.OnPackage {
+		p.opt.OnPackage(p.iBuf[:index+1])
+	}
+	if nil != p.opt.Debug {
+		fmt.Fprint(p.opt.Debug, "COBS: ")
+		dump(p.opt.Debug, p.iBuf[:index+1])
+	}
+	p.b = cobs.Decode(p.iBuf[:index+1])
+	p.stats.Packages++
+	if 0 == len(p.b) && 1 < index { // only the encodings of an empty package have less than 2 bytes before the terminating 0
+		t.addMessage("ERROR:invalid COBS package", p.iBuf[:index+1], "- ignoring it")
+		p.iBuf = p.iBuf[index+1:]
+		p.stats.Errors++
+		return true, nil
+	}
+	p.iBuf = p.iBuf[index+1:] // step forward (next package data in p.iBuf now, if any)
+	n := len(p.b)
+	if n&3 != 0 { // decoded trice COBS packages have a multiple of 4 len
+		t.Messages = append(t.Messages, fmt.Sprintln("ERROR:Decoded trice COBS package has not expected  multiple of 4 len. The len is", n, p.b))
+		p.b = p.b[:0]
+		p.stats.Errors++
+		return true, nil
+	}
+	if nil != p.opt.Debug {
+		fmt.Fprint(p.opt.Debug, "-> PKG:  ")
+		dump(p.opt.Debug, p.b)
+	}
+	if nil != p.opt.Decrypt { // encrypted
+		p.opt.Decrypt(p.b, p.b)
+		if nil != p.opt.Debug {
+			fmt.Fprint(p.opt.Debug, "-> DEC:  ")
+			dump(p.opt.Debug, p.b)
+		}
+	}
+	if n >= 4 {
+		p.modeDescriptor = p.readU32(p.b)
+		p.b = p.b[4:] // drop COBS package descriptor
+	}
+	return true, nil
+}
+
+// dump prints the byte slice as hex in one line
+func dump(w io.Writer, b []byte) {
+	for _, x := range b {
+		fmt.Fprintf(w, "%02x ", x)
+	}
+	fmt.Fprintln(w, "")
+}
+
+// sprintTrice returns the trice string and true or an appropriate message and false.
+func (p *Decoder) sprintTrice() (s string, ok bool) {
+	paramSpace := -1 // TRICE_S has a variable parameter space
+	if p.trice.Type == "TRICE_S" {
+		if len(p.b) < 4 {
+			return fmt.Sprintln("err:TRICE_S without string length - ignoring data", p.b) + fmt.Sprintln(Hints), false
+		}
+		n := p.readU32(p.b)
+		if uint64(n) > uint64(len(p.b)) {
+			return fmt.Sprintln("err:TRICE_S len", n, "exceeds package - ignoring data", p.b) + fmt.Sprintln(Hints), false
+		}
+		p.sLen = int(n)
+		paramSpace = (p.sLen + 7) & ^3 // +4 for 4 bytes sLen, +3^3 is alignment to 4
+	}
+
+	p.pFmt, p.u = uReplaceN(p.trice.Strg, p.opt.Unsigned)
+
+	var triceType string
+
+	if strings.HasPrefix(p.trice.Type, "TRICE_") {
+		triceType = "TRICE" + p.opt.DefaultBitWidth + "_" + p.trice.Type[6:]
+	}
+
+	if p.trice.Type == "TRICE" {
+		triceType = "TRICE0"
+	}
+
+	if p.trice.Type == "TRICE8" || p.trice.Type == "TRICE16" || p.trice.Type == "TRICE32" || p.trice.Type == "TRICE64" {
+		p.trice.Type = fmt.Sprintf(p.trice.Type+"_%d", len(p.u)) // append count
+	}
+
+	for _, f := range functionPtrList {
+		if f.triceType == p.trice.Type || f.triceType == triceType {
+			if f.paramSpace != -1 {
+				paramSpace = f.paramSpace
+			}
+			if paramSpace == p.paramSpace {
+				if len(p.b) < p.paramSpace {
+					return fmt.Sprintln("err:len(p.b) =", len(p.b), "< p.paramSpace = ", p.paramSpace, "- ignoring package", p.b[:len(p.b)]) + fmt.Sprintln(Hints), false
+				}
+				return f.triceFn(p, f.bitWidth, f.paramCount)
+			}
+			return fmt.Sprintln("err:trice.Type", p.trice.Type, ": s.paramSpace", paramSpace, "!= p.paramSpace", p.paramSpace, "- ignoring data", p.b[:p.paramSpace]) + fmt.Sprintln(Hints), false
+		}
+	}
+	return fmt.Sprintln("err:Unknown trice.Type:", p.trice.Type, "and", triceType, "not matching - ignoring trice data", p.b[:p.paramSpace]) + fmt.Sprintln(Hints), false
+}
+
+// triceTypeFn is the type for functionPtrList elements.
+type triceTypeFn struct {
+	triceType  string                                               // triceType describes if parameters, the parameter bit width or it the parameter is a string
+	triceFn    func(p *Decoder, bitwidth, count int) (string, bool) // triceFn performs the conversion to the output string.
+	paramSpace int                                                  // paramSpace is the count of bytes allocated for the parameters. -1 means variable.
+	bitWidth   int                                                  // bitWidth is the individual parameter width.
+	paramCount int                                                  // paramCount is the amount pf parameters for the format string, which must match the count of format specifiers.
+}
+
+// functionPtrList is a function pointer list.
+var functionPtrList = [...]triceTypeFn{
+	{"TRICE_S", (*Decoder).triceS, -1, 0, 0},
+	{"TRICE32_0", (*Decoder).trice0, 0, 0, 0},
+	{"TRICE0", (*Decoder).trice0, 0, 0, 0},
+	{"TRICE8_1", (*Decoder).unSignedOrSignedOut, 4, 8, 1},
+	{"TRICE8_2", (*Decoder).unSignedOrSignedOut, 4, 8, 2},
+	{"TRICE8_3", (*Decoder).unSignedOrSignedOut, 4, 8, 3},
+	{"TRICE8_4", (*Decoder).unSignedOrSignedOut, 4, 8, 4},
+	{"TRICE8_5", (*Decoder).unSignedOrSignedOut, 8, 8, 5},
+	{"TRICE8_6", (*Decoder).unSignedOrSignedOut, 8, 8, 6},
+	{"TRICE8_7", (*Decoder).unSignedOrSignedOut, 8, 8, 7},
+	{"TRICE8_8", (*Decoder).unSignedOrSignedOut, 8, 8, 8},
+	{"TRICE8_9", (*Decoder).unSignedOrSignedOut, 12, 8, 9},
+	{"TRICE8_10", (*Decoder).unSignedOrSignedOut, 12, 8, 10},
+	{"TRICE8_11", (*Decoder).unSignedOrSignedOut, 12, 8, 11},
+	{"TRICE8_12", (*Decoder).unSignedOrSignedOut, 12, 8, 12},
+	{"TRICE16_1", (*Decoder).unSignedOrSignedOut, 4, 16, 1},
+	{"TRICE16_2", (*Decoder).unSignedOrSignedOut, 4, 16, 2},
+	{"TRICE16_3", (*Decoder).unSignedOrSignedOut, 8, 16, 3},
+	{"TRICE16_4", (*Decoder).unSignedOrSignedOut, 8, 16, 4},
+	{"TRICE16_5", (*Decoder).unSignedOrSignedOut, 12, 16, 5},
+	{"TRICE16_6", (*Decoder).unSignedOrSignedOut, 12, 16, 6},
+	{"TRICE16_7", (*Decoder).unSignedOrSignedOut, 16, 16, 7},
+	{"TRICE16_8", (*Decoder).unSignedOrSignedOut, 16, 16, 8},
+	{"TRICE16_9", (*Decoder).unSignedOrSignedOut, 20, 16, 9},
+	{"TRICE16_10", (*Decoder).unSignedOrSignedOut, 20, 16, 10},
+	{"TRICE16_11", (*Decoder).unSignedOrSignedOut, 24, 16, 11},
+	{"TRICE16_12", (*Decoder).unSignedOrSignedOut, 24, 16, 12},
+	{"TRICE32_1", (*Decoder).unSignedOrSignedOut, 4, 32, 1},
+	{"TRICE32_2", (*Decoder).unSignedOrSignedOut, 8, 32, 2},
+	{"TRICE32_3", (*Decoder).unSignedOrSignedOut, 12, 32, 3},
+	{"TRICE32_4", (*Decoder).unSignedOrSignedOut, 16, 32, 4},
+	{"TRICE32_5", (*Decoder).unSignedOrSignedOut, 20, 32, 5},
+	{"TRICE32_6", (*Decoder).unSignedOrSignedOut, 24, 32, 6},
+	{"TRICE32_7", (*Decoder).unSignedOrSignedOut, 28, 32, 7},
+	{"TRICE32_8", (*Decoder).unSignedOrSignedOut, 32, 32, 8},
+	{"TRICE32_9", (*Decoder).unSignedOrSignedOut, 36, 32, 9},
+	{"TRICE32_10", (*Decoder).unSignedOrSignedOut, 40, 32, 10},
+	{"TRICE32_11", (*Decoder).unSignedOrSignedOut, 44, 32, 11},
+	{"TRICE32_12", (*Decoder).unSignedOrSignedOut, 48, 32, 12},
+	{"TRICE64_1", (*Decoder).unSignedOrSignedOut, 8, 64, 1},
+	{"TRICE64_2", (*Decoder).unSignedOrSignedOut, 16, 64, 2},
+	{"TRICE64_3", (*Decoder).unSignedOrSignedOut, 24, 64, 3},
+	{"TRICE64_4", (*Decoder).unSignedOrSignedOut, 32, 64, 4},
+	{"TRICE64_5", (*Decoder).unSignedOrSignedOut, 40, 64, 5},
+	{"TRICE64_6", (*Decoder).unSignedOrSignedOut, 48, 64, 6},
+	{"TRICE64_7", (*Decoder).unSignedOrSignedOut, 56, 64, 7},
+	{"TRICE64_8", (*Decoder).unSignedOrSignedOut, 64, 64, 8},
+	{"TRICE64_9", (*Decoder).unSignedOrSignedOut, 72, 64, 9},
+	{"TRICE64_10", (*Decoder).unSignedOrSignedOut, 80, 64, 10},
+	{"TRICE64_11", (*Decoder).unSignedOrSignedOut, 88, 64, 11},
+	{"TRICE64_12", (*Decoder).unSignedOrSignedOut, 96, 64, 12},
+}
+
+// triceS converts dynamic strings.
+func (p *Decoder) triceS(_ int, _ int) (string, bool) {
+	if nil != p.opt.Debug {
+		fmt.Fprintln(p.opt.Debug, p.b)
+	}
+	if len(p.b) < 4+p.sLen {
+		return fmt.Sprintln("err:TRICE_S len", p.sLen, "exceeds package - ignoring data", p.b) + fmt.Sprintln(Hints), false
+	}
+	s := string(p.b[4 : 4+p.sLen])
+	p.args = []interface{}{s}
+	return fmt.Sprintf(p.trice.Strg, s), true
+}
+
+// trice0 returns the trice format string.
+func (p *Decoder) trice0(_ int, _ int) (string, bool) {
+	return fmt.Sprintf(p.trice.Strg), true
+}
+
+// unSignedOrSignedOut returns p.b values according to the format string.
+func (p *Decoder) unSignedOrSignedOut(bitwidth, count int) (string, bool) {
+	if len(p.u) != count {
+		return fmt.Sprintln("ERROR: Invalid format specifier count inside", p.trice.Type, p.trice.Strg), false
+	}
+	v := make([]interface{}, len(p.u))
+	switch bitwidth {
+	case 8:
+		for i, f := range p.u {
+			if f {
+				v[i] = uint8(p.b[i])
+			} else {
+				v[i] = int8(p.b[i])
+			}
+		}
+	case 16:
+		for i, f := range p.u {
+			n := p.readU16(p.b[2*i:])
+			if f {
+				v[i] = n
+			} else {
+				v[i] = int16(n)
+			}
+		}
+	case 32:
+		for i, f := range p.u {
+			n := p.readU32(p.b[4*i:])
+			if f {
+				v[i] = n
+			} else {
+				v[i] = int32(n)
+			}
+		}
+	case 64:
+		for i, f := range p.u {
+			n := p.readU64(p.b[8*i:])
+			if f {
+				v[i] = n
+			} else {
+				v[i] = int64(n)
+			}
+		}
+	}
+	p.args = v
+	return fmt.Sprintf(p.pFmt, p.args...), true
+}
+
+// readU16 returns the 2 b bytes as uint16 according the specified endianness
+func (p *Decoder) readU16(b []byte) uint16 {
+	if p.opt.BigEndian {
+		return binary.BigEndian.Uint16(b)
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+// readU32 returns the 4 b bytes as uint32 according the specified endianness
+func (p *Decoder) readU32(b []byte) uint32 {
+	if p.opt.BigEndian {
+		return binary.BigEndian.Uint32(b)
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// readU64 returns the 8 b bytes as uint64 according the specified endianness
+func (p *Decoder) readU64(b []byte) uint64 {
+	if p.opt.BigEndian {
+		return binary.BigEndian.Uint64(b)
+	}
+	return binary.LittleEndian.Uint64(b)
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 Thomas.Hoehenleitner [at] seerose.net
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// blackbox test
+package trice_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/dim13/cobs"
+	"github.com/rokath/trice/pkg/trice"
+	"github.com/tj/assert"
+)
+
+// til is the trice id list content for test
+const til = `{
+	"48324": {
+		"Type": "TRICE16",
+		"Strg": "MSG: START select = %d, TriceDepthMax =%4u\\n"
+	},
+	"53709": {
+		"Type": "TRICE16",
+		"Strg": "MSG: STOP  select = %d, TriceDepthMax =%4u\\n"
+	}
+}`
+
+var (
+	start = []byte{2, 1, 1, 1, 3, 208, 7, 1, 5, 192, 1, 196, 188, 1, 1, 1, 1, 0}  // START trice with cycle 0xc0 and target timestamp 2000
+	stop  = []byte{2, 1, 1, 1, 3, 209, 7, 1, 5, 193, 1, 205, 209, 1, 2, 28, 1, 0} // STOP trice with cycle 0xc1 and target timestamp 2001
+)
+
+func lookUp(t *testing.T) trice.TriceIDLookUp {
+	lu, err := trice.NewLookUp([]byte(til))
+	assert.Nil(t, err)
+	return lu
+}
+
+func TestNext(t *testing.T) {
+	dec := trice.NewDecoder(bytes.NewReader(append(start, stop...)), lookUp(t), trice.Options{Unsigned: true})
+
+	x, err := dec.Next()
+	assert.Nil(t, err)
+	assert.True(t, x.Valid)
+	assert.Equal(t, trice.TriceID(48324), x.ID)
+	assert.Equal(t, "TRICE16_2", x.Type)
+	assert.Equal(t, "MSG: START select = %d, TriceDepthMax =%4u\\n", x.Fmt)
+	assert.Equal(t, []interface{}{int16(0), uint16(0)}, x.Args)
+	assert.Equal(t, "MSG: START select = 0, TriceDepthMax =   0\\n", x.Text)
+	assert.True(t, x.HasTargetTimestamp)
+	assert.Equal(t, uint32(2000), x.TargetTimestamp)
+	assert.Nil(t, x.Messages)
+	assert.False(t, x.PCTime.IsZero())
+
+	x, err = dec.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, trice.TriceID(53709), x.ID)
+	assert.Equal(t, []interface{}{int16(0), uint16(28)}, x.Args)
+	assert.Equal(t, uint32(2001), x.TargetTimestamp)
+	assert.Nil(t, x.Messages)
+
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+// TestIndependentDecoders checks that the cycle counter state of one decoder does not influence another one.
+func TestIndependentDecoders(t *testing.T) {
+	lu := lookUp(t)
+	a := trice.NewDecoder(bytes.NewReader(append(start, stop...)), lu, trice.Options{})
+	b := trice.NewDecoder(bytes.NewReader(stop), lu, trice.Options{})
+
+	x, err := a.Next()
+	assert.Nil(t, err)
+	assert.Nil(t, x.Messages)
+
+	y, err := b.Next() // b did not see the START trice
+	assert.Nil(t, err)
+	assert.True(t, y.Valid)
+	assert.Equal(t, []string{"CYCLE: 193 not equal expected value 192 - adjusting.\n"}, y.Messages)
+
+	x, err = a.Next()
+	assert.Nil(t, err)
+	assert.True(t, x.Valid)
+	assert.Nil(t, x.Messages)
+}
+
+func TestNextUnknownID(t *testing.T) {
+	lu := lookUp(t)
+	delete(lu, 53709)
+	dec := trice.NewDecoder(bytes.NewReader(append(start, stop...)), lu, trice.Options{})
+
+	x, err := dec.Next()
+	assert.Nil(t, err)
+	assert.True(t, x.Valid)
+
+	x, err = dec.Next()
+	assert.Nil(t, err)
+	assert.False(t, x.Valid)
+	assert.Equal(t, trice.TriceID(53709), x.ID)
+	assert.Equal(t, []string{"WARNING:unknown ID  53709 - ignoring trice [193 1 205 209 0 0 28 0]\n", trice.Hints + "\n"}, x.Messages)
+
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, dec.Stats().UnknownIDs)
+	assert.Equal(t, 0, dec.Errors())
+}
+
+func TestStats(t *testing.T) {
+	var in []byte
+	for _, b := range [][]byte{start, stop, stop, invalid[7], start} { // second stop is a cycle gap, second start a target reset
+		in = append(in, b...)
+	}
+	dec := trice.NewDecoder(bytes.NewReader(in), lookUp(t), trice.Options{})
+	for {
+		if _, err := dec.Next(); nil != err {
+			break
+		}
+	}
+	assert.Equal(t, trice.Stats{Bytes: len(in), Packages: 5, Trices: 4, Errors: 1, CycleGaps: 1, TargetResets: 1}, dec.Stats())
+	assert.Equal(t, trice.Stats{Bytes: 2 * len(in), Packages: 10, Trices: 8, Errors: 2, CycleGaps: 2, TargetResets: 2}, dec.Stats().Add(dec.Stats()))
+
+	in = nil // a target without cycle counter sends always 0xc0
+	for i := 0; i < 5; i++ {
+		in = append(in, start...)
+	}
+	dec = trice.NewDecoder(bytes.NewReader(in), lookUp(t), trice.Options{})
+	for {
+		if _, err := dec.Next(); nil != err {
+			break
+		}
+	}
+	assert.Equal(t, trice.Stats{Bytes: len(in), Packages: 5, Trices: 5}, dec.Stats())
+}
+
+// invalid are COBS packages with invalid content, each followed by a valid START trice.
+var invalid = [][]byte{
+	cobs.Encode([]byte{1, 0, 0, 0, 208, 7, 0, 0}),               // target timestamp without trice head
+	cobs.Encode([]byte{0, 0, 0, 0, 192, 0, 196, 188, 1}),        // len not a multiple of 4
+	cobs.Encode([]byte{0, 0, 0, 0, 192, 16, 196, 188}),          // param space exceeds package
+	cobs.Encode([]byte{0, 0, 0, 0, 192, 0, 196, 188}),           // param space does not match trice type
+	cobs.Encode([]byte{0, 0, 0, 0, 192, 0, 1, 0}),               // TRICE_S without string length
+	cobs.Encode([]byte{0, 0, 0, 0, 192, 4, 1, 0, 255, 0, 0, 0}), // TRICE_S string length exceeds package
+	{1, 2, 3, 0},  // invalid COBS
+	{255, 255, 0}, // invalid COBS
+}
+
+// TestNextInvalidData checks, that invalid data are dropped and counted and the decoding goes on.
+func TestNextInvalidData(t *testing.T) {
+	lu := lookUp(t)
+	lu[1] = trice.TriceFmt{Type: "TRICE_S", Strg: "%s"}
+	for i, b := range invalid {
+		dec := trice.NewDecoder(bytes.NewReader(append(b, start...)), lu, trice.Options{})
+		var valid int
+		for {
+			x, err := dec.Next()
+			if nil != err {
+				assert.Equal(t, io.EOF, err)
+				break
+			}
+			if x.Valid {
+				valid++
+				assert.Equal(t, trice.TriceID(48324), x.ID, i)
+			}
+		}
+		assert.Equal(t, 1, valid, i)
+		assert.Equal(t, 1, dec.Errors(), i)
+	}
+}
+
+// FuzzNext feeds random bytes into a Decoder. It must not panic.
+// Run it with "go test ./pkg/trice -run XXX -fuzz FuzzNext".
+func FuzzNext(f *testing.F) {
+	f.Add(append(start, stop...), false)
+	for _, b := range invalid {
+		f.Add(b, false)
+		f.Add(b, true)
+	}
+	lu, err := trice.NewLookUp([]byte(til))
+	assert.Nil(f, err)
+	lu[1] = trice.TriceFmt{Type: "TRICE_S", Strg: "%s"}
+	lu[2] = trice.TriceFmt{Type: "TRICE8", Strg: "%d %u %x"}
+	lu[3] = trice.TriceFmt{Type: "TRICE64_2", Strg: "%d %b"}
+	lu[4] = trice.TriceFmt{Type: "TRICE0", Strg: "msg:x"}
+	f.Fuzz(func(t *testing.T, b []byte, bigEndian bool) {
+		dec := trice.NewDecoder(bytes.NewReader(b), lu, trice.Options{BigEndian: bigEndian})
+		for i := 0; i <= len(b); i++ { // each call consumes at least one byte
+			if _, err := dec.Next(); nil != err {
+				return
+			}
+		}
+	})
+}
+
+func ExampleDecoder_Next() {
+	lu, _ := trice.NewLookUp([]byte(til))
+	dec := trice.NewDecoder(bytes.NewReader(append(start, stop...)), lu, trice.Options{Unsigned: true})
+	for {
+		x, err := dec.Next()
+		if nil != err {
+			break
+		}
+		fmt.Println(x.TargetTimestamp, x.ID, x.Type, x.Args)
+	}
+	// Output:
+	// 2000 48324 TRICE16_2 [0 0]
+	// 2001 53709 TRICE16_2 [0 28]
+}